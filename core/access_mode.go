@@ -0,0 +1,140 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+)
+
+// AccessMode is the mode with which a volume is attached to and mounted on
+// instances.
+type AccessMode string
+
+const (
+	// AccessModeReadWriteSingle is the mode of a volume that is attached to
+	// one instance at a time and mounted read-write. It is the mode of a
+	// volume for which no mode is provided.
+	AccessModeReadWriteSingle AccessMode = "rw"
+
+	// AccessModeReadOnlySingle is the mode of a volume that is attached to
+	// one instance at a time and mounted read-only.
+	AccessModeReadOnlySingle AccessMode = "ro"
+
+	// AccessModeReadOnlyMany is the mode of a volume that may be attached to
+	// many instances at the same time and is mounted read-only.
+	AccessModeReadOnlyMany AccessMode = "ro-many"
+
+	// AccessModeReadWriteMany is the mode of a volume that may be attached to
+	// many instances at the same time and is mounted read-write. The
+	// consumers of the volume are responsible for coordinating their writes.
+	AccessModeReadWriteMany AccessMode = "rw-many"
+)
+
+// VolumeAccessModeLabel is the label that holds the access mode with which a
+// volume is mounted when no mode is provided with the request to mount it.
+const VolumeAccessModeLabel = "rexray-accessmode"
+
+// ParseAccessMode returns the access mode named by the provided string. An
+// empty string is the read-write, single instance mode.
+func ParseAccessMode(mode string) (AccessMode, error) {
+	switch m := AccessMode(strings.ToLower(mode)); m {
+	case "":
+		return AccessModeReadWriteSingle, nil
+	case AccessModeReadWriteSingle,
+		AccessModeReadOnlySingle,
+		AccessModeReadOnlyMany,
+		AccessModeReadWriteMany:
+		return m, nil
+	}
+	return "", goof.WithField("accessMode", mode, "invalid access mode")
+}
+
+// ReadOnly returns a flag indicating whether or not volumes with the mode
+// are mounted read-only.
+func (m AccessMode) ReadOnly() bool {
+	return m == AccessModeReadOnlySingle || m == AccessModeReadOnlyMany
+}
+
+// Many returns a flag indicating whether or not volumes with the mode may be
+// attached to many instances at the same time.
+func (m AccessMode) Many() bool {
+	return m == AccessModeReadOnlyMany || m == AccessModeReadWriteMany
+}
+
+// AccessModeStorageDriver is implemented by storage drivers that attach
+// volumes with an access mode. A volume is attached with any other storage
+// driver as if it had the read-write, single instance mode; the read-only
+// modes are then enforced when the volume is mounted.
+type AccessModeStorageDriver interface {
+	StorageDriver
+
+	// AttachVolumeMode is AttachVolume with a context and an access mode. A
+	// volume attached with one of the many instance modes remains attached
+	// to the other instances to which it is attached.
+	AttachVolumeMode(
+		ctx context.Context,
+		runAsync bool,
+		volumeID, instanceID string,
+		force bool, mode AccessMode) ([]*VolumeAttachment, error)
+}
+
+// attachVolumeMode attaches the volume with the storage driver in the access
+// mode. A storage driver that does not implement AccessModeStorageDriver
+// attaches the volume as if it had the read-write, single instance mode. As
+// with runMutation, the volume is not attached if the context is already
+// done.
+func attachVolumeMode(
+	ctx context.Context,
+	d StorageDriver,
+	runAsync bool,
+	volumeID, instanceID string,
+	force bool, mode AccessMode) (atts []*VolumeAttachment, err error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if md, ok := d.(AccessModeStorageDriver); ok {
+		return md.AttachVolumeMode(
+			ctx, runAsync, volumeID, instanceID, force, mode)
+	}
+	if cd, ok := d.(ContextStorageDriver); ok {
+		return cd.AttachVolumeContext(
+			ctx, runAsync, volumeID, instanceID, force)
+	}
+	err = runMutation(ctx, func() (err error) {
+		atts, err = d.AttachVolume(runAsync, volumeID, instanceID, force)
+		return
+	})
+	return
+}
+
+// CheckAccessMode returns an error if the storage driver does not support
+// the access mode.
+func CheckAccessMode(d StorageDriver, mode AccessMode) error {
+	if !mode.Many() {
+		return nil
+	}
+	return CheckStorageCapability(d, CapabilityMultiAttach)
+}
+
+type accessModeContextKey int
+
+const accessModeKey accessModeContextKey = 0
+
+// WithAccessMode returns a copy of the provided context that directs the
+// volume driver to attach and mount a volume with the access mode. Without
+// it a volume is mounted with the mode of its VolumeAccessModeLabel.
+func WithAccessMode(ctx context.Context, mode AccessMode) context.Context {
+	return context.WithValue(ctx, accessModeKey, mode)
+}
+
+// AccessModeFromContext returns the access mode stored in the context by
+// WithAccessMode and whether or not one was stored. The mode of a context
+// without one is the read-write, single instance mode.
+func AccessModeFromContext(ctx context.Context) (mode AccessMode, ok bool) {
+	if mode, ok = ctx.Value(accessModeKey).(AccessMode); !ok {
+		mode = AccessModeReadWriteSingle
+	}
+	return
+}
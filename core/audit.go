@@ -0,0 +1,358 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/util"
+)
+
+const (
+	auditFileName = "audit.log"
+	redacted      = "******"
+)
+
+// AuditRecord is an entry in the audit log. A record is written for every
+// operation that changes the storage platform or the host.
+type AuditRecord struct {
+
+	// The time at which the operation started.
+	Time time.Time
+
+	// The caller that requested the operation, such as user:root or
+	// module:DockerVolumeDriverModule/2.
+	Caller string
+
+	// The name of the operation, such as AttachVolume.
+	Operation string
+
+	// The name of the driver that performed the operation.
+	Driver string
+
+	// The operation's arguments. Arguments that may hold secrets are
+	// redacted.
+	Args map[string]interface{} `json:",omitempty" yaml:",omitempty"`
+
+	// The duration of the operation.
+	Duration string
+
+	// The operation's result, either succeeded or failed.
+	Result string
+
+	// The error returned by the operation if it failed.
+	Error string `json:",omitempty" yaml:",omitempty"`
+}
+
+// AuditQuery describes the audit records to return from ReadAudit. Every
+// filter that is set must match for a record to be returned.
+type AuditQuery struct {
+
+	// Only records at or after this time are returned.
+	Since time.Time
+
+	// Only records before this time are returned.
+	Until time.Time
+
+	// The caller that requested the operation.
+	Caller string
+
+	// The name of the operation.
+	Operation string
+
+	// The name of the driver.
+	Driver string
+
+	// The ID of the volume on which the operation was performed.
+	VolumeID string
+
+	// A flag indicating whether or not only failed operations are returned.
+	Failed bool
+
+	// The maximum number of records to return. The most recent records are
+	// returned. Zero returns all of the records.
+	Limit int
+}
+
+// Match returns a flag indicating whether or not the record matches all of
+// the query's filters.
+func (q *AuditQuery) Match(a *AuditRecord) bool {
+	if !q.Since.IsZero() && a.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !a.Time.Before(q.Until) {
+		return false
+	}
+	if q.Caller != "" && a.Caller != q.Caller {
+		return false
+	}
+	if q.Operation != "" && !strings.EqualFold(a.Operation, q.Operation) {
+		return false
+	}
+	if q.Driver != "" && !strings.EqualFold(a.Driver, q.Driver) {
+		return false
+	}
+	if q.VolumeID != "" && a.Args["volumeID"] != q.VolumeID {
+		return false
+	}
+	if q.Failed && a.Error == "" {
+		return false
+	}
+	return true
+}
+
+// AuditSink is the destination to which audit records are written.
+type AuditSink interface {
+
+	// Write writes the record to the audit log.
+	Write(record *AuditRecord) error
+}
+
+// AuditReader is implemented by audit sinks from which the audit log can be
+// read back.
+type AuditReader interface {
+
+	// Read returns the records in the audit log that match the query, oldest
+	// first.
+	Read(query *AuditQuery) ([]*AuditRecord, error)
+}
+
+// NewAuditSink is a function that constructs a new audit sink.
+type NewAuditSink func(r *RexRay) (AuditSink, error)
+
+var auditSinkCtors = map[string]NewAuditSink{
+	"file": newFileAuditSink,
+}
+
+// RegisterAuditSink registers an audit sink that may be selected with the
+// configuration property rexray.audit.sink.
+func RegisterAuditSink(name string, ctor NewAuditSink) {
+	auditSinkCtors[name] = ctor
+}
+
+type callerContextKey int
+
+const callerKey callerContextKey = 0
+
+// WithCaller returns a copy of the provided context that identifies the
+// caller requesting the operations performed with the context. The caller is
+// recorded in the audit log.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey, caller)
+}
+
+// CallerFromContext returns the caller stored in the context by WithCaller
+// or, if there is none, by WithMountRef. If the context does not identify a
+// caller then the user running the process is returned.
+func CallerFromContext(ctx context.Context) string {
+	if caller, _ := ctx.Value(callerKey).(string); caller != "" {
+		return caller
+	}
+	if caller, _ := MountRefFromContext(ctx); caller != "" {
+		return "module:" + caller
+	}
+	for _, k := range []string{"SUDO_USER", "USER", "LOGNAME"} {
+		if u := os.Getenv(k); u != "" {
+			return "user:" + u
+		}
+	}
+	return "user:unknown"
+}
+
+// initAudit initializes the audit sink named by rexray.audit.sink.
+func (r *RexRay) initAudit() error {
+	name := r.Config.GetString("rexray.audit.sink")
+	if name == "" || strings.EqualFold(name, "none") {
+		r.audit = nil
+		return nil
+	}
+	ctor, ok := auditSinkCtors[strings.ToLower(name)]
+	if !ok {
+		return goof.WithField("sink", name, "unknown audit sink")
+	}
+	sink, err := ctor(r)
+	if err != nil {
+		return err
+	}
+	r.audit = sink
+	return nil
+}
+
+// ReadAudit returns the records in the audit log that match the query.
+func (r *RexRay) ReadAudit(query *AuditQuery) ([]*AuditRecord, error) {
+	if r.audit == nil {
+		if err := r.initAudit(); err != nil {
+			return nil, err
+		}
+	}
+	ar, ok := r.audit.(AuditReader)
+	if !ok {
+		return nil, goof.WithField("sink",
+			r.Config.GetString("rexray.audit.sink"),
+			"audit sink cannot be read")
+	}
+	if query == nil {
+		query = &AuditQuery{}
+	}
+	records, err := ar.Read(query)
+	if err != nil {
+		return nil, err
+	}
+	if query.Limit > 0 && len(records) > query.Limit {
+		records = records[len(records)-query.Limit:]
+	}
+	return records, nil
+}
+
+// auditOp writes an audit record for an operation that started at the
+// provided time and returned *err. It is meant to be deferred, once the
+// driver that performs the operation is known, by functions with a named
+// error result.
+func (r *RexRay) auditOp(
+	ctx context.Context,
+	op, driverName string,
+	args map[string]interface{},
+	start time.Time,
+	err *error) {
+
+	if r == nil || r.audit == nil {
+		return
+	}
+
+	a := &AuditRecord{
+		Time:      start,
+		Caller:    CallerFromContext(ctx),
+		Operation: op,
+		Driver:    driverName,
+		Args:      redactArgs(args),
+		Duration:  time.Since(start).String(),
+		Result:    "succeeded",
+	}
+	if err != nil && *err != nil {
+		a.Result = "failed"
+		a.Error = (*err).Error()
+	}
+
+	if werr := r.audit.Write(a); werr != nil {
+		log.WithFields(log.Fields{
+			"operation": op,
+			"error":     werr}).Error("error writing audit record")
+	}
+}
+
+var (
+	secretKeyRX   = regexp.MustCompile(`(?i)pass|secret|token|key|credential`)
+	secretValueRX = regexp.MustCompile(
+		`(?i)((?:pass|passwd|password|secret|token|key)\w*=)[^,\s]*`)
+)
+
+// redactArgs returns a copy of the arguments in which the values of
+// arguments, and of options within arguments, that may hold secrets are
+// replaced.
+func redactArgs(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	ra := map[string]interface{}{}
+	for k, v := range args {
+		if secretKeyRX.MatchString(k) {
+			ra[k] = redacted
+			continue
+		}
+		switch tv := v.(type) {
+		case string:
+			ra[k] = secretValueRX.ReplaceAllString(tv, "${1}"+redacted)
+		case VolumeOpts:
+			ro := VolumeOpts{}
+			for ok, ov := range tv {
+				if secretKeyRX.MatchString(ok) {
+					ro[ok] = redacted
+				} else {
+					ro[ok] = ov
+				}
+			}
+			ra[k] = ro
+		default:
+			ra[k] = v
+		}
+	}
+	return ra
+}
+
+// fileAuditSink appends audit records as lines of JSON to a file.
+type fileAuditSink struct {
+	sync.Mutex
+	path string
+}
+
+func newFileAuditSink(r *RexRay) (AuditSink, error) {
+	p := r.Config.GetString("rexray.audit.file")
+	if p == "" {
+		p = util.LogFilePath(auditFileName)
+	}
+	return &fileAuditSink{path: p}, nil
+}
+
+func (s *fileAuditSink) Write(a *AuditRecord) error {
+	buf, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+
+	s.Lock()
+	defer s.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// a single write of the whole line keeps the records of concurrent
+	// REX-Ray processes from interleaving
+	_, err = f.Write(buf)
+	return err
+}
+
+func (s *fileAuditSink) Read(query *AuditQuery) ([]*AuditRecord, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*AuditRecord
+	br := bufio.NewReader(f)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			var a AuditRecord
+			if jerr := json.Unmarshal(line, &a); jerr != nil {
+				log.WithFields(log.Fields{
+					"path":  s.path,
+					"error": jerr}).Warn("skipping invalid audit record")
+			} else if query.Match(&a) {
+				records = append(records, &a)
+			}
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"strconv"
+
+	"github.com/akutz/goof"
+)
+
+const (
+	// CapabilitySnapshots is the capability to create, get, and remove
+	// snapshots.
+	CapabilitySnapshots = "snapshots"
+
+	// CapabilityCopySnapshot is the capability to copy a snapshot.
+	CapabilityCopySnapshot = "copySnapshot"
+
+	// CapabilityAsync is the capability to return from an operation without
+	// waiting for it to complete when runAsync is set.
+	CapabilityAsync = "async"
+
+	// CapabilityForceAttach is the capability to attach a volume that is
+	// already attached to another instance when force is set.
+	CapabilityForceAttach = "forceAttach"
+
+	// CapabilityMultiAttach is the capability to attach a volume to more
+	// than one instance at the same time.
+	CapabilityMultiAttach = "multiAttach"
+
+	// CapabilityIOPS is the capability to provision a volume with IOPS.
+	CapabilityIOPS = "iops"
+
+	// CapabilityVolumeTypes is the capability to create a volume of a
+	// volume type.
+	CapabilityVolumeTypes = "volumeTypes"
+
+	// CapabilityAvailabilityZones is the capability to create a volume in an
+	// availability zone.
+	CapabilityAvailabilityZones = "availabilityZones"
+
+	// CapabilityExpandVolume is the capability to grow an existing volume.
+	CapabilityExpandVolume = "expandVolume"
+
+	// CapabilitySnapshotGroups is the capability to snapshot several volumes
+	// at the same point in time.
+	CapabilitySnapshotGroups = "snapshotGroups"
+)
+
+// Capabilities is the list of capabilities a storage driver may declare, in
+// the order in which they are displayed.
+var Capabilities = []string{
+	CapabilitySnapshots,
+	CapabilityCopySnapshot,
+	CapabilityAsync,
+	CapabilityForceAttach,
+	CapabilityMultiAttach,
+	CapabilityIOPS,
+	CapabilityVolumeTypes,
+	CapabilityAvailabilityZones,
+	CapabilityExpandVolume,
+	CapabilitySnapshotGroups,
+}
+
+// StorageCapabilities declares the optional features a storage driver
+// supports.
+type StorageCapabilities struct {
+
+	// Snapshots indicates support for creating, getting, and removing
+	// snapshots.
+	Snapshots bool
+
+	// CopySnapshot indicates support for copying snapshots.
+	CopySnapshot bool
+
+	// Async indicates the runAsync flag is honored.
+	Async bool
+
+	// ForceAttach indicates the force flag is honored when attaching a volume.
+	ForceAttach bool
+
+	// MultiAttach indicates a volume may be attached to more than one
+	// instance at the same time.
+	MultiAttach bool
+
+	// IOPS indicates support for provisioning volumes with IOPS.
+	IOPS bool
+
+	// VolumeTypes indicates support for creating volumes of a volume type.
+	VolumeTypes bool
+
+	// AvailabilityZones indicates support for creating volumes in an
+	// availability zone.
+	AvailabilityZones bool
+
+	// ExpandVolume indicates support for growing an existing volume.
+	ExpandVolume bool
+
+	// SnapshotGroups indicates support for snapshotting several volumes at
+	// the same point in time.
+	SnapshotGroups bool
+}
+
+// CapableStorageDriver is implemented by storage drivers that declare the
+// optional features they support.
+type CapableStorageDriver interface {
+	StorageDriver
+
+	// Capabilities returns the storage driver's capabilities.
+	Capabilities() *StorageCapabilities
+}
+
+// Supports returns a flag indicating whether or not the capability is
+// supported.
+func (c *StorageCapabilities) Supports(capability string) bool {
+	switch capability {
+	case CapabilitySnapshots:
+		return c.Snapshots
+	case CapabilityCopySnapshot:
+		return c.CopySnapshot
+	case CapabilityAsync:
+		return c.Async
+	case CapabilityForceAttach:
+		return c.ForceAttach
+	case CapabilityMultiAttach:
+		return c.MultiAttach
+	case CapabilityIOPS:
+		return c.IOPS
+	case CapabilityVolumeTypes:
+		return c.VolumeTypes
+	case CapabilityAvailabilityZones:
+		return c.AvailabilityZones
+	case CapabilityExpandVolume:
+		return c.ExpandVolume
+	case CapabilitySnapshotGroups:
+		return c.SnapshotGroups
+	}
+	return false
+}
+
+// GetStorageCapabilities returns the capabilities of the storage driver. A
+// driver that does not declare its capabilities is assumed to support all of
+// them.
+func GetStorageCapabilities(d StorageDriver) *StorageCapabilities {
+	if cd, ok := d.(CapableStorageDriver); ok {
+		if c := cd.Capabilities(); c != nil {
+			return c
+		}
+	}
+	return &StorageCapabilities{
+		Snapshots:         true,
+		CopySnapshot:      true,
+		Async:             true,
+		ForceAttach:       true,
+		MultiAttach:       true,
+		IOPS:              true,
+		VolumeTypes:       true,
+		AvailabilityZones: true,
+		ExpandVolume:      true,
+		SnapshotGroups:    true,
+	}
+}
+
+// CheckStorageCapability returns an error if the storage driver does not
+// support the capability.
+func CheckStorageCapability(d StorageDriver, capability string) error {
+	if GetStorageCapabilities(d).Supports(capability) {
+		return nil
+	}
+	return goof.WithFields(goof.Fields{
+		"driverName": d.Name(),
+		"capability": capability,
+	}, "capability not supported by storage driver")
+}
+
+// CheckCreateVolume returns an error if the storage driver does not support
+// one of the provided options for creating a volume.
+func CheckCreateVolume(
+	d StorageDriver,
+	volumeType string, IOPS int64, availabilityZone string) error {
+
+	if volumeType != "" {
+		if err := CheckStorageCapability(d, CapabilityVolumeTypes); err != nil {
+			return err
+		}
+	}
+	if IOPS > 0 {
+		if err := CheckStorageCapability(d, CapabilityIOPS); err != nil {
+			return err
+		}
+	}
+	if availabilityZone != "" {
+		err := CheckStorageCapability(d, CapabilityAvailabilityZones)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckVolumeOpts returns an error if the storage driver does not support
+// one of the provided volume options. The option keys must be lower-case.
+func CheckVolumeOpts(d StorageDriver, opts VolumeOpts) error {
+	var IOPS int64
+	if v := opts["iops"]; v != "" {
+		IOPS, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if err := CheckCreateVolume(
+		d, opts["volumetype"], IOPS, opts["availabilityzone"]); err != nil {
+		return err
+	}
+	if opts["accessmode"] != "" {
+		mode, err := ParseAccessMode(opts["accessmode"])
+		if err != nil {
+			return err
+		}
+		if err := CheckAccessMode(d, mode); err != nil {
+			return err
+		}
+	}
+	if opts["snapshotname"] != "" || opts["snapshotid"] != "" {
+		return CheckStorageCapability(d, CapabilitySnapshots)
+	}
+	return nil
+}
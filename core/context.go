@@ -0,0 +1,193 @@
+package core
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// ContextStorageDriver is implemented by storage drivers whose long-running
+// operations observe the cancellation and deadline of a context. The
+// operations of storage drivers that do not implement this interface which
+// only read are still bounded by the context, but an abandoned call
+// continues in the background until the underlying platform returns. The
+// operations which change a volume or snapshot are not bounded by the
+// context once they start (see runMutation).
+type ContextStorageDriver interface {
+	StorageDriver
+
+	// CreateSnapshotContext is CreateSnapshot with a context.
+	CreateSnapshotContext(
+		ctx context.Context,
+		runAsync bool,
+		snapshotName, volumeID, description string) ([]*Snapshot, error)
+
+	// CreateVolumeContext is CreateVolume with a context.
+	CreateVolumeContext(
+		ctx context.Context,
+		runAsync bool,
+		volumeName, volumeID, snapshotID, volumeType string,
+		IOPS, size int64,
+		availabilityZone string) (*Volume, error)
+
+	// RemoveVolumeContext is RemoveVolume with a context.
+	RemoveVolumeContext(ctx context.Context, volumeID string) error
+
+	// AttachVolumeContext is AttachVolume with a context.
+	AttachVolumeContext(
+		ctx context.Context,
+		runAsync bool,
+		volumeID, instanceID string, force bool) ([]*VolumeAttachment, error)
+
+	// DetachVolumeContext is DetachVolume with a context.
+	DetachVolumeContext(
+		ctx context.Context,
+		runAsync bool, volumeID, instanceID string, force bool) error
+
+	// CopySnapshotContext is CopySnapshot with a context.
+	CopySnapshotContext(
+		ctx context.Context,
+		runAsync bool, volumeID, snapshotID, snapshotName,
+		destinationSnapshotName, destinationRegion string) (*Snapshot, error)
+
+	// ExpandVolumeContext is ExpandVolume with a context.
+	ExpandVolumeContext(
+		ctx context.Context, volumeID string, newSize int64) error
+}
+
+// ContextVolumeDriver is implemented by volume drivers whose operations
+// observe the cancellation and deadline of a context. The operations of
+// volume drivers that do not implement this interface are bounded in the
+// same manner as those of storage drivers that do not implement
+// ContextStorageDriver.
+type ContextVolumeDriver interface {
+	VolumeDriver
+
+	// MountContext is Mount with a context.
+	MountContext(
+		ctx context.Context,
+		volumeName, volumeID string,
+		overwriteFs bool, newFsType string, preempt bool) (string, error)
+
+	// UnmountContext is Unmount with a context.
+	UnmountContext(ctx context.Context, volumeName, volumeID string) error
+
+	// PathContext is Path with a context.
+	PathContext(
+		ctx context.Context, volumeName, volumeID string) (string, error)
+
+	// CreateContext is Create with a context.
+	CreateContext(
+		ctx context.Context, volumeName string, opts VolumeOpts) error
+
+	// RemoveContext is Remove with a context.
+	RemoveContext(ctx context.Context, volumeName string) error
+
+	// AttachContext is Attach with a context.
+	AttachContext(
+		ctx context.Context,
+		volumeName, instanceID string, force bool) (string, error)
+
+	// DetachContext is Detach with a context.
+	DetachContext(
+		ctx context.Context, volumeName, instanceID string, force bool) error
+
+	// NetworkNameContext is NetworkName with a context.
+	NetworkNameContext(
+		ctx context.Context, volumeName, instanceID string) (string, error)
+}
+
+// ContextOSDriver is implemented by OS drivers whose operations observe the
+// cancellation and deadline of a context. The operations of OS drivers that
+// do not implement this interface are bounded by the context with runContext,
+// which abandons the call when the context is done.
+type ContextOSDriver interface {
+	OSDriver
+
+	// UnmountContext is Unmount with a context.
+	UnmountContext(ctx context.Context, mountPoint string) error
+
+	// MountContext is Mount with a context.
+	MountContext(
+		ctx context.Context,
+		device, target, mountOptions, mountLabel string) error
+
+	// FormatContext is Format with a context.
+	FormatContext(
+		ctx context.Context,
+		deviceName, fsType string, overwriteFs bool) error
+}
+
+// runContext invokes f and waits for it to return or for the context to be
+// done, whichever happens first. If the context is done first its error is
+// returned and f is left to complete in the background. The goroutine that
+// runs f is abandoned and its result is discarded, so runContext is only used
+// for calls that do not change the state the managers track, such as the
+// cache, the indices, and the audit log. Calls that do are made with
+// runMutation.
+func runContext(ctx context.Context, f func() error) error {
+	if ctx.Done() == nil {
+		return f()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		log.WithField("error", ctx.Err()).Warn(
+			"abandoning driver call that did not observe context")
+		return ctx.Err()
+	}
+}
+
+// runMutation invokes f, a call to a driver that does not observe contexts
+// and that changes a volume, a snapshot, or an attachment, and waits for it to
+// return. The call is not made if the context is already done, but once it is
+// made it is not abandoned when the context is done. Otherwise the storage
+// platform could complete the change after its error was returned, leaving
+// the cache stale and the audit log without a record of the change.
+func runMutation(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f()
+}
+
+// withTimeout returns a copy of the provided context bounded by the duration
+// parsed from the configuration key. The context is returned unaltered, with
+// a no-op cancel function, if the key is not set or is not a valid duration.
+func (r *RexRay) withTimeout(
+	ctx context.Context, key string) (context.Context, context.CancelFunc) {
+
+	if r == nil || r.Config == nil {
+		return ctx, func() {}
+	}
+
+	v := r.Config.GetString(key)
+	if v == "" {
+		return ctx, func() {}
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"key":   key,
+			"value": v,
+			"error": err}).Warn("invalid timeout")
+		return ctx, func() {}
+	}
+	if d <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, d)
+}
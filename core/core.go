@@ -15,6 +15,13 @@ func init() {
 	gofig.SetUserConfigPath(fmt.Sprintf("%s/.rexray", gotil.HomeDir()))
 	gofig.Register(globalRegistration())
 	gofig.Register(driverRegistration())
+	gofig.Register(timeoutRegistration())
+	gofig.Register(taskRegistration())
+	gofig.Register(retryRegistration())
+	gofig.Register(cacheRegistration())
+	gofig.Register(auditRegistration())
+	gofig.Register(snapshotRegistration())
+	gofig.Register(encryptionRegistration())
 }
 
 func globalRegistration() *gofig.Registration {
@@ -53,5 +60,142 @@ rexray:
 	r.Key(gofig.String, "", "docker",
 		"The volume drivers to consider", "rexray.volumeDrivers",
 		"volumeDrivers")
+	r.Key(gofig.String, "", "",
+		"The storage driver used when one cannot be inferred",
+		"rexray.storage.defaultDriver")
+	return r
+}
+
+func timeoutRegistration() *gofig.Registration {
+	r := gofig.NewRegistration("Timeouts")
+	r.Key(gofig.String, "", "",
+		"The deadline for getting storage objects",
+		"rexray.storage.timeouts.get")
+	r.Key(gofig.String, "", "",
+		"The deadline for creating a volume",
+		"rexray.storage.timeouts.create")
+	r.Key(gofig.String, "", "",
+		"The deadline for removing a volume or snapshot",
+		"rexray.storage.timeouts.remove")
+	r.Key(gofig.String, "", "",
+		"The deadline for attaching a volume",
+		"rexray.storage.timeouts.attach")
+	r.Key(gofig.String, "", "",
+		"The deadline for detaching a volume",
+		"rexray.storage.timeouts.detach")
+	r.Key(gofig.String, "", "",
+		"The deadline for creating a snapshot",
+		"rexray.storage.timeouts.snapshot")
+	r.Key(gofig.String, "", "",
+		"The deadline for copying a snapshot",
+		"rexray.storage.timeouts.copy")
+	r.Key(gofig.String, "", "",
+		"The deadline for expanding a volume",
+		"rexray.storage.timeouts.expand")
+	r.Key(gofig.String, "", "",
+		"The deadline for mounting a volume",
+		"rexray.volume.timeouts.mount")
+	r.Key(gofig.String, "", "",
+		"The deadline for unmounting a volume",
+		"rexray.volume.timeouts.unmount")
+	r.Key(gofig.String, "", "",
+		"The deadline for creating a volume with the volume driver",
+		"rexray.volume.timeouts.create")
+	r.Key(gofig.String, "", "",
+		"The deadline for removing a volume with the volume driver",
+		"rexray.volume.timeouts.remove")
+	r.Key(gofig.String, "", "",
+		"The deadline for expanding a volume and its filesystem",
+		"rexray.volume.timeouts.expand")
+	r.Key(gofig.String, "", "",
+		"The deadline for getting the statistics of mounted volumes",
+		"rexray.volume.timeouts.stats")
+	return r
+}
+
+func taskRegistration() *gofig.Registration {
+	r := gofig.NewRegistration("Tasks")
+	r.Key(gofig.Int, "", 0,
+		"The maximum number of tasks that may run at once",
+		"rexray.tasks.maxConcurrent")
+	r.Key(gofig.String, "", "72h",
+		"The period for which completed tasks are retained",
+		"rexray.tasks.retention")
+	return r
+}
+
+func retryRegistration() *gofig.Registration {
+	r := gofig.NewRegistration("Retries")
+	r.Key(gofig.Int, "", 3,
+		"The maximum number of attempts for a storage driver read",
+		"rexray.storage.retry.reads.maxAttempts")
+	r.Key(gofig.Int, "", 3,
+		"The maximum number of attempts for a storage driver write",
+		"rexray.storage.retry.writes.maxAttempts")
+	r.Key(gofig.String, "", "500ms",
+		"The delay before the first retry of a storage driver call",
+		"rexray.storage.retry.initialBackoff")
+	r.Key(gofig.String, "", "10s",
+		"The maximum delay between retries of a storage driver call",
+		"rexray.storage.retry.maxBackoff")
+	r.Key(gofig.Int, "", 5,
+		"The number of consecutive failures that open a circuit breaker",
+		"rexray.storage.retry.circuitBreaker.threshold")
+	r.Key(gofig.String, "", "30s",
+		"The time a circuit breaker stays open",
+		"rexray.storage.retry.circuitBreaker.resetTimeout")
+	return r
+}
+
+func cacheRegistration() *gofig.Registration {
+	r := gofig.NewRegistration("Cache")
+	r.Key(gofig.String, "", "",
+		"The time for which storage instances are cached",
+		"rexray.storage.cache.instanceTTL")
+	r.Key(gofig.String, "", "",
+		"The time for which storage volumes and attachments are cached",
+		"rexray.storage.cache.volumeTTL")
+	return r
+}
+
+func auditRegistration() *gofig.Registration {
+	r := gofig.NewRegistration("Audit")
+	r.Key(gofig.String, "", "file",
+		"The audit sink (file, none)",
+		"rexray.audit.sink")
+	r.Key(gofig.String, "", "",
+		"The path of the audit log written by the file sink",
+		"rexray.audit.file")
+	return r
+}
+
+func snapshotRegistration() *gofig.Registration {
+	r := gofig.NewRegistration("Snapshots")
+	r.Key(gofig.Bool, "", false,
+		"Freeze a mounted volume's filesystem while it is snapshotted",
+		"rexray.storage.snapshots.quiesce")
+	r.Key(gofig.String, "", "30s",
+		"The longest time for which a filesystem is frozen for a snapshot",
+		"rexray.storage.snapshots.quiesceTimeout")
+	r.Key(gofig.String, "", "",
+		"The names of the snapshot schedules run by the daemon",
+		"rexray.storage.snapshots.schedules")
+	return r
+}
+
+func encryptionRegistration() *gofig.Registration {
+	r := gofig.NewRegistration("Encryption")
+	r.Key(gofig.String, "", "env",
+		"The provider of volume encryption keys (file, env, command)",
+		"rexray.encryption.keyProvider")
+	r.Key(gofig.String, "", "",
+		"The key file, or directory of key files named by volume ID",
+		"rexray.encryption.file.path")
+	r.Key(gofig.String, "", defaultKeyEnvVar,
+		"The environment variable that holds the encryption key",
+		"rexray.encryption.env.name")
+	r.Key(gofig.String, "", "",
+		"The command that prints the key for the volume ID argument",
+		"rexray.encryption.command.path")
 	return r
 }
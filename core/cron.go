@@ -0,0 +1,171 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akutz/goof"
+)
+
+// cronSearchYears is how far ahead of a time CronSchedule.Next searches for
+// the next matching minute before concluding there is none, such as for the
+// 30th of February.
+const cronSearchYears = 5
+
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// CronSchedule is a schedule parsed from a cron expression.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar indicate whether the day-of-month and day-of-week
+	// fields are unrestricted. When both are restricted a day matches if
+	// either field matches.
+	domStar, dowStar bool
+}
+
+// ParseCronSchedule parses a standard five-field cron expression, "minute
+// hour day-of-month month day-of-week". Each field is a comma-separated list
+// of values, ranges such as 1-5, or *, each optionally followed by a step
+// such as */15. Sunday is day 0 or 7. The aliases @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight, and @hourly are also accepted.
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if alias, ok := cronAliases[strings.ToLower(spec)]; ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, goof.WithField(
+			"cron", expr, "cron expression must have five fields")
+	}
+
+	c := &CronSchedule{
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, goof.WithFieldE("cron", expr, "invalid minute", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, goof.WithFieldE("cron", expr, "invalid hour", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, goof.WithFieldE("cron", expr, "invalid day of month", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, goof.WithFieldE("cron", expr, "invalid month", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, goof.WithFieldE("cron", expr, "invalid day of week", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	return c, nil
+}
+
+// Next returns the first minute after the provided time that matches the
+// schedule, in the time's location. The zero time is returned if no minute
+// matches within the next five years.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(cronSearchYears, 0, 0)
+	loc := t.Location()
+
+	for t.Before(end) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(
+				t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *CronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parseCronField parses a cron field into a set of bits, one for each value
+// between min and max that the field matches.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rng = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, goof.WithField("field", field, "invalid step")
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, goof.WithField("field", field, "invalid range")
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, goof.WithField("field", field, "invalid range")
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, goof.WithField("field", field, "invalid value")
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, goof.WithFields(goof.Fields{
+				"field": field,
+				"min":   min,
+				"max":   max,
+			}, "value out of range")
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
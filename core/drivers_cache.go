@@ -0,0 +1,195 @@
+package core
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// storageCache caches the instances, volumes and attachments returned by the
+// storage drivers. Each storage driver's volumes and attachments are
+// invalidated when the storage driver manager attaches, detaches, creates or
+// removes one of the driver's volumes. The cache is disabled for objects
+// whose TTL is zero.
+type storageCache struct {
+	sync.Mutex
+
+	instanceTTL time.Duration
+	volumeTTL   time.Duration
+
+	instances   map[string]*cacheEntry
+	volumes     map[string]*cacheEntry
+	attachments map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newStorageCache(r *RexRay) *storageCache {
+	return &storageCache{
+		instanceTTL: r.cacheTTL("rexray.storage.cache.instanceTTL"),
+		volumeTTL:   r.cacheTTL("rexray.storage.cache.volumeTTL"),
+		instances:   map[string]*cacheEntry{},
+		volumes:     map[string]*cacheEntry{},
+		attachments: map[string]*cacheEntry{},
+	}
+}
+
+func (r *RexRay) cacheTTL(key string) time.Duration {
+	v := r.Config.GetString(key)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.WithFields(log.Fields{
+			"key":   key,
+			"value": v,
+			"error": err}).Warn("invalid cache ttl")
+		return 0
+	}
+	return d
+}
+
+func cacheKey(driverName string, parts ...string) string {
+	return strings.ToLower(driverName) + "/" + strings.Join(parts, "/")
+}
+
+func (c *storageCache) get(
+	entries map[string]*cacheEntry, key string) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *storageCache) set(
+	entries map[string]*cacheEntry,
+	key string, value interface{}, ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	entries[key] = &cacheEntry{value, time.Now().Add(ttl)}
+}
+
+// invalidate removes the driver's volumes and attachments from the cache.
+func (c *storageCache) invalidate(driverName string) {
+	if c == nil || c.volumeTTL == 0 {
+		return
+	}
+	prefix := cacheKey(driverName)
+	c.Lock()
+	defer c.Unlock()
+	for _, entries := range []map[string]*cacheEntry{
+		c.volumes, c.attachments} {
+		for k := range entries {
+			if strings.HasPrefix(k, prefix) {
+				delete(entries, k)
+			}
+		}
+	}
+}
+
+// getInstance returns the driver's instance from the cache or, if it is not
+// cached, from the driver.
+func (r *sdm) getInstance(d StorageDriver) (*Instance, error) {
+	c := r.cache
+	if c == nil || c.instanceTTL == 0 {
+		return d.GetInstance()
+	}
+	key := cacheKey(d.Name())
+	if v, ok := c.get(c.instances, key); ok {
+		i := *v.(*Instance)
+		return &i, nil
+	}
+	i, err := d.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	if i != nil {
+		ci := *i
+		c.set(c.instances, key, &ci, c.instanceTTL)
+	}
+	return i, nil
+}
+
+// getVolume returns the driver's volumes from the cache or, if they are not
+// cached, from the driver.
+func (r *sdm) getVolume(
+	d StorageDriver, volumeID, volumeName string) ([]*Volume, error) {
+	c := r.cache
+	if c == nil || c.volumeTTL == 0 {
+		return getVolume(d, volumeID, volumeName)
+	}
+	key := cacheKey(d.Name(), volumeID, volumeName)
+	if v, ok := c.get(c.volumes, key); ok {
+		return copyVolumes(v.([]*Volume)), nil
+	}
+	volumes, err := getVolume(d, volumeID, volumeName)
+	if err != nil {
+		return nil, err
+	}
+	c.set(c.volumes, key, copyVolumes(volumes), c.volumeTTL)
+	return volumes, nil
+}
+
+// getVolumeAttach returns the volume's attachments from the cache or, if they
+// are not cached, from the driver.
+func (r *sdm) getVolumeAttach(
+	d StorageDriver,
+	volumeID, instanceID string) ([]*VolumeAttachment, error) {
+	c := r.cache
+	if c == nil || c.volumeTTL == 0 {
+		return d.GetVolumeAttach(volumeID, instanceID)
+	}
+	key := cacheKey(d.Name(), volumeID, instanceID)
+	if v, ok := c.get(c.attachments, key); ok {
+		return copyAttachments(v.([]*VolumeAttachment)), nil
+	}
+	attachments, err := d.GetVolumeAttach(volumeID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	c.set(c.attachments, key, copyAttachments(attachments), c.volumeTTL)
+	return attachments, nil
+}
+
+// copyVolumes returns copies of the volumes so that callers may modify the
+// volumes they receive without altering the cached volumes.
+func copyVolumes(volumes []*Volume) []*Volume {
+	if volumes == nil {
+		return nil
+	}
+	copies := make([]*Volume, len(volumes))
+	for i, v := range volumes {
+		cv := *v
+		cv.Attachments = copyAttachments(v.Attachments)
+		if v.Labels != nil {
+			cv.Labels = mergeLabels(v.Labels, nil)
+		}
+		copies[i] = &cv
+	}
+	return copies
+}
+
+func copyAttachments(attachments []*VolumeAttachment) []*VolumeAttachment {
+	if attachments == nil {
+		return nil
+	}
+	copies := make([]*VolumeAttachment, len(attachments))
+	for i, a := range attachments {
+		ca := *a
+		copies[i] = &ca
+	}
+	return copies
+}
@@ -0,0 +1,620 @@
+package core
+
+import (
+	"golang.org/x/net/context"
+)
+
+// metricsOSDriver records the duration and errors of an OS driver's calls.
+type metricsOSDriver struct {
+	OSDriver
+}
+
+func newMetricsOSDriver(d OSDriver) OSDriver {
+	return &metricsOSDriver{d}
+}
+
+func (d *metricsOSDriver) observe(op string, f func() error) error {
+	return observe(osDriverType, d.Name(), op, f)
+}
+
+func (d *metricsOSDriver) GetMounts(
+	deviceName, mountPoint string) (mounts MountInfoArray, err error) {
+	err = d.observe("GetMounts", func() (err error) {
+		mounts, err = d.OSDriver.GetMounts(deviceName, mountPoint)
+		return
+	})
+	return
+}
+
+func (d *metricsOSDriver) Mounted(mountPoint string) (mounted bool, err error) {
+	err = d.observe("Mounted", func() (err error) {
+		mounted, err = d.OSDriver.Mounted(mountPoint)
+		return
+	})
+	return
+}
+
+func (d *metricsOSDriver) Unmount(mountPoint string) error {
+	return d.UnmountContext(context.Background(), mountPoint)
+}
+
+func (d *metricsOSDriver) UnmountContext(
+	ctx context.Context, mountPoint string) error {
+	return d.observe("Unmount", func() error {
+		if cd, ok := d.OSDriver.(ContextOSDriver); ok {
+			return cd.UnmountContext(ctx, mountPoint)
+		}
+		return runContext(ctx, func() error {
+			return d.OSDriver.Unmount(mountPoint)
+		})
+	})
+}
+
+func (d *metricsOSDriver) Mount(
+	device, target, mountOptions, mountLabel string) error {
+	return d.MountContext(
+		context.Background(), device, target, mountOptions, mountLabel)
+}
+
+func (d *metricsOSDriver) MountContext(
+	ctx context.Context,
+	device, target, mountOptions, mountLabel string) error {
+	return d.observe("Mount", func() error {
+		if cd, ok := d.OSDriver.(ContextOSDriver); ok {
+			return cd.MountContext(
+				ctx, device, target, mountOptions, mountLabel)
+		}
+		return runContext(ctx, func() error {
+			return d.OSDriver.Mount(device, target, mountOptions, mountLabel)
+		})
+	})
+}
+
+func (d *metricsOSDriver) Format(
+	deviceName, fsType string, overwriteFs bool) error {
+	return d.FormatContext(
+		context.Background(), deviceName, fsType, overwriteFs)
+}
+
+func (d *metricsOSDriver) FormatContext(
+	ctx context.Context,
+	deviceName, fsType string, overwriteFs bool) error {
+	return d.observe("Format", func() error {
+		if cd, ok := d.OSDriver.(ContextOSDriver); ok {
+			return cd.FormatContext(ctx, deviceName, fsType, overwriteFs)
+		}
+		return runContext(ctx, func() error {
+			return d.OSDriver.Format(deviceName, fsType, overwriteFs)
+		})
+	})
+}
+
+func (d *metricsOSDriver) ResizeFilesystem(deviceName, mountPoint string) error {
+	return d.observe("ResizeFilesystem", func() error {
+		return d.OSDriver.ResizeFilesystem(deviceName, mountPoint)
+	})
+}
+
+func (d *metricsOSDriver) Freeze(mountPoint string) error {
+	return d.observe("Freeze", func() error {
+		return d.OSDriver.Freeze(mountPoint)
+	})
+}
+
+func (d *metricsOSDriver) Thaw(mountPoint string) error {
+	return d.observe("Thaw", func() error {
+		return d.OSDriver.Thaw(mountPoint)
+	})
+}
+
+func (d *metricsOSDriver) Stats(
+	deviceName, mountPoint string) (stats *MountStats, err error) {
+	err = d.observe("Stats", func() error {
+		stats, err = d.OSDriver.Stats(deviceName, mountPoint)
+		return err
+	})
+	return
+}
+
+// metricsVolumeDriver records the duration and errors of a volume driver's
+// calls.
+type metricsVolumeDriver struct {
+	VolumeDriver
+}
+
+func newMetricsVolumeDriver(d VolumeDriver) VolumeDriver {
+	return &metricsVolumeDriver{d}
+}
+
+func (d *metricsVolumeDriver) observe(op string, f func() error) error {
+	return observe(volumeDriverType, d.Name(), op, f)
+}
+
+func (d *metricsVolumeDriver) Mount(
+	volumeName, volumeID string,
+	overwriteFs bool, newFsType string, preempt bool) (string, error) {
+	return d.MountContext(context.Background(),
+		volumeName, volumeID, overwriteFs, newFsType, preempt)
+}
+
+func (d *metricsVolumeDriver) MountContext(
+	ctx context.Context,
+	volumeName, volumeID string,
+	overwriteFs bool, newFsType string, preempt bool) (
+	mountPath string, err error) {
+	err = d.observe("Mount", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			mountPath, err = cd.MountContext(ctx,
+				volumeName, volumeID, overwriteFs, newFsType, preempt)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			mountPath, err = d.VolumeDriver.Mount(
+				volumeName, volumeID, overwriteFs, newFsType, preempt)
+			return
+		})
+	})
+	return
+}
+
+func (d *metricsVolumeDriver) Unmount(volumeName, volumeID string) error {
+	return d.UnmountContext(context.Background(), volumeName, volumeID)
+}
+
+func (d *metricsVolumeDriver) UnmountContext(
+	ctx context.Context, volumeName, volumeID string) error {
+	return d.observe("Unmount", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			return cd.UnmountContext(ctx, volumeName, volumeID)
+		}
+		return runMutation(ctx, func() error {
+			return d.VolumeDriver.Unmount(volumeName, volumeID)
+		})
+	})
+}
+
+func (d *metricsVolumeDriver) Path(
+	volumeName, volumeID string) (string, error) {
+	return d.PathContext(context.Background(), volumeName, volumeID)
+}
+
+func (d *metricsVolumeDriver) PathContext(
+	ctx context.Context,
+	volumeName, volumeID string) (mountPath string, err error) {
+	err = d.observe("Path", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			mountPath, err = cd.PathContext(ctx, volumeName, volumeID)
+			return err
+		}
+		return runContext(ctx, func() (err error) {
+			mountPath, err = d.VolumeDriver.Path(volumeName, volumeID)
+			return
+		})
+	})
+	return
+}
+
+func (d *metricsVolumeDriver) Create(volumeName string, opts VolumeOpts) error {
+	return d.CreateContext(context.Background(), volumeName, opts)
+}
+
+func (d *metricsVolumeDriver) CreateContext(
+	ctx context.Context, volumeName string, opts VolumeOpts) error {
+	return d.observe("Create", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			return cd.CreateContext(ctx, volumeName, opts)
+		}
+		return runMutation(ctx, func() error {
+			return d.VolumeDriver.Create(volumeName, opts)
+		})
+	})
+}
+
+func (d *metricsVolumeDriver) Remove(volumeName string) error {
+	return d.RemoveContext(context.Background(), volumeName)
+}
+
+func (d *metricsVolumeDriver) RemoveContext(
+	ctx context.Context, volumeName string) error {
+	return d.observe("Remove", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			return cd.RemoveContext(ctx, volumeName)
+		}
+		return runMutation(ctx, func() error {
+			return d.VolumeDriver.Remove(volumeName)
+		})
+	})
+}
+
+func (d *metricsVolumeDriver) Attach(
+	volumeName, instanceID string, force bool) (string, error) {
+	return d.AttachContext(
+		context.Background(), volumeName, instanceID, force)
+}
+
+func (d *metricsVolumeDriver) AttachContext(
+	ctx context.Context,
+	volumeName, instanceID string, force bool) (device string, err error) {
+	err = d.observe("Attach", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			device, err = cd.AttachContext(ctx, volumeName, instanceID, force)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			device, err = d.VolumeDriver.Attach(volumeName, instanceID, force)
+			return
+		})
+	})
+	return
+}
+
+func (d *metricsVolumeDriver) Detach(
+	volumeName, instanceID string, force bool) error {
+	return d.DetachContext(
+		context.Background(), volumeName, instanceID, force)
+}
+
+func (d *metricsVolumeDriver) DetachContext(
+	ctx context.Context, volumeName, instanceID string, force bool) error {
+	return d.observe("Detach", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			return cd.DetachContext(ctx, volumeName, instanceID, force)
+		}
+		return runMutation(ctx, func() error {
+			return d.VolumeDriver.Detach(volumeName, instanceID, force)
+		})
+	})
+}
+
+func (d *metricsVolumeDriver) NetworkName(
+	volumeName, instanceID string) (string, error) {
+	return d.NetworkNameContext(context.Background(), volumeName, instanceID)
+}
+
+func (d *metricsVolumeDriver) NetworkNameContext(
+	ctx context.Context,
+	volumeName, instanceID string) (networkName string, err error) {
+	err = d.observe("NetworkName", func() error {
+		if cd, ok := d.VolumeDriver.(ContextVolumeDriver); ok {
+			networkName, err = cd.NetworkNameContext(
+				ctx, volumeName, instanceID)
+			return err
+		}
+		return runContext(ctx, func() (err error) {
+			networkName, err = d.VolumeDriver.NetworkName(
+				volumeName, instanceID)
+			return
+		})
+	})
+	return
+}
+
+// newMetricsStorageDriver returns a storage driver that records the duration
+// and errors of the provided driver's calls. Like NewRetryStorageDriver, the
+// returned driver implements the same optional label and query interfaces as
+// the provided driver, and it always implements AccessModeStorageDriver so
+// that the access mode of an attach reaches the provided driver.
+func newMetricsStorageDriver(d StorageDriver) StorageDriver {
+	md := &metricsStorageDriver{d}
+
+	_, labels := d.(LabelStorageDriver)
+	_, query := d.(QueryStorageDriver)
+	switch {
+	case labels && query:
+		return &metricsLabelQueryDriver{&metricsLabelDriver{md}}
+	case labels:
+		return &metricsLabelDriver{md}
+	case query:
+		return &metricsQueryDriver{md}
+	}
+	return md
+}
+
+// metricsStorageDriver records the duration and errors of a storage driver's
+// calls.
+type metricsStorageDriver struct {
+	StorageDriver
+}
+
+type metricsLabelDriver struct {
+	*metricsStorageDriver
+}
+
+type metricsQueryDriver struct {
+	*metricsStorageDriver
+}
+
+type metricsLabelQueryDriver struct {
+	*metricsLabelDriver
+}
+
+func (d *metricsStorageDriver) observe(op string, f func() error) error {
+	return observe(storageDriverType, d.Name(), op, f)
+}
+
+func (d *metricsStorageDriver) Capabilities() *StorageCapabilities {
+	return GetStorageCapabilities(d.StorageDriver)
+}
+
+func (d *metricsStorageDriver) GetVolumeMapping() (
+	bds []*BlockDevice, err error) {
+	err = d.observe("GetVolumeMapping", func() (err error) {
+		bds, err = d.StorageDriver.GetVolumeMapping()
+		return
+	})
+	return
+}
+
+func (d *metricsStorageDriver) GetInstance() (i *Instance, err error) {
+	err = d.observe("GetInstance", func() (err error) {
+		i, err = d.StorageDriver.GetInstance()
+		return
+	})
+	return
+}
+
+func (d *metricsStorageDriver) GetVolume(
+	volumeID, volumeName string) (volumes []*Volume, err error) {
+	err = d.observe("GetVolume", func() (err error) {
+		volumes, err = d.StorageDriver.GetVolume(volumeID, volumeName)
+		return
+	})
+	return
+}
+
+func (d *metricsStorageDriver) GetVolumeAttach(
+	volumeID, instanceID string) (atts []*VolumeAttachment, err error) {
+	err = d.observe("GetVolumeAttach", func() (err error) {
+		atts, err = d.StorageDriver.GetVolumeAttach(volumeID, instanceID)
+		return
+	})
+	return
+}
+
+func (d *metricsStorageDriver) GetSnapshot(
+	volumeID, snapshotID, snapshotName string) (
+	snapshots []*Snapshot, err error) {
+	err = d.observe("GetSnapshot", func() (err error) {
+		snapshots, err = d.StorageDriver.GetSnapshot(
+			volumeID, snapshotID, snapshotName)
+		return
+	})
+	return
+}
+
+func (d *metricsStorageDriver) GetDeviceNextAvailable() (
+	device string, err error) {
+	err = d.observe("GetDeviceNextAvailable", func() (err error) {
+		device, err = d.StorageDriver.GetDeviceNextAvailable()
+		return
+	})
+	return
+}
+
+func (d *metricsStorageDriver) RemoveSnapshot(snapshotID string) error {
+	return d.observe("RemoveSnapshot", func() error {
+		return d.StorageDriver.RemoveSnapshot(snapshotID)
+	})
+}
+
+func (d *metricsStorageDriver) CreateSnapshot(
+	runAsync bool,
+	snapshotName, volumeID, description string) ([]*Snapshot, error) {
+	return d.CreateSnapshotContext(
+		context.Background(), runAsync, snapshotName, volumeID, description)
+}
+
+func (d *metricsStorageDriver) CreateSnapshotContext(
+	ctx context.Context,
+	runAsync bool,
+	snapshotName, volumeID, description string) (
+	snapshots []*Snapshot, err error) {
+	err = d.observe("CreateSnapshot", func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			snapshots, err = cd.CreateSnapshotContext(
+				ctx, runAsync, snapshotName, volumeID, description)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			snapshots, err = d.StorageDriver.CreateSnapshot(
+				runAsync, snapshotName, volumeID, description)
+			return
+		})
+	})
+	return
+}
+
+func (d *metricsStorageDriver) CreateVolume(
+	runAsync bool,
+	volumeName, volumeID, snapshotID, volumeType string,
+	IOPS, size int64,
+	availabilityZone string) (*Volume, error) {
+	return d.CreateVolumeContext(
+		context.Background(), runAsync, volumeName, volumeID, snapshotID,
+		volumeType, IOPS, size, availabilityZone)
+}
+
+func (d *metricsStorageDriver) CreateVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeName, volumeID, snapshotID, volumeType string,
+	IOPS, size int64,
+	availabilityZone string) (volume *Volume, err error) {
+	err = d.observe("CreateVolume", func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			volume, err = cd.CreateVolumeContext(
+				ctx, runAsync, volumeName, volumeID, snapshotID, volumeType,
+				IOPS, size, availabilityZone)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			volume, err = d.StorageDriver.CreateVolume(
+				runAsync, volumeName, volumeID, snapshotID, volumeType,
+				IOPS, size, availabilityZone)
+			return
+		})
+	})
+	return
+}
+
+func (d *metricsStorageDriver) RemoveVolume(volumeID string) error {
+	return d.RemoveVolumeContext(context.Background(), volumeID)
+}
+
+func (d *metricsStorageDriver) RemoveVolumeContext(
+	ctx context.Context, volumeID string) error {
+	return d.observe("RemoveVolume", func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			return cd.RemoveVolumeContext(ctx, volumeID)
+		}
+		return runMutation(ctx, func() error {
+			return d.StorageDriver.RemoveVolume(volumeID)
+		})
+	})
+}
+
+func (d *metricsStorageDriver) AttachVolume(
+	runAsync bool,
+	volumeID, instanceID string, force bool) ([]*VolumeAttachment, error) {
+	return d.AttachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (d *metricsStorageDriver) AttachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string, force bool) ([]*VolumeAttachment, error) {
+	mode, _ := AccessModeFromContext(ctx)
+	return d.AttachVolumeMode(
+		ctx, runAsync, volumeID, instanceID, force, mode)
+}
+
+func (d *metricsStorageDriver) AttachVolumeMode(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string,
+	force bool, mode AccessMode) (atts []*VolumeAttachment, err error) {
+	err = d.observe("AttachVolume", func() error {
+		atts, err = attachVolumeMode(ctx, d.StorageDriver,
+			runAsync, volumeID, instanceID, force, mode)
+		return err
+	})
+	return
+}
+
+func (d *metricsStorageDriver) DetachVolume(
+	runAsync bool, volumeID, instanceID string, force bool) error {
+	return d.DetachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (d *metricsStorageDriver) DetachVolumeContext(
+	ctx context.Context,
+	runAsync bool, volumeID, instanceID string, force bool) error {
+	return d.observe("DetachVolume", func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			return cd.DetachVolumeContext(
+				ctx, runAsync, volumeID, instanceID, force)
+		}
+		return runMutation(ctx, func() error {
+			return d.StorageDriver.DetachVolume(
+				runAsync, volumeID, instanceID, force)
+		})
+	})
+}
+
+func (d *metricsStorageDriver) CopySnapshot(
+	runAsync bool, volumeID, snapshotID, snapshotName,
+	destinationSnapshotName, destinationRegion string) (*Snapshot, error) {
+	return d.CopySnapshotContext(
+		context.Background(), runAsync, volumeID, snapshotID, snapshotName,
+		destinationSnapshotName, destinationRegion)
+}
+
+func (d *metricsStorageDriver) CopySnapshotContext(
+	ctx context.Context,
+	runAsync bool, volumeID, snapshotID, snapshotName,
+	destinationSnapshotName, destinationRegion string) (
+	snapshot *Snapshot, err error) {
+	err = d.observe("CopySnapshot", func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			snapshot, err = cd.CopySnapshotContext(
+				ctx, runAsync, volumeID, snapshotID, snapshotName,
+				destinationSnapshotName, destinationRegion)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			snapshot, err = d.StorageDriver.CopySnapshot(
+				runAsync, volumeID, snapshotID, snapshotName,
+				destinationSnapshotName, destinationRegion)
+			return
+		})
+	})
+	return
+}
+
+func (d *metricsStorageDriver) ExpandVolume(
+	volumeID string, newSize int64) error {
+	return d.ExpandVolumeContext(context.Background(), volumeID, newSize)
+}
+
+func (d *metricsStorageDriver) ExpandVolumeContext(
+	ctx context.Context, volumeID string, newSize int64) error {
+	return d.observe("ExpandVolume", func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			return cd.ExpandVolumeContext(ctx, volumeID, newSize)
+		}
+		return runMutation(ctx, func() error {
+			return d.StorageDriver.ExpandVolume(volumeID, newSize)
+		})
+	})
+}
+
+func (d *metricsStorageDriver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) (snapshots []*Snapshot, err error) {
+	err = d.observe("CreateSnapshotGroup", func() (err error) {
+		snapshots, err = d.StorageDriver.CreateSnapshotGroup(
+			runAsync, snapshotNames, description)
+		return
+	})
+	return
+}
+
+func (d *metricsLabelDriver) SetVolumeLabels(
+	volumeID string, labels map[string]string) error {
+	return d.observe("SetVolumeLabels", func() error {
+		return d.StorageDriver.(LabelStorageDriver).SetVolumeLabels(
+			volumeID, labels)
+	})
+}
+
+func (d *metricsLabelDriver) SetSnapshotLabels(
+	snapshotID string, labels map[string]string) error {
+	return d.observe("SetSnapshotLabels", func() error {
+		return d.StorageDriver.(LabelStorageDriver).SetSnapshotLabels(
+			snapshotID, labels)
+	})
+}
+
+func (d *metricsQueryDriver) GetVolumesByQuery(
+	query *VolumeQuery) ([]*Volume, error) {
+	return observeVolumesByQuery(d.metricsStorageDriver, query)
+}
+
+func (d *metricsLabelQueryDriver) GetVolumesByQuery(
+	query *VolumeQuery) ([]*Volume, error) {
+	return observeVolumesByQuery(d.metricsStorageDriver, query)
+}
+
+func observeVolumesByQuery(
+	d *metricsStorageDriver,
+	query *VolumeQuery) (volumes []*Volume, err error) {
+	err = d.observe("GetVolumesByQuery", func() (err error) {
+		volumes, err = d.StorageDriver.(QueryStorageDriver).
+			GetVolumesByQuery(query)
+		return
+	})
+	return
+}
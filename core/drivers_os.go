@@ -3,9 +3,11 @@ package core
 import (
 	"bytes"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/mount"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core/errors"
 )
@@ -32,16 +34,43 @@ type OSDriver interface {
 
 	// Format a device with a FS type
 	Format(string, string, bool) error
+
+	// Grow the filesystem on a device, mounted at a path, to fill the device
+	ResizeFilesystem(string, string) error
+
+	// Suspend writes to the filesystem mounted at a path
+	Freeze(string) error
+
+	// Resume writes to the filesystem mounted at a path
+	Thaw(string) error
+
+	// Get the usage of the filesystem mounted at a path on a device and the
+	// I/O counters of the device
+	Stats(string, string) (*MountStats, error)
 }
 
 // OSDriverManager acts as both a OSDriverManager and as an aggregate of OS
 // drivers, providing batch methods.
 type OSDriverManager interface {
-	OSDriver
+	ContextOSDriver
 
 	// Drivers gets a channel which receives a list of all of the configured
 	// OS drivers.
 	Drivers() <-chan OSDriver
+
+	// ResizeFilesystemContext is ResizeFilesystem with a context.
+	ResizeFilesystemContext(
+		ctx context.Context, deviceName, mountPoint string) error
+
+	// FreezeContext is Freeze with a context.
+	FreezeContext(ctx context.Context, mountPoint string) error
+
+	// ThawContext is Thaw with a context.
+	ThawContext(ctx context.Context, mountPoint string) error
+
+	// StatsContext is Stats with a context.
+	StatsContext(
+		ctx context.Context, deviceName, mountPoint string) (*MountStats, error)
 }
 
 type odm struct {
@@ -109,17 +138,38 @@ func (r *odm) Mounted(mountPoint string) (bool, error) {
 }
 
 func (r *odm) Unmount(mountPoint string) error {
+	return r.UnmountContext(context.Background(), mountPoint)
+}
+
+func (r *odm) UnmountContext(
+	ctx context.Context, mountPoint string) (err error) {
 	for _, d := range r.drivers {
 		log.WithFields(log.Fields{
 			"mountPoint": mountPoint,
 			"driverName": d.Name()}).Info("unmounting filesystem")
-		return d.Unmount(mountPoint)
+		defer r.rexray.auditOp(ctx, "Unmount", d.Name(),
+			map[string]interface{}{
+				"mountPoint": mountPoint,
+			}, time.Now(), &err)
+		if cd, ok := d.(ContextOSDriver); ok {
+			return cd.UnmountContext(ctx, mountPoint)
+		}
+		return runContext(ctx, func() error {
+			return d.Unmount(mountPoint)
+		})
 	}
 	return errors.ErrNoOSDetected
 }
 
 func (r *odm) Mount(
 	device, target, mountOptions, mountLabel string) error {
+	return r.MountContext(
+		context.Background(), device, target, mountOptions, mountLabel)
+}
+
+func (r *odm) MountContext(
+	ctx context.Context,
+	device, target, mountOptions, mountLabel string) (err error) {
 	for _, d := range r.drivers {
 		log.WithFields(log.Fields{
 			"device":       device,
@@ -127,7 +177,20 @@ func (r *odm) Mount(
 			"mountOptions": mountOptions,
 			"mountLabel":   mountLabel,
 			"driverName":   d.Name()}).Info("mounting filesystem")
-		return d.Mount(device, target, mountOptions, mountLabel)
+		defer r.rexray.auditOp(ctx, "Mount", d.Name(),
+			map[string]interface{}{
+				"device":       device,
+				"target":       target,
+				"mountOptions": mountOptions,
+				"mountLabel":   mountLabel,
+			}, time.Now(), &err)
+		if cd, ok := d.(ContextOSDriver); ok {
+			return cd.MountContext(
+				ctx, device, target, mountOptions, mountLabel)
+		}
+		return runContext(ctx, func() error {
+			return d.Mount(device, target, mountOptions, mountLabel)
+		})
 	}
 	return errors.ErrNoOSDetected
 }
@@ -138,6 +201,13 @@ func (r *odm) isNfsDevice(device string) bool {
 
 func (r *odm) Format(
 	deviceName, fsType string, overwriteFs bool) error {
+	return r.FormatContext(
+		context.Background(), deviceName, fsType, overwriteFs)
+}
+
+func (r *odm) FormatContext(
+	ctx context.Context,
+	deviceName, fsType string, overwriteFs bool) (err error) {
 	for _, d := range r.drivers {
 		log.WithFields(log.Fields{
 			"deviceName":  deviceName,
@@ -148,8 +218,131 @@ func (r *odm) Format(
 		if r.isNfsDevice(deviceName) {
 			return nil
 		}
+		defer r.rexray.auditOp(ctx, "Format", d.Name(),
+			map[string]interface{}{
+				"deviceName":  deviceName,
+				"fsType":      fsType,
+				"overwriteFs": overwriteFs,
+			}, time.Now(), &err)
 
-		return d.Format(deviceName, fsType, overwriteFs)
+		if cd, ok := d.(ContextOSDriver); ok {
+			err = cd.FormatContext(ctx, deviceName, fsType, overwriteFs)
+		} else {
+			err = runContext(ctx, func() error {
+				return d.Format(deviceName, fsType, overwriteFs)
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		r.rexray.publish(ctx, &Event{
+			Type:   EventVolumeFormatted,
+			Driver: d.Name(),
+			Device: &BlockDevice{DeviceName: deviceName},
+		})
+		return nil
 	}
 	return errors.ErrNoOSDetected
 }
+
+func (r *odm) ResizeFilesystem(deviceName, mountPoint string) error {
+	return r.ResizeFilesystemContext(
+		context.Background(), deviceName, mountPoint)
+}
+
+func (r *odm) ResizeFilesystemContext(
+	ctx context.Context, deviceName, mountPoint string) (err error) {
+	for _, d := range r.drivers {
+		log.WithFields(log.Fields{
+			"deviceName": deviceName,
+			"mountPoint": mountPoint,
+			"driverName": d.Name()}).Info("resizing filesystem")
+		if r.isNfsDevice(deviceName) {
+			return nil
+		}
+		defer r.rexray.auditOp(ctx, "ResizeFilesystem", d.Name(),
+			map[string]interface{}{
+				"deviceName": deviceName,
+				"mountPoint": mountPoint,
+			}, time.Now(), &err)
+
+		if err = runContext(ctx, func() error {
+			return d.ResizeFilesystem(deviceName, mountPoint)
+		}); err != nil {
+			return err
+		}
+
+		r.rexray.publish(ctx, &Event{
+			Type:       EventFilesystemResized,
+			Driver:     d.Name(),
+			MountPoint: mountPoint,
+			Device:     &BlockDevice{DeviceName: deviceName},
+		})
+		return nil
+	}
+	return errors.ErrNoOSDetected
+}
+
+func (r *odm) Freeze(mountPoint string) error {
+	return r.FreezeContext(context.Background(), mountPoint)
+}
+
+func (r *odm) FreezeContext(
+	ctx context.Context, mountPoint string) (err error) {
+	for _, d := range r.drivers {
+		log.WithFields(log.Fields{
+			"mountPoint": mountPoint,
+			"driverName": d.Name()}).Info("freezing filesystem")
+		defer r.rexray.auditOp(ctx, "Freeze", d.Name(),
+			map[string]interface{}{
+				"mountPoint": mountPoint,
+			}, time.Now(), &err)
+		return runContext(ctx, func() error {
+			return d.Freeze(mountPoint)
+		})
+	}
+	return errors.ErrNoOSDetected
+}
+
+func (r *odm) Thaw(mountPoint string) error {
+	return r.ThawContext(context.Background(), mountPoint)
+}
+
+func (r *odm) ThawContext(
+	ctx context.Context, mountPoint string) (err error) {
+	for _, d := range r.drivers {
+		log.WithFields(log.Fields{
+			"mountPoint": mountPoint,
+			"driverName": d.Name()}).Info("thawing filesystem")
+		defer r.rexray.auditOp(ctx, "Thaw", d.Name(),
+			map[string]interface{}{
+				"mountPoint": mountPoint,
+			}, time.Now(), &err)
+		return runContext(ctx, func() error {
+			return d.Thaw(mountPoint)
+		})
+	}
+	return errors.ErrNoOSDetected
+}
+
+func (r *odm) Stats(deviceName, mountPoint string) (*MountStats, error) {
+	return r.StatsContext(context.Background(), deviceName, mountPoint)
+}
+
+func (r *odm) StatsContext(
+	ctx context.Context,
+	deviceName, mountPoint string) (stats *MountStats, err error) {
+	for _, d := range r.drivers {
+		log.WithFields(log.Fields{
+			"deviceName": deviceName,
+			"mountPoint": mountPoint,
+			"driverName": d.Name()}).Debug("getting filesystem stats")
+		err = runContext(ctx, func() (err error) {
+			stats, err = d.Stats(deviceName, mountPoint)
+			return
+		})
+		return
+	}
+	return nil, errors.ErrNoOSDetected
+}
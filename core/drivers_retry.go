@@ -0,0 +1,663 @@
+package core
+
+import (
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core/errors"
+)
+
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+	defaultRetryResetTimeout   = 30 * time.Second
+)
+
+// RetryPolicy describes how the calls to a storage driver are retried and
+// when the driver's circuit breaker opens.
+type RetryPolicy struct {
+
+	// The maximum number of attempts for a call that only reads from the
+	// storage platform. A value less than two disables the retries.
+	ReadAttempts int
+
+	// The maximum number of attempts for a call that modifies the storage
+	// platform. Such calls are only retried when the error indicates that
+	// the request was not processed, for example because it was throttled or
+	// the connection was refused.
+	WriteAttempts int
+
+	// The delay before the first retry. The delay doubles with each retry,
+	// up to MaxBackoff, and a random jitter of up to half the delay is
+	// subtracted from it.
+	InitialBackoff time.Duration
+
+	// The maximum delay between two attempts.
+	MaxBackoff time.Duration
+
+	// The number of consecutive transient failures after which the circuit
+	// breaker opens and calls fail immediately. Zero disables the circuit
+	// breaker.
+	FailureThreshold int
+
+	// The amount of time the circuit breaker stays open before a single call
+	// is let through to probe the storage platform.
+	ResetTimeout time.Duration
+}
+
+// enabled returns a flag indicating whether or not the policy alters the
+// behavior of a storage driver.
+func (p *RetryPolicy) enabled() bool {
+	return p.ReadAttempts > 1 || p.WriteAttempts > 1 || p.FailureThreshold > 0
+}
+
+// retryPolicy returns the retry policy from the configuration.
+func (r *RexRay) retryPolicy() *RetryPolicy {
+	p := &RetryPolicy{}
+	p.ReadAttempts = r.Config.GetInt(
+		"rexray.storage.retry.reads.maxAttempts")
+	p.WriteAttempts = r.Config.GetInt(
+		"rexray.storage.retry.writes.maxAttempts")
+	p.FailureThreshold = r.Config.GetInt(
+		"rexray.storage.retry.circuitBreaker.threshold")
+	p.InitialBackoff = r.retryDuration(
+		"rexray.storage.retry.initialBackoff", defaultRetryInitialBackoff)
+	p.MaxBackoff = r.retryDuration(
+		"rexray.storage.retry.maxBackoff", defaultRetryMaxBackoff)
+	p.ResetTimeout = r.retryDuration(
+		"rexray.storage.retry.circuitBreaker.resetTimeout",
+		defaultRetryResetTimeout)
+	return p
+}
+
+func (r *RexRay) retryDuration(key string, dflt time.Duration) time.Duration {
+	v := r.Config.GetString(key)
+	if v == "" {
+		return dflt
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.WithFields(log.Fields{
+			"key":   key,
+			"value": v,
+			"error": err}).Warn("invalid duration")
+		return dflt
+	}
+	return d
+}
+
+// NewRetryStorageDriver returns a storage driver that retries the calls to
+// the provided driver according to the policy and stops calling it while its
+// circuit breaker is open. The returned driver implements the same optional
+// label and query interfaces as the provided driver, and it always implements
+// ContextStorageDriver, CapableStorageDriver, and AccessModeStorageDriver.
+func NewRetryStorageDriver(d StorageDriver, p *RetryPolicy) StorageDriver {
+	rd := &retryDriver{
+		StorageDriver: d,
+		policy:        p,
+		breaker:       &circuitBreaker{policy: p},
+	}
+
+	_, labels := d.(LabelStorageDriver)
+	_, query := d.(QueryStorageDriver)
+	switch {
+	case labels && query:
+		return &retryLabelQueryDriver{&retryLabelDriver{rd}}
+	case labels:
+		return &retryLabelDriver{rd}
+	case query:
+		return &retryQueryDriver{rd}
+	}
+	return rd
+}
+
+// retryDriver decorates a storage driver with retries and a circuit breaker.
+type retryDriver struct {
+	StorageDriver
+	policy  *RetryPolicy
+	breaker *circuitBreaker
+}
+
+type retryLabelDriver struct {
+	*retryDriver
+}
+
+type retryQueryDriver struct {
+	*retryDriver
+}
+
+type retryLabelQueryDriver struct {
+	*retryLabelDriver
+}
+
+// do invokes f until it succeeds, the error may not be retried, the attempts
+// are exhausted or the context is done.
+func (d *retryDriver) do(
+	ctx context.Context, op string, idempotent bool, f func() error) error {
+
+	attempts := d.policy.WriteAttempts
+	if idempotent {
+		attempts = d.policy.ReadAttempts
+	}
+
+	backoff := d.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		if !d.breaker.allow() {
+			return goof.WithFieldsE(goof.Fields{
+				"driverName": d.Name(),
+				"operation":  op,
+			}, "storage driver unavailable", errors.ErrCircuitOpen)
+		}
+
+		err := f()
+		if err == nil {
+			d.breaker.success()
+			return nil
+		}
+
+		transient := IsTransientError(err)
+		if transient {
+			d.breaker.failure()
+		} else {
+			d.breaker.success()
+		}
+
+		if attempt >= attempts || ctx.Err() != nil {
+			return err
+		}
+		if idempotent && !transient || !idempotent && !IsUnprocessedError(err) {
+			return err
+		}
+
+		driverCallRetries.WithLabelValues(d.Name(), op).Inc()
+
+		sleep := jitter(backoff)
+		log.WithFields(log.Fields{
+			"driverName": d.Name(),
+			"operation":  op,
+			"attempt":    attempt,
+			"backoff":    sleep,
+			"error":      err}).Warn("retrying storage driver call")
+
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return err
+		}
+
+		if backoff *= 2; backoff > d.policy.MaxBackoff {
+			backoff = d.policy.MaxBackoff
+		}
+	}
+}
+
+// jitter returns a random duration between half of and the full backoff.
+func jitter(backoff time.Duration) time.Duration {
+	if backoff <= 1 {
+		return backoff
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)))
+}
+
+func (d *retryDriver) Capabilities() *StorageCapabilities {
+	return GetStorageCapabilities(d.StorageDriver)
+}
+
+func (d *retryDriver) GetVolumeMapping() (bds []*BlockDevice, err error) {
+	err = d.do(context.Background(), "GetVolumeMapping", true,
+		func() (err error) {
+			bds, err = d.StorageDriver.GetVolumeMapping()
+			return
+		})
+	return
+}
+
+func (d *retryDriver) GetInstance() (i *Instance, err error) {
+	err = d.do(context.Background(), "GetInstance", true,
+		func() (err error) {
+			i, err = d.StorageDriver.GetInstance()
+			return
+		})
+	return
+}
+
+func (d *retryDriver) GetVolume(
+	volumeID, volumeName string) (volumes []*Volume, err error) {
+	err = d.do(context.Background(), "GetVolume", true,
+		func() (err error) {
+			volumes, err = d.StorageDriver.GetVolume(volumeID, volumeName)
+			return
+		})
+	return
+}
+
+func (d *retryDriver) GetVolumeAttach(
+	volumeID, instanceID string) (atts []*VolumeAttachment, err error) {
+	err = d.do(context.Background(), "GetVolumeAttach", true,
+		func() (err error) {
+			atts, err = d.StorageDriver.GetVolumeAttach(volumeID, instanceID)
+			return
+		})
+	return
+}
+
+func (d *retryDriver) GetSnapshot(
+	volumeID, snapshotID, snapshotName string) (
+	snapshots []*Snapshot, err error) {
+	err = d.do(context.Background(), "GetSnapshot", true,
+		func() (err error) {
+			snapshots, err = d.StorageDriver.GetSnapshot(
+				volumeID, snapshotID, snapshotName)
+			return
+		})
+	return
+}
+
+func (d *retryDriver) GetDeviceNextAvailable() (device string, err error) {
+	err = d.do(context.Background(), "GetDeviceNextAvailable", true,
+		func() (err error) {
+			device, err = d.StorageDriver.GetDeviceNextAvailable()
+			return
+		})
+	return
+}
+
+func (d *retryDriver) RemoveSnapshot(snapshotID string) error {
+	return d.do(context.Background(), "RemoveSnapshot", false,
+		func() error {
+			return d.StorageDriver.RemoveSnapshot(snapshotID)
+		})
+}
+
+func (d *retryDriver) CreateSnapshot(
+	runAsync bool,
+	snapshotName, volumeID, description string) ([]*Snapshot, error) {
+	return d.CreateSnapshotContext(
+		context.Background(), runAsync, snapshotName, volumeID, description)
+}
+
+func (d *retryDriver) CreateSnapshotContext(
+	ctx context.Context,
+	runAsync bool,
+	snapshotName, volumeID, description string) (
+	snapshots []*Snapshot, err error) {
+	err = d.do(ctx, "CreateSnapshot", false, func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			snapshots, err = cd.CreateSnapshotContext(
+				ctx, runAsync, snapshotName, volumeID, description)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			snapshots, err = d.StorageDriver.CreateSnapshot(
+				runAsync, snapshotName, volumeID, description)
+			return
+		})
+	})
+	return
+}
+
+func (d *retryDriver) CreateVolume(
+	runAsync bool,
+	volumeName, volumeID, snapshotID, volumeType string,
+	IOPS, size int64,
+	availabilityZone string) (*Volume, error) {
+	return d.CreateVolumeContext(
+		context.Background(), runAsync, volumeName, volumeID, snapshotID,
+		volumeType, IOPS, size, availabilityZone)
+}
+
+func (d *retryDriver) CreateVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeName, volumeID, snapshotID, volumeType string,
+	IOPS, size int64,
+	availabilityZone string) (volume *Volume, err error) {
+	err = d.do(ctx, "CreateVolume", false, func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			volume, err = cd.CreateVolumeContext(
+				ctx, runAsync, volumeName, volumeID, snapshotID, volumeType,
+				IOPS, size, availabilityZone)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			volume, err = d.StorageDriver.CreateVolume(
+				runAsync, volumeName, volumeID, snapshotID, volumeType,
+				IOPS, size, availabilityZone)
+			return
+		})
+	})
+	return
+}
+
+func (d *retryDriver) RemoveVolume(volumeID string) error {
+	return d.RemoveVolumeContext(context.Background(), volumeID)
+}
+
+func (d *retryDriver) RemoveVolumeContext(
+	ctx context.Context, volumeID string) error {
+	return d.do(ctx, "RemoveVolume", false, func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			return cd.RemoveVolumeContext(ctx, volumeID)
+		}
+		return runMutation(ctx, func() error {
+			return d.StorageDriver.RemoveVolume(volumeID)
+		})
+	})
+}
+
+func (d *retryDriver) AttachVolume(
+	runAsync bool,
+	volumeID, instanceID string, force bool) ([]*VolumeAttachment, error) {
+	return d.AttachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (d *retryDriver) AttachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string, force bool) ([]*VolumeAttachment, error) {
+	mode, _ := AccessModeFromContext(ctx)
+	return d.AttachVolumeMode(
+		ctx, runAsync, volumeID, instanceID, force, mode)
+}
+
+func (d *retryDriver) AttachVolumeMode(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string,
+	force bool, mode AccessMode) (atts []*VolumeAttachment, err error) {
+	err = d.do(ctx, "AttachVolume", false, func() error {
+		atts, err = attachVolumeMode(ctx, d.StorageDriver,
+			runAsync, volumeID, instanceID, force, mode)
+		return err
+	})
+	return
+}
+
+func (d *retryDriver) DetachVolume(
+	runAsync bool, volumeID, instanceID string, force bool) error {
+	return d.DetachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (d *retryDriver) DetachVolumeContext(
+	ctx context.Context,
+	runAsync bool, volumeID, instanceID string, force bool) error {
+	return d.do(ctx, "DetachVolume", false, func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			return cd.DetachVolumeContext(
+				ctx, runAsync, volumeID, instanceID, force)
+		}
+		return runMutation(ctx, func() error {
+			return d.StorageDriver.DetachVolume(
+				runAsync, volumeID, instanceID, force)
+		})
+	})
+}
+
+func (d *retryDriver) CopySnapshot(
+	runAsync bool, volumeID, snapshotID, snapshotName,
+	destinationSnapshotName, destinationRegion string) (*Snapshot, error) {
+	return d.CopySnapshotContext(
+		context.Background(), runAsync, volumeID, snapshotID, snapshotName,
+		destinationSnapshotName, destinationRegion)
+}
+
+func (d *retryDriver) CopySnapshotContext(
+	ctx context.Context,
+	runAsync bool, volumeID, snapshotID, snapshotName,
+	destinationSnapshotName, destinationRegion string) (
+	snapshot *Snapshot, err error) {
+	err = d.do(ctx, "CopySnapshot", false, func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			snapshot, err = cd.CopySnapshotContext(
+				ctx, runAsync, volumeID, snapshotID, snapshotName,
+				destinationSnapshotName, destinationRegion)
+			return err
+		}
+		return runMutation(ctx, func() (err error) {
+			snapshot, err = d.StorageDriver.CopySnapshot(
+				runAsync, volumeID, snapshotID, snapshotName,
+				destinationSnapshotName, destinationRegion)
+			return
+		})
+	})
+	return
+}
+
+func (d *retryDriver) ExpandVolume(volumeID string, newSize int64) error {
+	return d.ExpandVolumeContext(context.Background(), volumeID, newSize)
+}
+
+func (d *retryDriver) ExpandVolumeContext(
+	ctx context.Context, volumeID string, newSize int64) error {
+	return d.do(ctx, "ExpandVolume", false, func() error {
+		if cd, ok := d.StorageDriver.(ContextStorageDriver); ok {
+			return cd.ExpandVolumeContext(ctx, volumeID, newSize)
+		}
+		return runMutation(ctx, func() error {
+			return d.StorageDriver.ExpandVolume(volumeID, newSize)
+		})
+	})
+}
+
+func (d *retryDriver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) (snapshots []*Snapshot, err error) {
+	err = d.do(context.Background(), "CreateSnapshotGroup", false,
+		func() (err error) {
+			snapshots, err = d.StorageDriver.CreateSnapshotGroup(
+				runAsync, snapshotNames, description)
+			return
+		})
+	return
+}
+
+// Setting labels replaces the values of the provided keys, so the label calls
+// are retried like reads.
+
+func (d *retryLabelDriver) SetVolumeLabels(
+	volumeID string, labels map[string]string) error {
+	return d.do(context.Background(), "SetVolumeLabels", true, func() error {
+		return d.StorageDriver.(LabelStorageDriver).SetVolumeLabels(
+			volumeID, labels)
+	})
+}
+
+func (d *retryLabelDriver) SetSnapshotLabels(
+	snapshotID string, labels map[string]string) error {
+	return d.do(context.Background(), "SetSnapshotLabels", true, func() error {
+		return d.StorageDriver.(LabelStorageDriver).SetSnapshotLabels(
+			snapshotID, labels)
+	})
+}
+
+func (d *retryQueryDriver) GetVolumesByQuery(
+	query *VolumeQuery) ([]*Volume, error) {
+	return getVolumesByQuery(d.retryDriver, query)
+}
+
+func (d *retryLabelQueryDriver) GetVolumesByQuery(
+	query *VolumeQuery) ([]*Volume, error) {
+	return getVolumesByQuery(d.retryDriver, query)
+}
+
+func getVolumesByQuery(
+	d *retryDriver, query *VolumeQuery) (volumes []*Volume, err error) {
+	err = d.do(context.Background(), "GetVolumesByQuery", true,
+		func() (err error) {
+			volumes, err = d.StorageDriver.(QueryStorageDriver).
+				GetVolumesByQuery(query)
+			return
+		})
+	return
+}
+
+// circuitBreaker stops the calls to a storage platform after a number of
+// consecutive transient failures. Once the reset timeout elapses a single
+// call is let through; the breaker closes if it succeeds and opens again if
+// it fails.
+type circuitBreaker struct {
+	sync.Mutex
+	policy   *RetryPolicy
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	if b.policy.FailureThreshold <= 0 {
+		return true
+	}
+	b.Lock()
+	defer b.Unlock()
+	if b.failures < b.policy.FailureThreshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.policy.ResetTimeout {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.Lock()
+	defer b.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.Lock()
+	defer b.Unlock()
+	b.failures++
+	if b.probing || b.failures == b.policy.FailureThreshold {
+		b.openedAt = time.Now()
+	}
+	b.probing = false
+}
+
+var (
+	throttledErrors = []string{
+		"throttl",
+		"requestlimitexceeded",
+		"too many requests",
+		"rate exceeded",
+		"slowdown",
+		"slow down",
+	}
+
+	unavailableErrors = []string{
+		"internalerror",
+		"internal server error",
+		"serviceunavailable",
+		"service unavailable",
+		"bad gateway",
+		"gateway timeout",
+		"connection reset",
+		"broken pipe",
+		"i/o timeout",
+		"tls handshake timeout",
+		"unexpected eof",
+	}
+
+	unreachableErrors = []string{
+		"connection refused",
+		"no such host",
+		"network is unreachable",
+		"no route to host",
+	}
+)
+
+// IsTransientError returns a flag indicating whether or not the error is the
+// result of a condition, such as throttling, an unavailable service or a
+// network failure, that may not occur if the call is made again.
+func IsTransientError(err error) bool {
+	if err == nil || isContextError(err) {
+		return false
+	}
+	if IsUnprocessedError(err) {
+		return true
+	}
+	for _, e := range errorChain(err) {
+		if ne, ok := e.(net.Error); ok && (ne.Timeout() || ne.Temporary()) {
+			return true
+		}
+		if containsAny(e.Error(), unavailableErrors) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnprocessedError returns a flag indicating whether or not the error
+// indicates that the storage platform did not process the request, either
+// because the request was throttled or because the platform could not be
+// reached. Calls that modify the storage platform are only retried on these
+// errors.
+func IsUnprocessedError(err error) bool {
+	if err == nil || isContextError(err) {
+		return false
+	}
+	for _, e := range errorChain(err) {
+		if oe, ok := e.(*net.OpError); ok && oe.Op == "dial" {
+			return true
+		}
+		msg := e.Error()
+		if containsAny(msg, throttledErrors) ||
+			containsAny(msg, unreachableErrors) {
+			return true
+		}
+	}
+	return false
+}
+
+func isContextError(err error) bool {
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
+
+func containsAny(s string, substrs []string) bool {
+	s = strings.ToLower(s)
+	for _, ss := range substrs {
+		if strings.Contains(s, ss) {
+			return true
+		}
+	}
+	return false
+}
+
+// fielder is implemented by errors that carry fields, such as the errors
+// created with goof.
+type fielder interface {
+	Fields() map[string]interface{}
+}
+
+// errorChain returns the error and the errors it wraps.
+func errorChain(err error) []error {
+	var chain []error
+	for err != nil && len(chain) < 16 {
+		chain = append(chain, err)
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case fielder:
+			err, _ = e.Fields()["inner"].(error)
+		default:
+			err = nil
+		}
+	}
+	return chain
+}
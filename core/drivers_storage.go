@@ -2,7 +2,14 @@ package core
 
 import (
 	"bytes"
+	"strings"
 	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"github.com/akutz/gotil"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core/errors"
 )
@@ -71,6 +78,9 @@ type Snapshot struct {
 
 	// The status of the snapshot.
 	Status string
+
+	// The snapshot's labels.
+	Labels map[string]string
 }
 
 // Volume provides information about a storage volume.
@@ -102,6 +112,9 @@ type Volume struct {
 
 	// The volume's attachments.
 	Attachments []*VolumeAttachment
+
+	// The volume's labels.
+	Labels map[string]string
 }
 
 // VolumeAttachment provides information about an object attached to a
@@ -188,30 +201,124 @@ type StorageDriver interface {
 	CopySnapshot(
 		runAsync bool, volumeID, snapshotID, snapshotName,
 		destinationSnapshotName, destinationRegion string) (*Snapshot, error)
+
+	// ExpandVolume grows the volume of volumeID to newSize GB. The volume's
+	// filesystem is not resized.
+	ExpandVolume(volumeID string, newSize int64) error
+
+	// CreateSnapshotGroup snapshots several volumes at the same point in
+	// time. The snapshotNames map the ID of each volume to the name of its
+	// snapshot.
+	CreateSnapshotGroup(
+		runAsync bool,
+		snapshotNames map[string]string,
+		description string) ([]*Snapshot, error)
 }
 
 // StorageDriverManager acts as both a StorageDriverManager and as an aggregate
 // of storage drivers, providing batch methods.
 type StorageDriverManager interface {
-	StorageDriver
+	ContextStorageDriver
 
 	// Drivers gets a channel which receives a list of all of the configured
 	// storage drivers.
 	Drivers() <-chan StorageDriver
 
+	// Driver gets the configured storage driver with the provided name.
+	Driver(name string) (StorageDriver, error)
+
 	// GetInstances gets the instance for each of the configured drivers.
 	GetInstances() ([]*Instance, error)
+
+	// GetInstancesContext is GetInstances with a context.
+	GetInstancesContext(ctx context.Context) ([]*Instance, error)
+
+	// GetVolumeInstanceContext gets the instance of the driver that owns the
+	// volume with the provided ID.
+	GetVolumeInstanceContext(
+		ctx context.Context, volumeID string) (*Instance, error)
+
+	// GetVolumeContext is GetVolume with a context.
+	GetVolumeContext(
+		ctx context.Context, volumeID, volumeName string) ([]*Volume, error)
+
+	// QueryVolumes returns a page of the volumes from all of the drivers that
+	// match the query.
+	QueryVolumes(query *VolumeQuery) (*VolumeQueryResult, error)
+
+	// QueryVolumesContext is QueryVolumes with a context.
+	QueryVolumesContext(
+		ctx context.Context, query *VolumeQuery) (*VolumeQueryResult, error)
+
+	// GetVolumeAttachContext is GetVolumeAttach with a context.
+	GetVolumeAttachContext(
+		ctx context.Context,
+		volumeID, instanceID string) ([]*VolumeAttachment, error)
+
+	// GetSnapshotContext is GetSnapshot with a context.
+	GetSnapshotContext(
+		ctx context.Context,
+		volumeID, snapshotID, snapshotName string) ([]*Snapshot, error)
+
+	// CreateSnapshotGroupContext is CreateSnapshotGroup with a context.
+	CreateSnapshotGroupContext(
+		ctx context.Context,
+		runAsync bool,
+		snapshotNames map[string]string,
+		description string) ([]*Snapshot, error)
+
+	// GetSnapshotGroup returns the snapshots in the snapshot group with the
+	// provided ID.
+	GetSnapshotGroup(groupID string) ([]*Snapshot, error)
+
+	// GetSnapshotGroupContext is GetSnapshotGroup with a context.
+	GetSnapshotGroupContext(
+		ctx context.Context, groupID string) ([]*Snapshot, error)
+
+	// RestoreSnapshotGroup creates a volume from each of the snapshots in the
+	// snapshot group with the provided ID. Each volume is named after the
+	// volume of which its snapshot was taken, followed by the suffix.
+	RestoreSnapshotGroup(
+		runAsync bool, groupID, volumeNameSuffix string) ([]*Volume, error)
+
+	// RestoreSnapshotGroupContext is RestoreSnapshotGroup with a context.
+	RestoreSnapshotGroupContext(
+		ctx context.Context,
+		runAsync bool, groupID, volumeNameSuffix string) ([]*Volume, error)
 }
 
 type sdm struct {
 	rexray  *RexRay
 	drivers map[string]StorageDriver
+	names   []string
+
+	idxLock sync.RWMutex
+	volIdx  map[string]StorageDriver
+	snapIdx map[string]StorageDriver
+
+	cache *storageCache
 }
 
 func (r *sdm) Init(rexray *RexRay) error {
 	if len(r.drivers) == 0 {
 		return errors.ErrNoStorageDrivers
 	}
+
+	// the names of the drivers are ordered by their position in the
+	// rexray.storageDrivers list so that iterating the drivers is stable
+	for _, n := range r.rexray.Config.GetStringSlice("rexray.storageDrivers") {
+		for dn := range r.drivers {
+			if strings.EqualFold(n, dn) && !gotil.StringInSlice(dn, r.names) {
+				r.names = append(r.names, dn)
+			}
+		}
+	}
+	for dn := range r.drivers {
+		if !gotil.StringInSlice(dn, r.names) {
+			r.names = append(r.names, dn)
+		}
+	}
+
 	return nil
 }
 
@@ -233,19 +340,159 @@ func (r *sdm) Drivers() <-chan StorageDriver {
 			close(c)
 			return
 		}
-		for _, v := range r.drivers {
-			c <- v
+		for _, n := range r.driverNames() {
+			c <- r.drivers[n]
 		}
 		close(c)
 	}()
 	return c
 }
 
+func (r *sdm) Driver(name string) (StorageDriver, error) {
+	if len(r.drivers) == 0 {
+		return nil, errors.ErrNoStorageDetected
+	}
+	for n, d := range r.drivers {
+		if strings.EqualFold(n, name) {
+			return d, nil
+		}
+	}
+	return nil, goof.WithField(
+		"driverName", name, "storage driver not configured")
+}
+
+// Capabilities returns the capabilities of the default driver.
+func (r *sdm) Capabilities() *StorageCapabilities {
+	d, err := r.defaultDriver()
+	if err != nil {
+		return nil
+	}
+	return GetStorageCapabilities(d)
+}
+
+// ignoredFlags logs the runAsync and force flags that are set but not
+// honored by the driver.
+func ignoredFlags(d StorageDriver, runAsync, force bool) {
+	c := GetStorageCapabilities(d)
+	if runAsync && !c.Async {
+		log.WithField("driverName", d.Name()).Warn(
+			"runAsync not supported by storage driver; ignoring")
+	}
+	if force && !c.ForceAttach {
+		log.WithField("driverName", d.Name()).Warn(
+			"force not supported by storage driver; ignoring")
+	}
+}
+
+// driverNames returns the names of the drivers in a stable order. The names
+// are only ordered once the manager is initialized, so until then the map's
+// keys are used.
+func (r *sdm) driverNames() []string {
+	if len(r.names) == len(r.drivers) {
+		return r.names
+	}
+	var names []string
+	for n := range r.drivers {
+		names = append(names, n)
+	}
+	return names
+}
+
+// defaultDriver returns the driver to which calls are routed when there is
+// no volume or snapshot ID with which to determine the owning driver. This is
+// the driver named by rexray.storage.defaultDriver or, if that is not set,
+// the first of the configured storage drivers.
+func (r *sdm) defaultDriver() (StorageDriver, error) {
+	if len(r.drivers) == 0 {
+		return nil, errors.ErrNoStorageDetected
+	}
+	if n := r.rexray.Config.GetString(
+		"rexray.storage.defaultDriver"); n != "" {
+		return r.Driver(n)
+	}
+	return r.drivers[r.driverNames()[0]], nil
+}
+
+// volumeDriver returns the driver that owns the volume with the provided ID.
+// The index is consulted first, and if the volume is not yet known then the
+// drivers are queried for it. If no driver claims the volume then the
+// default driver is returned.
+func (r *sdm) volumeDriver(
+	ctx context.Context, volumeID string) (StorageDriver, error) {
+	if len(r.drivers) == 0 {
+		return nil, errors.ErrNoStorageDetected
+	}
+	if volumeID == "" {
+		return r.defaultDriver()
+	}
+	if d, ok := r.indexed(r.volIdx, volumeID); ok {
+		return d, nil
+	}
+	if _, err := r.GetVolumeContext(ctx, volumeID, ""); err != nil {
+		log.WithFields(log.Fields{
+			"volumeID": volumeID,
+			"error":    err}).Debug("error discovering volume's driver")
+	}
+	if d, ok := r.indexed(r.volIdx, volumeID); ok {
+		return d, nil
+	}
+	return r.defaultDriver()
+}
+
+// snapshotDriver returns the driver that owns the snapshot with the provided
+// ID in the same manner as volumeDriver.
+func (r *sdm) snapshotDriver(
+	ctx context.Context, snapshotID string) (StorageDriver, error) {
+	if len(r.drivers) == 0 {
+		return nil, errors.ErrNoStorageDetected
+	}
+	if snapshotID == "" {
+		return r.defaultDriver()
+	}
+	if d, ok := r.indexed(r.snapIdx, snapshotID); ok {
+		return d, nil
+	}
+	if _, err := r.GetSnapshotContext(
+		ctx, "", snapshotID, ""); err != nil {
+		log.WithFields(log.Fields{
+			"snapshotID": snapshotID,
+			"error":      err}).Debug("error discovering snapshot's driver")
+	}
+	if d, ok := r.indexed(r.snapIdx, snapshotID); ok {
+		return d, nil
+	}
+	return r.defaultDriver()
+}
+
+func (r *sdm) indexed(
+	idx map[string]StorageDriver, id string) (StorageDriver, bool) {
+	r.idxLock.RLock()
+	defer r.idxLock.RUnlock()
+	d, ok := idx[id]
+	return d, ok
+}
+
+func (r *sdm) index(idx map[string]StorageDriver, id string, d StorageDriver) {
+	if id == "" {
+		return
+	}
+	r.idxLock.Lock()
+	defer r.idxLock.Unlock()
+	idx[id] = d
+}
+
+func (r *sdm) unindex(idx map[string]StorageDriver, id string) {
+	r.idxLock.Lock()
+	defer r.idxLock.Unlock()
+	delete(idx, id)
+}
+
 // GetVolumeMapping performs storage introspection and
 // returns a listing of block devices from the guest
 func (r *sdm) GetVolumeMapping() ([]*BlockDevice, error) {
 	var allBlockDevices []*BlockDevice
-	for _, driver := range r.drivers {
+	for _, n := range r.driverNames() {
+		driver := r.drivers[n]
 		blockDevices, err := driver.GetVolumeMapping()
 		if err != nil {
 			return []*BlockDevice{}, err
@@ -253,6 +500,7 @@ func (r *sdm) GetVolumeMapping() ([]*BlockDevice, error) {
 
 		if len(blockDevices) > 0 {
 			for _, blockDevice := range blockDevices {
+				r.index(r.volIdx, blockDevice.VolumeID, driver)
 				allBlockDevices = append(allBlockDevices, blockDevice)
 			}
 		}
@@ -282,7 +530,7 @@ func (r *sdm) GetInstances() ([]*Instance, error) {
 				defer wg.Done()
 				var e error
 				var i *Instance
-				i, e = d.GetInstance()
+				i, e = r.getInstance(d)
 				if e != nil {
 					cE <- e
 				} else {
@@ -314,100 +562,668 @@ func (r *sdm) GetInstances() ([]*Instance, error) {
 	}
 }
 
+func (r *sdm) GetInstancesContext(ctx context.Context) ([]*Instance, error) {
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.get")
+	defer cancel()
+
+	var instances []*Instance
+	if err := runContext(ctx, func() (err error) {
+		instances, err = r.GetInstances()
+		return
+	}); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// GetVolumeInstanceContext returns the instance of the driver that owns the
+// volume with the provided ID.
+func (r *sdm) GetVolumeInstanceContext(
+	ctx context.Context, volumeID string) (*Instance, error) {
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.get")
+	defer cancel()
+
+	d, err := r.volumeDriver(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var instance *Instance
+	if err := runContext(ctx, func() (err error) {
+		instance, err = r.getInstance(d)
+		return
+	}); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
 func (r *sdm) GetInstance() (*Instance, error) {
-	for _, d := range r.drivers {
-		return d.GetInstance()
+	d, err := r.defaultDriver()
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.ErrNoStorageDetected
+	return r.getInstance(d)
 }
 
+// GetVolume queries all of the drivers and merges the results. An error is
+// returned only if every driver fails.
 func (r *sdm) GetVolume(volumeID, volumeName string) ([]*Volume, error) {
-	for _, d := range r.drivers {
-		return d.GetVolume(volumeID, volumeName)
+	return r.GetVolumeContext(context.Background(), volumeID, volumeName)
+}
+
+func (r *sdm) GetVolumeContext(
+	ctx context.Context, volumeID, volumeName string) ([]*Volume, error) {
+	if len(r.drivers) == 0 {
+		return nil, errors.ErrNoStorageDetected
+	}
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.get")
+	defer cancel()
+
+	driverName := StorageDriverFromContext(ctx)
+	if driverName != "" {
+		if _, err := r.Driver(driverName); err != nil {
+			return nil, err
+		}
 	}
-	return nil, errors.ErrNoStorageDetected
+	selector := LabelSelectorFromContext(ctx)
+
+	var firstErr error
+	var failed, queried int
+	var allVolumes []*Volume
+
+	for _, n := range r.driverNames() {
+		if driverName != "" && !strings.EqualFold(n, driverName) {
+			continue
+		}
+		queried++
+		d := r.drivers[n]
+		var volumes []*Volume
+		err := runContext(ctx, func() (err error) {
+			volumes, err = r.getVolume(d, volumeID, volumeName)
+			return
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"driverName": n,
+				"volumeID":   volumeID,
+				"volumeName": volumeName,
+				"error":      err}).Warn("error getting volumes")
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		for _, v := range volumes {
+			r.index(r.volIdx, v.VolumeID, d)
+			if MatchLabels(v.Labels, selector) {
+				allVolumes = append(allVolumes, v)
+			}
+		}
+	}
+
+	if failed == queried {
+		return nil, firstErr
+	}
+
+	return allVolumes, nil
 }
 
-func (r *sdm) GetSnapshot(volumeID, snapshotID, snapshotName string) ([]*Snapshot, error) {
-	for _, d := range r.drivers {
-		return d.GetSnapshot(volumeID, snapshotID, snapshotName)
+// GetSnapshot queries all of the drivers and merges the results. An error is
+// returned only if every driver fails.
+func (r *sdm) GetSnapshot(
+	volumeID, snapshotID, snapshotName string) ([]*Snapshot, error) {
+	return r.GetSnapshotContext(
+		context.Background(), volumeID, snapshotID, snapshotName)
+}
+
+func (r *sdm) GetSnapshotContext(
+	ctx context.Context,
+	volumeID, snapshotID, snapshotName string) ([]*Snapshot, error) {
+	if len(r.drivers) == 0 {
+		return nil, errors.ErrNoStorageDetected
 	}
-	return nil, errors.ErrNoStorageDetected
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.get")
+	defer cancel()
+
+	var firstErr error
+	var failed int
+	var allSnapshots []*Snapshot
+
+	for _, n := range r.driverNames() {
+		d := r.drivers[n]
+		var snapshots []*Snapshot
+		err := runContext(ctx, func() (err error) {
+			snapshots, err = getSnapshot(
+				d, volumeID, snapshotID, snapshotName)
+			return
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"driverName":   n,
+				"volumeID":     volumeID,
+				"snapshotID":   snapshotID,
+				"snapshotName": snapshotName,
+				"error":        err}).Warn("error getting snapshots")
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		for _, s := range snapshots {
+			r.index(r.snapIdx, s.SnapshotID, d)
+			allSnapshots = append(allSnapshots, s)
+		}
+	}
+
+	if failed == len(r.drivers) {
+		return nil, firstErr
+	}
+
+	return allSnapshots, nil
 }
 
 func (r *sdm) CreateSnapshot(runAsync bool,
 	snapshotName, volumeID, description string) ([]*Snapshot, error) {
-	for _, d := range r.drivers {
-		return d.CreateSnapshot(runAsync, snapshotName, volumeID, description)
+	return r.CreateSnapshotContext(context.Background(),
+		runAsync, snapshotName, volumeID, description)
+}
+
+func (r *sdm) CreateSnapshotContext(ctx context.Context, runAsync bool,
+	snapshotName, volumeID, description string) (
+	snapshots []*Snapshot, err error) {
+
+	ctx, cancel := r.rexray.withTimeout(
+		ctx, "rexray.storage.timeouts.snapshot")
+	defer cancel()
+
+	d, err := r.volumeDriver(ctx, volumeID)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.ErrNoStorageDetected
+	if err := CheckStorageCapability(d, CapabilitySnapshots); err != nil {
+		return nil, err
+	}
+	ignoredFlags(d, runAsync, false)
+	defer r.rexray.auditOp(ctx, "CreateSnapshot", d.Name(),
+		map[string]interface{}{
+			"snapshotName": snapshotName,
+			"volumeID":     volumeID,
+			"description":  description,
+			"runAsync":     runAsync,
+		}, time.Now(), &err)
+
+	labels := LabelsFromContext(ctx)
+	ld, native := d.(LabelStorageDriver)
+	if !native {
+		if snapshotName, err = labelName(snapshotName, labels); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.rexray.quiesce(ctx) {
+		if runAsync {
+			return nil, errQuiesceAsync(volumeID)
+		}
+		thaw, err := r.rexray.quiesceVolume(ctx, volumeID)
+		if err != nil {
+			return nil, err
+		}
+		defer thaw()
+	}
+
+	if cd, ok := d.(ContextStorageDriver); ok {
+		snapshots, err = cd.CreateSnapshotContext(
+			ctx, runAsync, snapshotName, volumeID, description)
+	} else {
+		err = runMutation(ctx, func() (err error) {
+			snapshots, err = d.CreateSnapshot(
+				runAsync, snapshotName, volumeID, description)
+			return
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range snapshots {
+		r.index(r.snapIdx, s.SnapshotID, d)
+		labelSnapshot(d, s)
+		if native && len(labels) > 0 {
+			if err := ld.SetSnapshotLabels(s.SnapshotID, labels); err != nil {
+				return nil, err
+			}
+			s.Labels = mergeLabels(s.Labels, labels)
+		}
+	}
+	return snapshots, nil
 }
 
-func (r *sdm) RemoveSnapshot(snapshotID string) error {
-	for _, d := range r.drivers {
+func (r *sdm) RemoveSnapshot(snapshotID string) (err error) {
+	ctx, cancel := r.rexray.withTimeout(
+		context.Background(), "rexray.storage.timeouts.remove")
+	defer cancel()
+
+	d, err := r.snapshotDriver(ctx, snapshotID)
+	if err != nil {
+		return err
+	}
+	defer r.rexray.auditOp(ctx, "RemoveSnapshot", d.Name(),
+		map[string]interface{}{
+			"snapshotID": snapshotID,
+		}, time.Now(), &err)
+	if err := runMutation(ctx, func() error {
 		return d.RemoveSnapshot(snapshotID)
+	}); err != nil {
+		return err
 	}
-	return errors.ErrNoStorageDetected
+	r.unindex(r.snapIdx, snapshotID)
+	return nil
 }
 
+// CreateVolume routes the request to the driver that owns the source volume
+// or snapshot, if either is specified, otherwise the driver named with
+// WithStorageDriver or the default driver.
 func (r *sdm) CreateVolume(runAsync bool,
 	volumeName, volumeID, snapshotID, volumeType string,
 	IOPS, size int64, availabilityZone string) (*Volume, error) {
-	for _, d := range r.drivers {
-		return d.CreateVolume(
-			runAsync, volumeName, volumeID, snapshotID, volumeType,
+	return r.CreateVolumeContext(context.Background(),
+		runAsync, volumeName, volumeID, snapshotID, volumeType,
+		IOPS, size, availabilityZone)
+}
+
+func (r *sdm) CreateVolumeContext(ctx context.Context, runAsync bool,
+	volumeName, volumeID, snapshotID, volumeType string,
+	IOPS, size int64, availabilityZone string) (volume *Volume, err error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.create")
+	defer cancel()
+
+	var d StorageDriver
+
+	switch {
+	case volumeID != "":
+		d, err = r.volumeDriver(ctx, volumeID)
+	case snapshotID != "":
+		d, err = r.snapshotDriver(ctx, snapshotID)
+	case StorageDriverFromContext(ctx) != "":
+		d, err = r.Driver(StorageDriverFromContext(ctx))
+	default:
+		d, err = r.defaultDriver()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckCreateVolume(
+		d, volumeType, IOPS, availabilityZone); err != nil {
+		return nil, err
+	}
+	ignoredFlags(d, runAsync, false)
+	defer r.cache.invalidate(d.Name())
+	defer r.rexray.auditOp(ctx, "CreateVolume", d.Name(),
+		map[string]interface{}{
+			"volumeName":       volumeName,
+			"volumeID":         volumeID,
+			"snapshotID":       snapshotID,
+			"volumeType":       volumeType,
+			"IOPS":             IOPS,
+			"size":             size,
+			"availabilityZone": availabilityZone,
+			"runAsync":         runAsync,
+		}, time.Now(), &err)
+
+	labels := LabelsFromContext(ctx)
+	ld, native := d.(LabelStorageDriver)
+	if !native {
+		if volumeName, err = labelName(volumeName, labels); err != nil {
+			return nil, err
+		}
+	}
+
+	if cd, ok := d.(ContextStorageDriver); ok {
+		volume, err = cd.CreateVolumeContext(
+			ctx, runAsync, volumeName, volumeID, snapshotID, volumeType,
 			IOPS, size, availabilityZone)
+	} else {
+		err = runMutation(ctx, func() (err error) {
+			volume, err = d.CreateVolume(
+				runAsync, volumeName, volumeID, snapshotID, volumeType,
+				IOPS, size, availabilityZone)
+			return
+		})
+	}
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.ErrNoStorageDetected
+
+	if volume != nil {
+		r.index(r.volIdx, volume.VolumeID, d)
+		labelVolume(d, volume)
+		if native && len(labels) > 0 {
+			if err := ld.SetVolumeLabels(volume.VolumeID, labels); err != nil {
+				return nil, err
+			}
+			volume.Labels = mergeLabels(volume.Labels, labels)
+		}
+	}
+
+	e := &Event{
+		Type:       EventVolumeCreated,
+		Driver:     d.Name(),
+		VolumeName: volumeName,
+		Volume:     volume,
+	}
+	if volume != nil {
+		e.VolumeID = volume.VolumeID
+	}
+	r.rexray.publish(ctx, e)
+
+	return volume, nil
 }
 
 func (r *sdm) RemoveVolume(volumeID string) error {
-	for _, d := range r.drivers {
-		return d.RemoveVolume(volumeID)
+	return r.RemoveVolumeContext(context.Background(), volumeID)
+}
+
+func (r *sdm) RemoveVolumeContext(
+	ctx context.Context, volumeID string) (err error) {
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.remove")
+	defer cancel()
+
+	d, err := r.volumeDriver(ctx, volumeID)
+	if err != nil {
+		return err
 	}
-	return errors.ErrNoStorageDetected
+	defer r.cache.invalidate(d.Name())
+	defer r.rexray.auditOp(ctx, "RemoveVolume", d.Name(),
+		map[string]interface{}{
+			"volumeID": volumeID,
+		}, time.Now(), &err)
+
+	if cd, ok := d.(ContextStorageDriver); ok {
+		err = cd.RemoveVolumeContext(ctx, volumeID)
+	} else {
+		err = runMutation(ctx, func() error {
+			return d.RemoveVolume(volumeID)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	r.unindex(r.volIdx, volumeID)
+	r.rexray.publish(ctx, &Event{
+		Type:     EventVolumeRemoved,
+		Driver:   d.Name(),
+		VolumeID: volumeID,
+	})
+	return nil
 }
 
 func (r *sdm) AttachVolume(
 	runAsync bool,
 	volumeID, instanceID string, force bool) ([]*VolumeAttachment, error) {
-	for _, d := range r.drivers {
-		return d.AttachVolume(runAsync, volumeID, instanceID, force)
+	return r.AttachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (r *sdm) AttachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string, force bool) (
+	attachments []*VolumeAttachment, err error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.attach")
+	defer cancel()
+
+	d, err := r.volumeDriver(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	mode, _ := AccessModeFromContext(ctx)
+	if err := CheckAccessMode(d, mode); err != nil {
+		return nil, err
+	}
+	ignoredFlags(d, runAsync, force)
+	defer r.cache.invalidate(d.Name())
+	defer r.rexray.auditOp(ctx, "AttachVolume", d.Name(),
+		map[string]interface{}{
+			"volumeID":   volumeID,
+			"instanceID": instanceID,
+			"force":      force,
+			"runAsync":   runAsync,
+			"accessMode": mode,
+		}, time.Now(), &err)
+
+	attachments, err = attachVolumeMode(
+		ctx, d, runAsync, volumeID, instanceID, force, mode)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.ErrNoStorageDetected
+
+	r.rexray.publish(ctx, &Event{
+		Type:        EventVolumeAttached,
+		Driver:      d.Name(),
+		VolumeID:    volumeID,
+		InstanceID:  instanceID,
+		Attachments: attachments,
+	})
+	return attachments, nil
 }
 
 func (r *sdm) DetachVolume(
 	runAsync bool,
 	volumeID, instanceID string, force bool) error {
-	for _, d := range r.drivers {
-		return d.DetachVolume(runAsync, volumeID, instanceID, force)
+	return r.DetachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (r *sdm) DetachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string, force bool) (err error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.detach")
+	defer cancel()
+
+	d, err := r.volumeDriver(ctx, volumeID)
+	if err != nil {
+		return err
+	}
+	ignoredFlags(d, runAsync, false)
+	defer r.cache.invalidate(d.Name())
+	defer r.rexray.auditOp(ctx, "DetachVolume", d.Name(),
+		map[string]interface{}{
+			"volumeID":   volumeID,
+			"instanceID": instanceID,
+			"force":      force,
+			"runAsync":   runAsync,
+		}, time.Now(), &err)
+
+	if cd, ok := d.(ContextStorageDriver); ok {
+		err = cd.DetachVolumeContext(
+			ctx, runAsync, volumeID, instanceID, force)
+	} else {
+		err = runMutation(ctx, func() error {
+			return d.DetachVolume(runAsync, volumeID, instanceID, force)
+		})
 	}
-	return errors.ErrNoStorageDetected
+	if err != nil {
+		return err
+	}
+
+	r.rexray.publish(ctx, &Event{
+		Type:       EventVolumeDetached,
+		Driver:     d.Name(),
+		VolumeID:   volumeID,
+		InstanceID: instanceID,
+	})
+	return nil
 }
 
 func (r *sdm) GetVolumeAttach(
 	volumeID, instanceID string) ([]*VolumeAttachment, error) {
-	for _, d := range r.drivers {
-		return d.GetVolumeAttach(volumeID, instanceID)
+	return r.GetVolumeAttachContext(
+		context.Background(), volumeID, instanceID)
+}
+
+func (r *sdm) GetVolumeAttachContext(
+	ctx context.Context,
+	volumeID, instanceID string) ([]*VolumeAttachment, error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.get")
+	defer cancel()
+
+	d, err := r.volumeDriver(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []*VolumeAttachment
+	if err := runContext(ctx, func() (err error) {
+		attachments, err = r.getVolumeAttach(d, volumeID, instanceID)
+		return
+	}); err != nil {
+		return nil, err
 	}
-	return nil, errors.ErrNoStorageDetected
+	return attachments, nil
 }
 
+// CopySnapshot routes the request to the driver that owns the source
+// snapshot or volume. When the source is identified only by name the drivers
+// are queried for it.
 func (r *sdm) CopySnapshot(
 	runAsync bool,
 	volumeID, snapshotID, snapshotName,
 	targetSnapshotName, targetRegion string) (*Snapshot, error) {
-	for _, d := range r.drivers {
-		return d.CopySnapshot(runAsync, volumeID, snapshotID, snapshotName,
-			targetSnapshotName, targetRegion)
+	return r.CopySnapshotContext(context.Background(),
+		runAsync, volumeID, snapshotID, snapshotName,
+		targetSnapshotName, targetRegion)
+}
+
+func (r *sdm) CopySnapshotContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, snapshotID, snapshotName,
+	targetSnapshotName, targetRegion string) (
+	snapshot *Snapshot, err error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.copy")
+	defer cancel()
+
+	var d StorageDriver
+
+	switch {
+	case snapshotID != "":
+		d, err = r.snapshotDriver(ctx, snapshotID)
+	case volumeID != "":
+		d, err = r.volumeDriver(ctx, volumeID)
+	case snapshotName != "":
+		var snapshots []*Snapshot
+		if snapshots, err = r.GetSnapshotContext(
+			ctx, "", "", snapshotName); err == nil && len(snapshots) > 0 {
+			d, err = r.snapshotDriver(ctx, snapshots[0].SnapshotID)
+		} else {
+			d, err = r.defaultDriver()
+		}
+	default:
+		d, err = r.defaultDriver()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckStorageCapability(d, CapabilityCopySnapshot); err != nil {
+		return nil, err
 	}
-	return nil, errors.ErrNoStorageDetected
+	ignoredFlags(d, runAsync, false)
+	defer r.rexray.auditOp(ctx, "CopySnapshot", d.Name(),
+		map[string]interface{}{
+			"volumeID":           volumeID,
+			"snapshotID":         snapshotID,
+			"snapshotName":       snapshotName,
+			"targetSnapshotName": targetSnapshotName,
+			"targetRegion":       targetRegion,
+			"runAsync":           runAsync,
+		}, time.Now(), &err)
+
+	if cd, ok := d.(ContextStorageDriver); ok {
+		snapshot, err = cd.CopySnapshotContext(ctx, runAsync, volumeID,
+			snapshotID, snapshotName, targetSnapshotName, targetRegion)
+	} else {
+		err = runMutation(ctx, func() (err error) {
+			snapshot, err = d.CopySnapshot(runAsync, volumeID, snapshotID,
+				snapshotName, targetSnapshotName, targetRegion)
+			return
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot != nil && targetRegion == "" {
+		r.index(r.snapIdx, snapshot.SnapshotID, d)
+	}
+	return snapshot, nil
+}
+
+func (r *sdm) ExpandVolume(volumeID string, newSize int64) error {
+	return r.ExpandVolumeContext(context.Background(), volumeID, newSize)
+}
+
+func (r *sdm) ExpandVolumeContext(
+	ctx context.Context, volumeID string, newSize int64) (err error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.expand")
+	defer cancel()
+
+	if volumeID == "" {
+		return errors.ErrMissingVolumeID
+	}
+	if newSize <= 0 {
+		return errors.ErrInvalidVolumeSize
+	}
+
+	d, err := r.volumeDriver(ctx, volumeID)
+	if err != nil {
+		return err
+	}
+	if err := CheckStorageCapability(d, CapabilityExpandVolume); err != nil {
+		return err
+	}
+	defer r.cache.invalidate(d.Name())
+	defer r.rexray.auditOp(ctx, "ExpandVolume", d.Name(),
+		map[string]interface{}{
+			"volumeID": volumeID,
+			"newSize":  newSize,
+		}, time.Now(), &err)
+
+	if cd, ok := d.(ContextStorageDriver); ok {
+		err = cd.ExpandVolumeContext(ctx, volumeID, newSize)
+	} else {
+		err = runMutation(ctx, func() error {
+			return d.ExpandVolume(volumeID, newSize)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	r.rexray.publish(ctx, &Event{
+		Type:     EventVolumeExpanded,
+		Driver:   d.Name(),
+		VolumeID: volumeID,
+	})
+	return nil
 }
 
 func (r *sdm) GetDeviceNextAvailable() (string, error) {
-	for _, d := range r.drivers {
-		return d.GetDeviceNextAvailable()
+	d, err := r.defaultDriver()
+	if err != nil {
+		return "", err
 	}
-	return "", errors.ErrNoStorageDetected
+	return d.GetDeviceNextAvailable()
 }
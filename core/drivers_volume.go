@@ -2,9 +2,17 @@ package core
 
 import (
 	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
 	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"github.com/akutz/gotil"
+	"golang.org/x/net/context"
+
 	"github.com/emccode/rexray/core/errors"
-	"sync"
+	"github.com/emccode/rexray/util"
 )
 
 // VolumeOpts is a map of options used when creating a new volume
@@ -51,34 +59,81 @@ type VolumeDriver interface {
 // VolumeDriverManager acts as both a VolumeDriver and as an aggregate of
 // volume drivers, providing batch methods.
 type VolumeDriverManager interface {
-	VolumeDriver
+	ContextVolumeDriver
 
 	// Drivers gets a channel which receives a list of all of the configured
 	// volume drivers.
 	Drivers() <-chan VolumeDriver
 
 	// UnmountAll unmounts all volumes.
-	UnmountAll() error
+	UnmountAll() ([]*VolumeResult, error)
 
-	// RemoveAll removes all volumes.
-	RemoveAll() error
+	// RemoveAll removes all of the volumes mounted by REX-Ray on the local
+	// instance.
+	RemoveAll() ([]*VolumeResult, error)
 
 	// DetachAll detaches all volumes attached to the instance of instanceID.
-	DetachAll(instanceID string) error
+	DetachAll(instanceID string) ([]*VolumeResult, error)
+
+	// Expand grows the volume of volumeName or volumeID to newSize GB. If
+	// the volume is mounted on the local instance its filesystem is grown to
+	// fill the volume.
+	Expand(volumeName, volumeID string, newSize int64) error
+
+	// ExpandContext is Expand with a context.
+	ExpandContext(
+		ctx context.Context,
+		volumeName, volumeID string, newSize int64) error
+
+	// Stats returns the statistics of the volumes mounted on the local
+	// instance.
+	Stats() ([]*VolumeStats, error)
+
+	// StatsContext returns the statistics of the volume of volumeName or
+	// volumeID or, if neither is provided, of all of the volumes mounted on
+	// the local instance.
+	StatsContext(
+		ctx context.Context,
+		volumeName, volumeID string) ([]*VolumeStats, error)
+
+	// MountRefs returns the references held on the volumes mounted by
+	// REX-Ray by the names of the volumes.
+	MountRefs() map[string]*VolumeMountRefs
+
+	// ReleaseMountRef releases the reference held on the volume of
+	// volumeName by the caller and mount ID, unmounting the volume if no
+	// references remain. It releases the references of consumers that
+	// exited without unmounting the volume.
+	ReleaseMountRef(volumeName, caller, mountID string) error
+
+	// ReleaseMountRefContext is ReleaseMountRef with a context.
+	ReleaseMountRefContext(
+		ctx context.Context, volumeName, caller, mountID string) error
+}
+
+// VolumeResult is the result of a batch operation on a single volume.
+type VolumeResult struct {
+
+	// The name of the volume.
+	VolumeName string
+
+	// The volume ID.
+	VolumeID string
+
+	// The error that occurred while operating on the volume, if any.
+	Error string `json:",omitempty" yaml:",omitempty"`
 }
 
 type vdm struct {
-	rexray       *RexRay
-	drivers      map[string]VolumeDriver
-	m            sync.Mutex
-	mapUsedCount map[string]*int
+	rexray  *RexRay
+	drivers map[string]VolumeDriver
 }
 
 func (r *vdm) Init(rexray *RexRay) error {
 	if len(r.drivers) == 0 {
 		return errors.ErrNoVolumeDrivers
 	}
-	r.mapUsedCount = make(map[string]*int)
+	r.reconcile()
 	return nil
 }
 
@@ -108,95 +163,515 @@ func (r *vdm) Drivers() <-chan VolumeDriver {
 	return c
 }
 
-// UnmountAll unmounts all volumes.
-func (r *vdm) UnmountAll() error {
-	for range r.drivers {
-		return nil
+// UnmountAll unmounts all of the volumes mounted by REX-Ray on the local
+// instance. A volume that is also mounted outside of REX-Ray's directory is
+// skipped and reported.
+func (r *vdm) UnmountAll() ([]*VolumeResult, error) {
+	d, err := r.driver()
+	if err != nil {
+		return nil, err
 	}
-	return errors.ErrNoVolumesDetected
+
+	ctx := context.Background()
+	vols, err := r.attachedVolumes(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*VolumeResult{}
+	for _, v := range vols {
+		managed, err := r.isVolumeManaged(v)
+		switch {
+		case err != nil:
+			results = append(results, newVolumeResult(v, err))
+		case managed:
+			results = append(results, newVolumeResult(
+				v, r.unmountVolume(ctx, d, v)))
+		}
+	}
+	return results, nil
 }
 
-// RemoveAll removes all volumes.
-func (r *vdm) RemoveAll() error {
-	for range r.drivers {
-		return nil
+// RemoveAll unmounts, detaches, and removes all of the volumes that REX-Ray
+// manages on the local instance. The other volumes attached to the local
+// instance are left in place and reported.
+func (r *vdm) RemoveAll() ([]*VolumeResult, error) {
+	d, err := r.driver()
+	if err != nil {
+		return nil, err
 	}
-	return errors.ErrNoVolumesDetected
+
+	ctx := context.Background()
+	vols, err := r.attachedVolumes(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*VolumeResult{}
+	for _, v := range vols {
+		err := r.checkVolumeManaged(v)
+		if err == nil {
+			err = r.unmountVolume(ctx, d, v)
+		}
+		if err == nil {
+			err = r.rexray.Storage.RemoveVolumeContext(ctx, v.VolumeID)
+		}
+		results = append(results, newVolumeResult(v, err))
+	}
+	return results, nil
 }
 
-// DetachAll detaches all volumes attached to the instance of instanceID.
-func (r *vdm) DetachAll(instanceID string) error {
-	for range r.drivers {
-		return nil
+// DetachAll detaches all volumes attached to the instance of instanceID. If
+// instanceID is empty the volumes that REX-Ray manages on the local instance
+// are unmounted and detached, and the other volumes attached to the local
+// instance are left in place and reported.
+func (r *vdm) DetachAll(instanceID string) ([]*VolumeResult, error) {
+	d, err := r.driver()
+	if err != nil {
+		return nil, err
 	}
-	return errors.ErrNoVolumesDetected
+
+	ctx := context.Background()
+	vols, err := r.attachedVolumes(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*VolumeResult{}
+	for _, v := range vols {
+		var err error
+		if instanceID != "" {
+			err = r.rexray.Storage.DetachVolumeContext(
+				ctx, false, v.VolumeID, instanceID, false)
+		} else if err = r.checkVolumeManaged(v); err == nil {
+			err = r.unmountVolume(ctx, d, v)
+		}
+		results = append(results, newVolumeResult(v, err))
+	}
+	return results, nil
 }
 
-func (r *vdm) countUse(volumeName string) {
-	r.m.Lock()
-	if c, ok := r.mapUsedCount[volumeName]; ok {
-		*c++
-		log.WithFields(log.Fields{
-			"volumeName": volumeName,
-			"count":      *c,
-		}).Info("set count to")
-		r.m.Unlock()
+// Expand grows the volume of volumeName or volumeID to newSize GB and grows
+// the filesystem of each of its mounted devices on the local instance.
+func (r *vdm) Expand(volumeName, volumeID string, newSize int64) error {
+	return r.ExpandContext(
+		context.Background(), volumeName, volumeID, newSize)
+}
+
+func (r *vdm) ExpandContext(
+	ctx context.Context,
+	volumeName, volumeID string, newSize int64) error {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.volume.timeouts.expand")
+	defer cancel()
+
+	vols, err := r.rexray.Storage.GetVolumeContext(ctx, volumeID, volumeName)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(vols) == 0:
+		return errors.ErrNoVolumesReturned
+	case len(vols) > 1:
+		return errors.ErrMultipleVolumesReturned
+	}
+	v := vols[0]
+
+	if size, err := strconv.ParseInt(v.Size, 10, 64); err == nil &&
+		newSize <= size {
+		return goof.WithFieldsE(goof.Fields{
+			"volumeID": v.VolumeID,
+			"size":     size,
+			"newSize":  newSize,
+		}, "error expanding volume", errors.ErrInvalidVolumeSize)
+	}
+
+	if err := r.rexray.Storage.ExpandVolumeContext(
+		ctx, v.VolumeID, newSize); err != nil {
+		return err
+	}
+
+	mounts, err := r.rexray.localMounts(ctx, v.VolumeID)
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if err := r.rexray.OS.ResizeFilesystemContext(
+			ctx, m.deviceName, m.mountPoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// volumeMount is a filesystem, mounted on the local instance, on the device
+// of a volume.
+type volumeMount struct {
+	deviceName string
+	mountPoint string
+}
+
+// localMounts returns the filesystems mounted on the local instance on the
+// devices of the volume with the provided ID.
+func (r *RexRay) localMounts(
+	ctx context.Context, volumeID string) ([]*volumeMount, error) {
+
+	instances, err := r.Storage.GetInstancesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumeMounts []*volumeMount
+	for _, i := range instances {
+		attachments, err := r.Storage.GetVolumeAttachContext(
+			ctx, volumeID, i.InstanceID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attachments {
+			if a.DeviceName == "" {
+				continue
+			}
+			mounts, err := r.OS.GetMounts(a.DeviceName, "")
+			if err != nil {
+				return nil, err
+			}
+			if len(mounts) == 0 {
+				continue
+			}
+			volumeMounts = append(volumeMounts, &volumeMount{
+				deviceName: a.DeviceName,
+				mountPoint: mounts[0].Mountpoint,
+			})
+		}
+	}
+	return volumeMounts, nil
+}
+
+// driver returns the volume driver used by the batch methods, which is the
+// first of the drivers named by rexray.volumeDrivers that is configured.
+func (r *vdm) driver() (VolumeDriver, error) {
+	for _, n := range r.rexray.Config.GetStringSlice("rexray.volumeDrivers") {
+		if d, ok := r.drivers[n]; ok {
+			return d, nil
+		}
+	}
+	return nil, errors.ErrNoVolumesDetected
+}
+
+// attachedVolumes returns the volumes attached to the instance of instanceID
+// or, if instanceID is empty, to the local instance. The attachments of each
+// returned volume are limited to those of the instance.
+func (r *vdm) attachedVolumes(
+	ctx context.Context, instanceID string) ([]*Volume, error) {
+
+	instanceIDs := []string{instanceID}
+	if instanceID == "" {
+		instances, err := r.rexray.Storage.GetInstancesContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		instanceIDs = nil
+		for _, i := range instances {
+			instanceIDs = append(instanceIDs, i.InstanceID)
+		}
+	}
+
+	vols, err := r.rexray.Storage.GetVolumeContext(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var attached []*Volume
+	for _, v := range vols {
+		var attachments []*VolumeAttachment
+		for _, a := range v.Attachments {
+			if gotil.StringInSlice(a.InstanceID, instanceIDs) {
+				attachments = append(attachments, a)
+			}
+		}
+		if len(attachments) == 0 {
+			continue
+		}
+		av := *v
+		av.Attachments = attachments
+		attached = append(attached, &av)
+	}
+	return attached, nil
+}
+
+// unmountVolume unmounts the volume regardless of the number of references
+// held on it and releases all of the references.
+func (r *vdm) unmountVolume(
+	ctx context.Context, d VolumeDriver, v *Volume) error {
+
+	var err error
+	if cd, ok := d.(ContextVolumeDriver); ok {
+		err = cd.UnmountContext(ctx, v.Name, v.VolumeID)
 	} else {
-		r.m.Unlock()
-		r.countInit(volumeName)
-		r.countUse(volumeName)
+		err = runMutation(ctx, func() error {
+			return d.Unmount(v.Name, v.VolumeID)
+		})
 	}
+	if err != nil {
+		return err
+	}
+	r.countInit(v.Name)
+	r.publishUnmounted(ctx, d, v.Name, v.VolumeID)
+	return nil
 }
 
-func (r *vdm) countInit(volumeName string) {
-	r.m.Lock()
-	defer r.m.Unlock()
+func (r *vdm) publishUnmounted(
+	ctx context.Context, d VolumeDriver, volumeName, volumeID string) {
+	r.rexray.publish(ctx, &Event{
+		Type:       EventVolumeUnmounted,
+		Driver:     d.Name(),
+		VolumeName: volumeName,
+		VolumeID:   volumeID,
+	})
+}
 
-	var c int
-	c = 0
-	r.mapUsedCount[volumeName] = &c
-	log.WithFields(log.Fields{
-		"volumeName": volumeName,
-		"count":      c,
-	}).Info("initialized count")
+// errVolumeNotManaged is reported for a volume that the batch methods leave
+// in place because REX-Ray did not mount it.
+var errVolumeNotManaged = goof.New("volume is not mounted by REX-Ray")
+
+// checkVolumeManaged returns errVolumeNotManaged if REX-Ray does not manage
+// the volume on the local instance, or the error of isVolumeManaged.
+func (r *vdm) checkVolumeManaged(v *Volume) error {
+	managed, err := r.isVolumeManaged(v)
+	if err == nil && !managed {
+		return errVolumeNotManaged
+	}
+	return err
+}
+
+// isVolumeManaged returns a flag indicating whether or not REX-Ray manages the
+// volume on the local instance. A volume is managed if the filesystem on one
+// of its devices, or on a device's encrypted mapping, is mounted inside of the
+// directory where REX-Ray mounts volumes, or if it has the block mode and
+// REX-Ray linked its device. An error is returned for a volume that is also
+// mounted anywhere else, since unmounting and detaching it would pull the
+// device out from under its other consumers.
+func (r *vdm) isVolumeManaged(v *Volume) (bool, error) {
+	mountDir := util.LibFilePath("volumes") + "/"
+	managed := false
+	for _, a := range v.Attachments {
+		if a.DeviceName == "" {
+			continue
+		}
+		mounts, err := r.rexray.OS.GetMounts(a.DeviceName, "")
+		if err != nil {
+			return false, err
+		}
+		for _, m := range mounts {
+			if !strings.HasPrefix(m.Mountpoint, mountDir) {
+				return false, goof.Newf(
+					"volume is mounted outside of REX-Ray at %s",
+					m.Mountpoint)
+			}
+			managed = true
+		}
+	}
+	if !managed && IsBlockVolume(v) {
+		managed = isDeviceLink(util.LibFilePath("devices/" + v.Name))
+	}
+	return managed, nil
 }
 
-func (r *vdm) countRelease(volumeName string) {
-	r.m.Lock()
-	defer r.m.Unlock()
-	if c, ok := r.mapUsedCount[volumeName]; ok {
-		*c--
+func newVolumeResult(v *Volume, err error) *VolumeResult {
+	vr := &VolumeResult{
+		VolumeName: v.Name,
+		VolumeID:   v.VolumeID,
+	}
+	if err != nil {
+		vr.Error = err.Error()
 		log.WithFields(log.Fields{
-			"volumeName": volumeName,
-			"count":      *c,
-		}).Info("released count")
+			"volumeName": v.Name,
+			"volumeID":   v.VolumeID,
+			"error":      err}).Error("error processing volume")
 	}
+	return vr
 }
 
-func (r *vdm) countExists(volumeName string) bool {
-	_, exists := r.mapUsedCount[volumeName]
-	log.WithFields(log.Fields{
-		"volumeName": volumeName,
-		"exists":     exists,
-	}).Info("status of count")
+// countUse takes a reference on the volume for the caller and mount ID in the
+// context. A mount ID that already holds a reference does not take another,
+// so a repeated mount with the same ID is released by a single unmount.
+func (r *vdm) countUse(
+	ctx context.Context, volumeName, mountPath string) {
+
+	caller, mountID := MountRefFromContext(ctx)
 
-	return exists
+	updateVolumeRefs(func(state volumeRefsState) bool {
+		vr, ok := state[volumeName]
+		if !ok {
+			vr = &VolumeMountRefs{}
+			state[volumeName] = vr
+		}
+		if mountPath != "" {
+			vr.MountPath = mountPath
+		}
+		if mountID != "" && vr.index(caller, mountID) >= 0 {
+			log.WithFields(log.Fields{
+				"volumeName": volumeName,
+				"caller":     caller,
+				"mountID":    mountID,
+				"count":      len(vr.Refs),
+			}).Info("mount ID already holds a reference")
+			return true
+		}
+		vr.Refs = append(vr.Refs, &MountRef{
+			Caller:  caller,
+			MountID: mountID,
+			Time:    time.Now().UTC(),
+		})
+		log.WithFields(log.Fields{
+			"volumeName": volumeName,
+			"caller":     caller,
+			"mountID":    mountID,
+			"count":      len(vr.Refs),
+		}).Info("set count to")
+		return true
+	})
+}
+
+func (r *vdm) countInit(volumeName string) {
+	updateVolumeRefs(func(state volumeRefsState) bool {
+		log.WithFields(log.Fields{
+			"volumeName": volumeName,
+			"count":      0,
+		}).Info("initialized count")
+		if _, ok := state[volumeName]; !ok {
+			return false
+		}
+		delete(state, volumeName)
+		return true
+	})
 }
 
-func (r *vdm) countReset(volumeName string) bool {
-	r.m.Lock()
-	defer r.m.Unlock()
+// countRelease releases the reference held by the caller and mount ID in the
+// context or, if the context has no mount ID, the most recently taken
+// reference. A mount ID that holds no reference releases nothing, even if
+// no references are held on the volume at all, so a repeated unmount with the
+// same ID neither releases another caller's reference nor unmounts the volume
+// again. The returned flag indicates whether or not the volume should be
+// unmounted because no references remain, and the released reference, if
+// any, is returned so that it may be restored if the unmount fails.
+func (r *vdm) countRelease(
+	ctx context.Context, volumeName string) (bool, *MountRef) {
+
+	caller, mountID := MountRefFromContext(ctx)
+
+	var (
+		last     bool
+		released *MountRef
+	)
+	updateVolumeRefs(func(state volumeRefsState) bool {
+		vr, ok := state[volumeName]
+		i := -1
+		if ok {
+			i = vr.index(caller, mountID)
+		}
+		if i < 0 {
+			if mountID != "" {
+				var count int
+				if ok {
+					count = len(vr.Refs)
+				}
+				log.WithFields(log.Fields{
+					"volumeName": volumeName,
+					"caller":     caller,
+					"mountID":    mountID,
+					"count":      count,
+				}).Info("mount ID holds no reference")
+				return false
+			}
+			if !ok || len(vr.Refs) == 0 {
+				last = true
+				return false
+			}
+			i = len(vr.Refs) - 1
+		}
+		released = vr.Refs[i]
+		vr.Refs = append(vr.Refs[:i], vr.Refs[i+1:]...)
+		last = len(vr.Refs) == 0
+		log.WithFields(log.Fields{
+			"volumeName": volumeName,
+			"caller":     caller,
+			"mountID":    mountID,
+			"count":      len(vr.Refs),
+		}).Info("released count")
+		return true
+	})
+	return last, released
+}
 
-	c, _ := r.mapUsedCount[volumeName]
-	if c != nil && *c < 2 {
+// countRestore takes the reference released by countRelease again after the
+// volume could not be unmounted, since the volume is still mounted for the
+// reference's holder.
+func (r *vdm) countRestore(volumeName string, ref *MountRef) {
+	if ref == nil {
+		return
+	}
+	updateVolumeRefs(func(state volumeRefsState) bool {
+		vr, ok := state[volumeName]
+		if !ok {
+			vr = &VolumeMountRefs{}
+			state[volumeName] = vr
+		}
+		vr.Refs = append(vr.Refs, ref)
 		log.WithFields(log.Fields{
 			"volumeName": volumeName,
-			"count":      *c,
-		}).Info("count reset")
-		*c = 0
+			"caller":     ref.Caller,
+			"mountID":    ref.MountID,
+			"count":      len(vr.Refs),
+		}).Info("restored count after failed unmount")
 		return true
+	})
+}
+
+// MountRefs returns the references held on the volumes mounted by REX-Ray by
+// the names of the volumes.
+func (r *vdm) MountRefs() map[string]*VolumeMountRefs {
+	var refs map[string]*VolumeMountRefs
+	updateVolumeRefs(func(state volumeRefsState) bool {
+		refs = state
+		return false
+	})
+	return refs
+}
+
+// ReleaseMountRef releases the reference held on the volume of volumeName by
+// the caller and mount ID, unmounting the volume if no references remain.
+func (r *vdm) ReleaseMountRef(volumeName, caller, mountID string) error {
+	return r.ReleaseMountRefContext(
+		context.Background(), volumeName, caller, mountID)
+}
+
+func (r *vdm) ReleaseMountRefContext(
+	ctx context.Context, volumeName, caller, mountID string) error {
+
+	var held bool
+	updateVolumeRefs(func(state volumeRefsState) bool {
+		vr, ok := state[volumeName]
+		held = ok && vr.index(caller, mountID) >= 0
+		return false
+	})
+	if !held {
+		return goof.WithFields(goof.Fields{
+			"volumeName": volumeName,
+			"caller":     caller,
+			"mountID":    mountID,
+		}, "mount ref not found")
 	}
-	return false
+
+	log.WithFields(log.Fields{
+		"volumeName": volumeName,
+		"caller":     caller,
+		"mountID":    mountID,
+	}).Info("releasing mount ref")
+
+	return r.UnmountContext(
+		WithMountRef(ctx, caller, mountID), volumeName, "")
 }
 
 // Mount will return a mount point path when specifying either a volumeName
@@ -205,17 +680,47 @@ func (r *vdm) countReset(volumeName string) bool {
 func (r *vdm) Mount(
 	volumeName, volumeID string,
 	overwriteFs bool, newFsType string, preempt bool) (string, error) {
+	return r.MountContext(context.Background(),
+		volumeName, volumeID, overwriteFs, newFsType, preempt)
+}
+
+func (r *vdm) MountContext(
+	ctx context.Context,
+	volumeName, volumeID string,
+	overwriteFs bool, newFsType string, preempt bool) (string, error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.volume.timeouts.mount")
+	defer cancel()
+
 	for _, d := range r.drivers {
 		if !preempt {
 			preempt = r.preempt()
 		}
 
-		mp, err := d.Mount(volumeName, volumeID, overwriteFs, newFsType, preempt)
+		var mp string
+		var err error
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			mp, err = cd.MountContext(
+				ctx, volumeName, volumeID, overwriteFs, newFsType, preempt)
+		} else {
+			err = runMutation(ctx, func() (err error) {
+				mp, err = d.Mount(
+					volumeName, volumeID, overwriteFs, newFsType, preempt)
+				return
+			})
+		}
 		if err != nil {
 			return "", err
 		}
 
-		r.countUse(volumeName)
+		r.countUse(ctx, volumeName, mp)
+		r.rexray.publish(ctx, &Event{
+			Type:       EventVolumeMounted,
+			Driver:     d.Name(),
+			VolumeName: volumeName,
+			VolumeID:   volumeID,
+			MountPoint: mp,
+		})
 
 		return mp, nil
 	}
@@ -224,39 +729,105 @@ func (r *vdm) Mount(
 
 // Unmount will unmount the specified volume by volumeName or volumeID.
 func (r *vdm) Unmount(volumeName, volumeID string) error {
+	return r.UnmountContext(context.Background(), volumeName, volumeID)
+}
+
+func (r *vdm) UnmountContext(
+	ctx context.Context, volumeName, volumeID string) error {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.volume.timeouts.unmount")
+	defer cancel()
+
 	for _, d := range r.drivers {
-		if r.ignoreUsedCount() || r.countReset(volumeName) || !r.countExists(volumeName) {
-			r.countInit(volumeName)
-			return d.Unmount(volumeName, volumeID)
+		var released *MountRef
+		if !r.ignoreUsedCount() {
+			var last bool
+			if last, released = r.countRelease(ctx, volumeName); !last {
+				return nil
+			}
+		}
+		var err error
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			err = cd.UnmountContext(ctx, volumeName, volumeID)
 		} else {
-			r.countRelease(volumeName)
-			return nil
+			err = runMutation(ctx, func() error {
+				return d.Unmount(volumeName, volumeID)
+			})
+		}
+		if err != nil {
+			r.countRestore(volumeName, released)
+			return err
 		}
+		r.countInit(volumeName)
+		r.publishUnmounted(ctx, d, volumeName, volumeID)
+		return nil
 	}
 	return errors.ErrNoVolumesDetected
 }
 
 // Path will return the mounted path of the volumeName or volumeID.
 func (r *vdm) Path(volumeName, volumeID string) (string, error) {
+	return r.PathContext(context.Background(), volumeName, volumeID)
+}
+
+func (r *vdm) PathContext(
+	ctx context.Context, volumeName, volumeID string) (string, error) {
 	for _, d := range r.drivers {
-		return d.Path(volumeName, volumeID)
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			return cd.PathContext(ctx, volumeName, volumeID)
+		}
+		var p string
+		if err := runContext(ctx, func() (err error) {
+			p, err = d.Path(volumeName, volumeID)
+			return
+		}); err != nil {
+			return "", err
+		}
+		return p, nil
 	}
 	return "", errors.ErrNoVolumesDetected
 }
 
 // Create will create a new volume with the volumeName and opts.
 func (r *vdm) Create(volumeName string, opts VolumeOpts) error {
+	return r.CreateContext(context.Background(), volumeName, opts)
+}
+
+func (r *vdm) CreateContext(
+	ctx context.Context, volumeName string, opts VolumeOpts) error {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.volume.timeouts.create")
+	defer cancel()
+
 	for _, d := range r.drivers {
 		r.countInit(volumeName)
-		return d.Create(volumeName, opts)
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			return cd.CreateContext(ctx, volumeName, opts)
+		}
+		return runMutation(ctx, func() error {
+			return d.Create(volumeName, opts)
+		})
 	}
 	return errors.ErrNoVolumesDetected
 }
 
 // Remove will remove a volume of volumeName.
 func (r *vdm) Remove(volumeName string) error {
+	return r.RemoveContext(context.Background(), volumeName)
+}
+
+func (r *vdm) RemoveContext(ctx context.Context, volumeName string) error {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.volume.timeouts.remove")
+	defer cancel()
+
 	for _, d := range r.drivers {
-		return d.Remove(volumeName)
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			return cd.RemoveContext(ctx, volumeName)
+		}
+		return runMutation(ctx, func() error {
+			return d.Remove(volumeName)
+		})
 	}
 	return errors.ErrNoVolumesDetected
 }
@@ -264,8 +835,24 @@ func (r *vdm) Remove(volumeName string) error {
 // Attach will attach a volume based on volumeName to the instance of
 // instanceID.
 func (r *vdm) Attach(volumeName, instanceID string, force bool) (string, error) {
+	return r.AttachContext(context.Background(), volumeName, instanceID, force)
+}
+
+func (r *vdm) AttachContext(
+	ctx context.Context,
+	volumeName, instanceID string, force bool) (string, error) {
 	for _, d := range r.drivers {
-		return d.Attach(volumeName, instanceID, force)
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			return cd.AttachContext(ctx, volumeName, instanceID, force)
+		}
+		var nn string
+		if err := runMutation(ctx, func() (err error) {
+			nn, err = d.Attach(volumeName, instanceID, force)
+			return
+		}); err != nil {
+			return "", err
+		}
+		return nn, nil
 	}
 	return "", errors.ErrNoVolumesDetected
 }
@@ -273,8 +860,19 @@ func (r *vdm) Attach(volumeName, instanceID string, force bool) (string, error)
 // Detach will detach a volume based on volumeName to the instance of
 // instanceID.
 func (r *vdm) Detach(volumeName, instanceID string, force bool) error {
+	return r.DetachContext(context.Background(), volumeName, instanceID, force)
+}
+
+func (r *vdm) DetachContext(
+	ctx context.Context,
+	volumeName, instanceID string, force bool) error {
 	for _, d := range r.drivers {
-		return d.Detach(volumeName, instanceID, force)
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			return cd.DetachContext(ctx, volumeName, instanceID, force)
+		}
+		return runMutation(ctx, func() error {
+			return d.Detach(volumeName, instanceID, force)
+		})
 	}
 	return errors.ErrNoVolumesDetected
 }
@@ -283,8 +881,23 @@ func (r *vdm) Detach(volumeName, instanceID string, force bool) error {
 // corelating a local device to a device that is the volumeName to the
 // local instanceID.
 func (r *vdm) NetworkName(volumeName, instanceID string) (string, error) {
+	return r.NetworkNameContext(context.Background(), volumeName, instanceID)
+}
+
+func (r *vdm) NetworkNameContext(
+	ctx context.Context, volumeName, instanceID string) (string, error) {
 	for _, d := range r.drivers {
-		return d.NetworkName(volumeName, instanceID)
+		if cd, ok := d.(ContextVolumeDriver); ok {
+			return cd.NetworkNameContext(ctx, volumeName, instanceID)
+		}
+		var nn string
+		if err := runContext(ctx, func() (err error) {
+			nn, err = d.NetworkName(volumeName, instanceID)
+			return
+		}); err != nil {
+			return "", err
+		}
+		return nn, nil
 	}
 	return "", errors.ErrNoVolumesDetected
 }
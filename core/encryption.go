@@ -0,0 +1,195 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+)
+
+// VolumeEncryptedLabel is the label that marks a volume whose device is
+// encrypted. Volumes with this label are decrypted when they are mounted.
+const VolumeEncryptedLabel = "rexray-encrypted"
+
+const defaultKeyEnvVar = "REXRAY_ENCRYPTION_KEY"
+
+// KeyProvider provides the keys with which the devices of encrypted volumes
+// are encrypted.
+type KeyProvider interface {
+
+	// Key returns the key for the volume with the provided ID.
+	Key(ctx context.Context, volumeID string) ([]byte, error)
+}
+
+// NewKeyProvider is a function that constructs a new key provider.
+type NewKeyProvider func(r *RexRay) (KeyProvider, error)
+
+var keyProviderCtors = map[string]NewKeyProvider{
+	"file":    newFileKeyProvider,
+	"env":     newEnvKeyProvider,
+	"command": newCommandKeyProvider,
+}
+
+// RegisterKeyProvider registers a key provider that may be selected with the
+// configuration property rexray.encryption.keyProvider.
+func RegisterKeyProvider(name string, ctor NewKeyProvider) {
+	keyProviderCtors[name] = ctor
+}
+
+type encryptionContextKey int
+
+const (
+	encryptionKey encryptionContextKey = iota
+	encryptionKeyKey
+)
+
+// WithEncryption returns a copy of the provided context that directs the
+// volume driver to encrypt, or not to encrypt, the device of the volume it
+// mounts. Without it a volume is encrypted only if it has the
+// VolumeEncryptedLabel.
+func WithEncryption(ctx context.Context, encrypted bool) context.Context {
+	return context.WithValue(ctx, encryptionKey, encrypted)
+}
+
+// EncryptionFromContext returns the flag stored in the context by
+// WithEncryption and whether or not one was stored.
+func EncryptionFromContext(ctx context.Context) (encrypted, ok bool) {
+	encrypted, ok = ctx.Value(encryptionKey).(bool)
+	return
+}
+
+// WithEncryptionKey returns a copy of the provided context that carries the
+// key with which the OS driver encrypts the device it formats and decrypts
+// the device it mounts.
+func WithEncryptionKey(ctx context.Context, key []byte) context.Context {
+	return context.WithValue(ctx, encryptionKeyKey, key)
+}
+
+// EncryptionKeyFromContext returns the key stored in the context by
+// WithEncryptionKey, if any.
+func EncryptionKeyFromContext(ctx context.Context) []byte {
+	key, _ := ctx.Value(encryptionKeyKey).([]byte)
+	return key
+}
+
+// EncryptionKey returns the key for the volume with the provided ID from the
+// key provider named by rexray.encryption.keyProvider.
+func (r *RexRay) EncryptionKey(
+	ctx context.Context, volumeID string) ([]byte, error) {
+
+	name := r.Config.GetString("rexray.encryption.keyProvider")
+	ctor, ok := keyProviderCtors[strings.ToLower(name)]
+	if !ok {
+		return nil, goof.WithField(
+			"keyProvider", name, "unknown key provider")
+	}
+	kp, err := ctor(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := kp.Key(ctx, volumeID)
+	if err != nil {
+		return nil, goof.WithFieldsE(goof.Fields{
+			"keyProvider": name,
+			"volumeID":    volumeID,
+		}, "error getting encryption key", err)
+	}
+	if len(key) == 0 {
+		return nil, goof.WithFields(goof.Fields{
+			"keyProvider": name,
+			"volumeID":    volumeID,
+		}, "empty encryption key")
+	}
+	return key, nil
+}
+
+// fileKeyProvider reads keys from rexray.encryption.file.path. If the path is
+// a directory then the key for a volume is read from the file in the
+// directory named after the volume's ID.
+type fileKeyProvider struct {
+	path string
+}
+
+func newFileKeyProvider(r *RexRay) (KeyProvider, error) {
+	p := r.Config.GetString("rexray.encryption.file.path")
+	if p == "" {
+		return nil, goof.New("missing rexray.encryption.file.path")
+	}
+	return &fileKeyProvider{path: p}, nil
+}
+
+func (p *fileKeyProvider) Key(
+	ctx context.Context, volumeID string) ([]byte, error) {
+
+	keyPath := p.path
+	if fi, err := os.Stat(keyPath); err == nil && fi.IsDir() {
+		keyPath = filepath.Join(keyPath, filepath.Base(volumeID))
+	}
+	return ioutil.ReadFile(keyPath)
+}
+
+// envKeyProvider reads the key for every volume from the environment
+// variable named by rexray.encryption.env.name.
+type envKeyProvider struct {
+	name string
+}
+
+func newEnvKeyProvider(r *RexRay) (KeyProvider, error) {
+	name := r.Config.GetString("rexray.encryption.env.name")
+	if name == "" {
+		name = defaultKeyEnvVar
+	}
+	return &envKeyProvider{name: name}, nil
+}
+
+func (p *envKeyProvider) Key(
+	ctx context.Context, volumeID string) ([]byte, error) {
+	return []byte(os.Getenv(p.name)), nil
+}
+
+// commandKeyProvider runs the command rexray.encryption.command.path with
+// the volume's ID as its argument and reads the key from its output. A
+// trailing newline is not part of the key.
+type commandKeyProvider struct {
+	path string
+}
+
+func newCommandKeyProvider(r *RexRay) (KeyProvider, error) {
+	p := r.Config.GetString("rexray.encryption.command.path")
+	if p == "" {
+		return nil, goof.New("missing rexray.encryption.command.path")
+	}
+	return &commandKeyProvider{path: p}, nil
+}
+
+func (p *commandKeyProvider) Key(
+	ctx context.Context, volumeID string) ([]byte, error) {
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(p.path, volumeID)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, goof.WithFieldE(
+			"command", p.path, "error running key command", err)
+	}
+	if err := runContext(ctx, cmd.Wait); err != nil {
+		if ctx.Err() != nil {
+			cmd.Process.Kill()
+			return nil, goof.WithFieldE(
+				"command", p.path, "error running key command", err)
+		}
+		return nil, goof.WithFieldsE(goof.Fields{
+			"command": p.path,
+			"stderr":  strings.TrimSpace(stderr.String()),
+		}, "error running key command", err)
+	}
+	return bytes.TrimRight(stdout.Bytes(), "\r\n"), nil
+}
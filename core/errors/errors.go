@@ -85,6 +85,14 @@ const (
 	// ErrCodeRunAsyncFromVolume is the error code for when an asynchronous
 	// create volume is received.
 	ErrCodeRunAsyncFromVolume
+
+	// ErrCodeCircuitOpen is the error code for when a storage driver is not
+	// called because its circuit breaker is open.
+	ErrCodeCircuitOpen
+
+	// ErrCodeInvalidVolumeSize is the error code for when a volume is expanded
+	// to a size that is not larger than its current size.
+	ErrCodeInvalidVolumeSize
 )
 
 var (
@@ -158,6 +166,14 @@ var (
 	// ErrRunAsyncFromVolume is the error for when an asynchronous
 	// create volume is received.
 	ErrRunAsyncFromVolume = ErrRexRay(ErrCodeRunAsyncFromVolume)
+
+	// ErrCircuitOpen is the error for when a storage driver is not called
+	// because its circuit breaker is open.
+	ErrCircuitOpen = ErrRexRay(ErrCodeCircuitOpen)
+
+	// ErrInvalidVolumeSize is the error for when a volume is expanded to a
+	// size that is not larger than its current size.
+	ErrInvalidVolumeSize = ErrRexRay(ErrCodeInvalidVolumeSize)
 )
 
 // ErrRexRay creates a new instance of a RexRayErr with a given error code.
@@ -193,6 +209,10 @@ func errCodeToString(code RexRayErrCode) string {
 		return "getting local volume mounts"
 	case ErrCodeRunAsyncFromVolume:
 		return "cannot create volume from volume and run asynchronously"
+	case ErrCodeCircuitOpen:
+		return "circuit breaker open"
+	case ErrCodeInvalidVolumeSize:
+		return "new volume size must be larger than current size"
 	case ErrCodeNotImplemented:
 		return "not implemented"
 	default:
@@ -257,3 +277,80 @@ func errCodeUnknownToString(code RexRayErrCode) string {
 		return "unknown error"
 	}
 }
+
+var errCodeNames = map[RexRayErrCode]string{
+	ErrCodeUnknown:                    "Unknown",
+	ErrCodeNoOSDetected:               "NoOSDetected",
+	ErrCodeNoVolumesDetected:          "NoVolumesDetected",
+	ErrCodeNoStorageDetected:          "NoStorageDetected",
+	ErrCodeDriverBlockDeviceDiscovery: "DriverBlockDeviceDiscovery",
+	ErrCodeDriverInstanceDiscovery:    "DriverInstanceDiscovery",
+	ErrCodeDriverVolumeDiscovery:      "DriverVolumeDiscovery",
+	ErrCodeDriverSnapshotDiscovery:    "DriverSnapshotDiscovery",
+	ErrCodeMultipleDriversDetected:    "MultipleDriversDetected",
+	ErrCodeNoOSDrivers:                "NoOSDrivers",
+	ErrCodeNoVolumeDrivers:            "NoVolumeDrivers",
+	ErrCodeNoStorageDrivers:           "NoStorageDrivers",
+	ErrCodeNotImplemented:             "NotImplemented",
+	ErrCodeUnknownOS:                  "UnknownOS",
+	ErrCodeUnknownFileSystem:          "UnknownFileSystem",
+	ErrCodeMissingVolumeID:            "MissingVolumeID",
+	ErrCodeMultipleVolumesReturned:    "MultipleVolumesReturned",
+	ErrCodeNoVolumesReturned:          "NoVolumesReturned",
+	ErrCodeLocalVolumeMaps:            "LocalVolumeMaps",
+	ErrCodeRunAsyncFromVolume:         "RunAsyncFromVolume",
+	ErrCodeCircuitOpen:                "CircuitOpen",
+	ErrCodeInvalidVolumeSize:          "InvalidVolumeSize",
+}
+
+var errCodes = map[error]RexRayErrCode{
+	ErrNoOSDetected:               ErrCodeNoOSDetected,
+	ErrNoVolumesDetected:          ErrCodeNoVolumesDetected,
+	ErrNoStorageDetected:          ErrCodeNoStorageDetected,
+	ErrDriverBlockDeviceDiscovery: ErrCodeDriverBlockDeviceDiscovery,
+	ErrDriverInstanceDiscovery:    ErrCodeDriverInstanceDiscovery,
+	ErrDriverVolumeDiscovery:      ErrCodeDriverVolumeDiscovery,
+	ErrDriverSnapshotDiscovery:    ErrCodeDriverSnapshotDiscovery,
+	ErrMultipleDriversDetected:    ErrCodeMultipleDriversDetected,
+	ErrNoOSDrivers:                ErrCodeNoOSDrivers,
+	ErrNoVolumeDrivers:            ErrCodeNoVolumeDrivers,
+	ErrNoStorageDrivers:           ErrCodeNoStorageDrivers,
+	ErrNotImplemented:             ErrCodeNotImplemented,
+	ErrUnknownOS:                  ErrCodeUnknownOS,
+	ErrUnknownFileSystem:          ErrCodeUnknownFileSystem,
+	ErrMissingVolumeID:            ErrCodeMissingVolumeID,
+	ErrMultipleVolumesReturned:    ErrCodeMultipleVolumesReturned,
+	ErrNoVolumesReturned:          ErrCodeNoVolumesReturned,
+	ErrLocalVolumeMaps:            ErrCodeLocalVolumeMaps,
+	ErrRunAsyncFromVolume:         ErrCodeRunAsyncFromVolume,
+	ErrCircuitOpen:                ErrCodeCircuitOpen,
+	ErrInvalidVolumeSize:          ErrCodeInvalidVolumeSize,
+}
+
+// Name returns the name of the error code, such as NoVolumesDetected.
+func (code RexRayErrCode) Name() string {
+	if n, ok := errCodeNames[code]; ok {
+		return n
+	}
+	return errCodeNames[ErrCodeUnknown]
+}
+
+// ErrCode returns the code of the REX-Ray error that is, or is wrapped by,
+// the provided error. ErrCodeUnknown is returned for any other error.
+func ErrCode(err error) RexRayErrCode {
+	for i := 0; err != nil && i < 16; i++ {
+		for e, code := range errCodes {
+			if err == e {
+				return code
+			}
+		}
+		f, ok := err.(interface {
+			Fields() map[string]interface{}
+		})
+		if !ok {
+			break
+		}
+		err, _ = f.Fields()["inner"].(error)
+	}
+	return ErrCodeUnknown
+}
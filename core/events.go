@@ -0,0 +1,205 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// EventType is the type of a volume lifecycle event.
+type EventType string
+
+const (
+	// EventVolumeCreated is published when a volume is created. The event's
+	// Volume is the created volume.
+	EventVolumeCreated EventType = "VolumeCreated"
+
+	// EventVolumeAttached is published when a volume is attached to an
+	// instance. The event's Attachments are those returned by the storage
+	// driver, if any.
+	EventVolumeAttached EventType = "VolumeAttached"
+
+	// EventVolumeFormatted is published when a device is formatted. The
+	// event's Device is the formatted device.
+	EventVolumeFormatted EventType = "VolumeFormatted"
+
+	// EventVolumeMounted is published when a volume is mounted. The event's
+	// MountPoint is the path at which the volume is mounted.
+	EventVolumeMounted EventType = "VolumeMounted"
+
+	// EventVolumeUnmounted is published when a volume is unmounted. It is not
+	// published when an unmount only releases one of several references to a
+	// mounted volume.
+	EventVolumeUnmounted EventType = "VolumeUnmounted"
+
+	// EventVolumeDetached is published when a volume is detached from an
+	// instance.
+	EventVolumeDetached EventType = "VolumeDetached"
+
+	// EventVolumeRemoved is published when a volume is removed.
+	EventVolumeRemoved EventType = "VolumeRemoved"
+
+	// EventVolumeExpanded is published when a volume is grown by its storage
+	// driver.
+	EventVolumeExpanded EventType = "VolumeExpanded"
+
+	// EventFilesystemResized is published when the filesystem on a volume's
+	// device is grown to fill the device. The event's Device is the device
+	// and its MountPoint is the path at which the filesystem is mounted.
+	EventFilesystemResized EventType = "FilesystemResized"
+)
+
+// eventBufferSize is the number of events buffered for each subscription.
+// Events published to a subscription whose buffer is full are dropped so that
+// a slow subscriber never delays a storage operation.
+const eventBufferSize = 64
+
+// events is the bus shared by every REX-Ray instance in the process, so that
+// a daemon module may subscribe to the events caused by the requests served
+// by other modules.
+var events = newEventBus()
+
+// Event is a volume lifecycle event.
+type Event struct {
+
+	// The event's type.
+	Type EventType
+
+	// The time at which the event was published.
+	Time time.Time
+
+	// The caller that requested the operation that caused the event.
+	Caller string
+
+	// The name of the driver that performed the operation.
+	Driver string `json:",omitempty" yaml:",omitempty"`
+
+	// The name of the volume.
+	VolumeName string `json:",omitempty" yaml:",omitempty"`
+
+	// The ID of the volume.
+	VolumeID string `json:",omitempty" yaml:",omitempty"`
+
+	// The ID of the instance to or from which the volume was attached or
+	// detached.
+	InstanceID string `json:",omitempty" yaml:",omitempty"`
+
+	// The path at which the volume was mounted or unmounted.
+	MountPoint string `json:",omitempty" yaml:",omitempty"`
+
+	// The volume.
+	Volume *Volume `json:",omitempty" yaml:",omitempty"`
+
+	// The volume's attachments.
+	Attachments []*VolumeAttachment `json:",omitempty" yaml:",omitempty"`
+
+	// The volume's device.
+	Device *BlockDevice `json:",omitempty" yaml:",omitempty"`
+}
+
+// EventBus is the in-process bus to which the driver managers publish volume
+// lifecycle events. Every REX-Ray instance in a process shares the same bus.
+type EventBus interface {
+
+	// Subscribe returns a subscription that receives the published events of
+	// the provided types. If no types are provided then the subscription
+	// receives all events.
+	Subscribe(types ...EventType) *EventSubscription
+
+	// Publish publishes an event to the bus's subscribers.
+	Publish(e *Event)
+}
+
+// EventSubscription is a subscription to the events published to an event
+// bus.
+type EventSubscription struct {
+
+	// C is the channel on which the subscription receives events. The channel
+	// is closed when the subscription is closed.
+	C <-chan *Event
+
+	c     chan *Event
+	types map[EventType]bool
+	bus   *eventBus
+}
+
+// Close closes the subscription. No events are received once Close returns.
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+func (s *EventSubscription) wants(t EventType) bool {
+	return len(s.types) == 0 || s.types[t]
+}
+
+type eventBus struct {
+	sync.RWMutex
+	subs map[*EventSubscription]bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: map[*EventSubscription]bool{}}
+}
+
+func (b *eventBus) Subscribe(types ...EventType) *EventSubscription {
+	c := make(chan *Event, eventBufferSize)
+	s := &EventSubscription{
+		C:     c,
+		c:     c,
+		types: map[EventType]bool{},
+		bus:   b,
+	}
+	for _, t := range types {
+		s.types[t] = true
+	}
+
+	b.Lock()
+	b.subs[s] = true
+	b.Unlock()
+	return s
+}
+
+func (b *eventBus) unsubscribe(s *EventSubscription) {
+	b.Lock()
+	defer b.Unlock()
+	if !b.subs[s] {
+		return
+	}
+	delete(b.subs, s)
+	close(s.c)
+}
+
+func (b *eventBus) Publish(e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	b.RLock()
+	defer b.RUnlock()
+	for s := range b.subs {
+		if !s.wants(e.Type) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			log.WithFields(log.Fields{
+				"type":       e.Type,
+				"volumeName": e.VolumeName,
+				"volumeID":   e.VolumeID,
+			}).Warn("dropped event for slow subscriber")
+		}
+	}
+}
+
+// publish publishes an event caused by an operation requested with the
+// provided context.
+func (r *RexRay) publish(ctx context.Context, e *Event) {
+	if r == nil || r.Events == nil {
+		return
+	}
+	e.Caller = CallerFromContext(ctx)
+	r.Events.Publish(e)
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"golang.org/x/net/context"
+)
+
+// VolumeMountOptionsLabel is the label that holds the options with which a
+// volume's filesystem is mounted when no options are provided with the
+// request to mount it. The options are separated by semicolons rather than
+// commas so that the label can be encoded in a volume's name.
+const VolumeMountOptionsLabel = "rexray-mountopts"
+
+// FormatOptions are the options with which an OS driver creates a filesystem
+// on a device.
+type FormatOptions struct {
+
+	// Additional arguments for the command that creates the filesystem.
+	Args string
+
+	// The filesystem's label, such as the name of the volume. The label is
+	// truncated to the length the filesystem supports.
+	Label string
+}
+
+type fsOptionsContextKey int
+
+const (
+	formatOptionsKey fsOptionsContextKey = iota
+	mountOptionsKey
+)
+
+// WithFormatOptions returns a copy of the provided context that carries the
+// options with which the OS driver creates a filesystem.
+func WithFormatOptions(
+	ctx context.Context, opts *FormatOptions) context.Context {
+	return context.WithValue(ctx, formatOptionsKey, opts)
+}
+
+// FormatOptionsFromContext returns the options stored in the context by
+// WithFormatOptions, if any.
+func FormatOptionsFromContext(ctx context.Context) *FormatOptions {
+	opts, _ := ctx.Value(formatOptionsKey).(*FormatOptions)
+	return opts
+}
+
+// WithMountOptions returns a copy of the provided context that carries the
+// comma-separated options with which the volume driver mounts a volume's
+// filesystem.
+func WithMountOptions(ctx context.Context, opts string) context.Context {
+	return context.WithValue(ctx, mountOptionsKey, opts)
+}
+
+// MountOptionsFromContext returns the options stored in the context by
+// WithMountOptions, if any.
+func MountOptionsFromContext(ctx context.Context) string {
+	opts, _ := ctx.Value(mountOptionsKey).(string)
+	return opts
+}
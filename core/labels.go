@@ -0,0 +1,265 @@
+package core
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+)
+
+const (
+	// labelNameSep separates a volume or snapshot's name from the labels
+	// encoded in the name by storage drivers that cannot persist labels
+	// natively.
+	labelNameSep = "@"
+
+	labelSep      = ","
+	labelValueSep = "="
+)
+
+// LabelStorageDriver is implemented by storage drivers that persist labels
+// on the storage platform along with the volume or snapshot. The labels of
+// volumes and snapshots created with any other storage driver are encoded in
+// their names as name@key=value,key=value.
+type LabelStorageDriver interface {
+	StorageDriver
+
+	// SetVolumeLabels adds the labels to the volume with the provided ID.
+	SetVolumeLabels(volumeID string, labels map[string]string) error
+
+	// SetSnapshotLabels adds the labels to the snapshot with the provided ID.
+	SetSnapshotLabels(snapshotID string, labels map[string]string) error
+}
+
+type labelContextKey int
+
+const (
+	labelsContextKey labelContextKey = iota
+	labelSelectorContextKey
+	storageDriverContextKey
+)
+
+// WithLabels returns a copy of the provided context that carries the labels
+// with which a volume or snapshot is created.
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	return context.WithValue(ctx, labelsContextKey, labels)
+}
+
+// LabelsFromContext returns the labels stored in the context by WithLabels.
+func LabelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsContextKey).(map[string]string)
+	return labels
+}
+
+// WithLabelSelector returns a copy of the provided context that restricts
+// the volumes returned by GetVolume to those with all of the selector's
+// labels.
+func WithLabelSelector(
+	ctx context.Context, selector map[string]string) context.Context {
+	return context.WithValue(ctx, labelSelectorContextKey, selector)
+}
+
+// LabelSelectorFromContext returns the label selector stored in the context
+// by WithLabelSelector.
+func LabelSelectorFromContext(ctx context.Context) map[string]string {
+	selector, _ := ctx.Value(labelSelectorContextKey).(map[string]string)
+	return selector
+}
+
+// WithStorageDriver returns a copy of the provided context that directs the
+// storage driver manager to create new volumes with, and get volumes from,
+// only the named storage driver.
+func WithStorageDriver(ctx context.Context, driverName string) context.Context {
+	return context.WithValue(ctx, storageDriverContextKey, driverName)
+}
+
+// StorageDriverFromContext returns the name of the storage driver stored in
+// the context by WithStorageDriver.
+func StorageDriverFromContext(ctx context.Context) string {
+	driverName, _ := ctx.Value(storageDriverContextKey).(string)
+	return driverName
+}
+
+// ParseLabels parses labels in the form key=value. An entry without a value
+// is a label with an empty value.
+func ParseLabels(entries []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, e := range entries {
+		if e == "" {
+			continue
+		}
+		kv := strings.SplitN(e, labelValueSep, 2)
+		k := strings.TrimSpace(kv[0])
+		if k == "" {
+			return nil, goof.WithField("label", e, "invalid label")
+		}
+		if len(kv) == 2 {
+			labels[k] = strings.TrimSpace(kv[1])
+		} else {
+			labels[k] = ""
+		}
+	}
+	return labels, nil
+}
+
+// MatchLabels returns a flag indicating whether or not the labels include
+// every key and value in the selector.
+func MatchLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// labelName returns the name with the labels encoded in it.
+func labelName(name string, labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return name, nil
+	}
+
+	var keys []string
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteString(name)
+	b.WriteString(labelNameSep)
+	for i, k := range keys {
+		v := labels[k]
+		if strings.ContainsAny(k, labelNameSep+labelSep+labelValueSep) ||
+			strings.ContainsAny(v, labelNameSep+labelSep) {
+			return "", goof.WithFields(goof.Fields{
+				"key":   k,
+				"value": v,
+			}, "label cannot be encoded in name")
+		}
+		if i > 0 {
+			b.WriteString(labelSep)
+		}
+		b.WriteString(k)
+		b.WriteString(labelValueSep)
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+// splitLabelName returns the name and labels encoded in the name by
+// labelName. A name that does not contain labels is returned unchanged.
+func splitLabelName(name string) (string, map[string]string) {
+	i := strings.LastIndex(name, labelNameSep)
+	if i < 0 {
+		return name, nil
+	}
+	labels := map[string]string{}
+	for _, e := range strings.Split(name[i+1:], labelSep) {
+		kv := strings.SplitN(e, labelValueSep, 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return name, nil
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return name[:i], labels
+}
+
+// labelVolume sets the volume's name and labels from the labels encoded in
+// its name if the driver does not persist labels natively.
+func labelVolume(d StorageDriver, v *Volume) {
+	if _, ok := d.(LabelStorageDriver); ok {
+		return
+	}
+	v.Name, v.Labels = splitLabelName(v.Name)
+}
+
+// labelSnapshot sets the snapshot's name and labels from the labels encoded
+// in its name if the driver does not persist labels natively.
+func labelSnapshot(d StorageDriver, s *Snapshot) {
+	if _, ok := d.(LabelStorageDriver); ok {
+		return
+	}
+	s.Name, s.Labels = splitLabelName(s.Name)
+}
+
+// mergeLabels returns the union of the labels, preferring the values in b.
+func mergeLabels(a, b map[string]string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range a {
+		labels[k] = v
+	}
+	for k, v := range b {
+		labels[k] = v
+	}
+	return labels
+}
+
+// getVolume gets the volumes from the driver and sets their labels. If the
+// driver does not persist labels natively and no volume has the provided
+// name, the volumes are searched for one whose name, once its encoded labels
+// are removed, matches.
+func getVolume(
+	d StorageDriver, volumeID, volumeName string) ([]*Volume, error) {
+
+	volumes, err := d.GetVolume(volumeID, volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, native := d.(LabelStorageDriver)
+	if len(volumes) > 0 || volumeName == "" || native {
+		for _, v := range volumes {
+			labelVolume(d, v)
+		}
+		return volumes, nil
+	}
+
+	if volumes, err = d.GetVolume(volumeID, ""); err != nil {
+		return nil, err
+	}
+	var named []*Volume
+	for _, v := range volumes {
+		labelVolume(d, v)
+		if v.Name == volumeName {
+			named = append(named, v)
+		}
+	}
+	return named, nil
+}
+
+// getSnapshot gets the snapshots from the driver and sets their labels. If
+// the driver does not persist labels natively and no snapshot has the
+// provided name, the snapshots are searched for one whose name, once its
+// encoded labels are removed, matches.
+func getSnapshot(
+	d StorageDriver,
+	volumeID, snapshotID, snapshotName string) ([]*Snapshot, error) {
+
+	snapshots, err := d.GetSnapshot(volumeID, snapshotID, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, native := d.(LabelStorageDriver)
+	if len(snapshots) > 0 || snapshotName == "" || native {
+		for _, s := range snapshots {
+			labelSnapshot(d, s)
+		}
+		return snapshots, nil
+	}
+
+	if snapshots, err = d.GetSnapshot(volumeID, snapshotID, ""); err != nil {
+		return nil, err
+	}
+	var named []*Snapshot
+	for _, s := range snapshots {
+		labelSnapshot(d, s)
+		if s.Name == snapshotName {
+			named = append(named, s)
+		}
+	}
+	return named, nil
+}
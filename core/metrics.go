@@ -0,0 +1,85 @@
+package core
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/emccode/rexray/core/errors"
+)
+
+const (
+	metricsNamespace = "rexray"
+
+	osDriverType      = "os"
+	volumeDriverType  = "volume"
+	storageDriverType = "storage"
+)
+
+var (
+	driverCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "driver",
+			Name:      "call_duration_seconds",
+			Help:      "The duration of driver calls.",
+			Buckets: []float64{
+				.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{"type", "driver", "operation"})
+
+	driverCallErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "driver",
+			Name:      "call_errors_total",
+			Help:      "The number of driver calls that failed, by error code.",
+		},
+		[]string{"type", "driver", "operation", "code"})
+
+	driverCallsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "driver",
+			Name:      "calls_in_flight",
+			Help:      "The number of driver calls in progress.",
+		},
+		[]string{"type", "driver", "operation"})
+
+	driverCallRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "driver",
+			Name:      "call_retries_total",
+			Help:      "The number of times storage driver calls were retried.",
+		},
+		[]string{"driver", "operation"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		driverCallDuration,
+		driverCallErrors,
+		driverCallsInFlight,
+		driverCallRetries)
+}
+
+// observe invokes f and records the duration and outcome of the call as the
+// operation of the driver.
+func observe(driverType, driverName, op string, f func() error) error {
+	inFlight := driverCallsInFlight.WithLabelValues(
+		driverType, driverName, op)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	err := f()
+	driverCallDuration.WithLabelValues(driverType, driverName, op).Observe(
+		time.Since(start).Seconds())
+
+	if err != nil {
+		driverCallErrors.WithLabelValues(
+			driverType, driverName, op, errors.ErrCode(err).Name()).Inc()
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+)
+
+// defaultQuiesceTimeout is the longest time for which a filesystem is frozen
+// when rexray.storage.snapshots.quiesceTimeout is not set.
+const defaultQuiesceTimeout = 30 * time.Second
+
+type quiesceContextKey int
+
+const quiesceKey quiesceContextKey = iota
+
+// WithQuiesce returns a copy of the provided context that directs the storage
+// driver manager to freeze, or not to freeze, the filesystem mounted on a
+// volume's device while the volume is snapshotted. Without it the setting
+// rexray.storage.snapshots.quiesce applies.
+func WithQuiesce(ctx context.Context, quiesce bool) context.Context {
+	return context.WithValue(ctx, quiesceKey, quiesce)
+}
+
+// QuiesceFromContext returns the flag stored in the context by WithQuiesce
+// and whether or not one was stored.
+func QuiesceFromContext(ctx context.Context) (quiesce, ok bool) {
+	quiesce, ok = ctx.Value(quiesceKey).(bool)
+	return
+}
+
+func (r *RexRay) quiesce(ctx context.Context) bool {
+	if quiesce, ok := QuiesceFromContext(ctx); ok {
+		return quiesce
+	}
+	return r.Config.GetBool("rexray.storage.snapshots.quiesce")
+}
+
+// errQuiesceAsync returns the error for a snapshot that is both quiesced and
+// asynchronous. The filesystems are thawed when the storage driver returns,
+// which for an asynchronous snapshot may be before the snapshot is taken, so
+// only synchronous snapshots are quiesced.
+func errQuiesceAsync(volumeID string) error {
+	return goof.WithField(
+		"volumeID", volumeID, "cannot quiesce an asynchronous snapshot")
+}
+
+// quiesceVolume freezes the filesystems mounted on the devices of the volume
+// on the local instance and returns a function that thaws them. A volume that
+// is not mounted on the local instance is not frozen.
+func (r *RexRay) quiesceVolume(
+	ctx context.Context, volumeID string) (func(), error) {
+
+	mounts, err := r.localMounts(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(mounts) == 0 {
+		log.WithField("volumeID", volumeID).Debug(
+			"volume not mounted on local instance; not freezing")
+		return func() {}, nil
+	}
+
+	var mountPoints []string
+	for _, m := range mounts {
+		mountPoints = append(mountPoints, m.mountPoint)
+	}
+	return r.freeze(ctx, mountPoints)
+}
+
+// freeze freezes the filesystems mounted at the mount points and returns a
+// function that thaws them. A watchdog thaws the filesystems if the function
+// is not called before rexray.storage.snapshots.quiesceTimeout elapses, so a
+// snapshot that hangs never leaves a filesystem frozen. If a filesystem
+// cannot be frozen then those already frozen are thawed and the error is
+// returned.
+func (r *RexRay) freeze(
+	ctx context.Context, mountPoints []string) (func(), error) {
+
+	timeout := r.retryDuration(
+		"rexray.storage.snapshots.quiesceTimeout", defaultQuiesceTimeout)
+
+	// the filesystems are thawed even if the context is done
+	thawCtx := WithCaller(context.Background(), CallerFromContext(ctx))
+
+	var frozen []string
+	var once sync.Once
+	thaw := func() {
+		once.Do(func() {
+			for _, mountPoint := range frozen {
+				err := r.OS.ThawContext(thawCtx, mountPoint)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"mountPoint": mountPoint,
+						"error":      err}).Error("error thawing filesystem")
+				}
+			}
+		})
+	}
+
+	for _, mountPoint := range mountPoints {
+		if err := r.OS.FreezeContext(ctx, mountPoint); err != nil {
+			thaw()
+			return nil, err
+		}
+		frozen = append(frozen, mountPoint)
+	}
+
+	watchdog := time.AfterFunc(timeout, func() {
+		log.WithFields(log.Fields{
+			"mountPoints": frozen,
+			"timeout":     timeout,
+		}).Warn("thawing filesystems before snapshot completed")
+		thaw()
+	})
+
+	return func() {
+		watchdog.Stop()
+		thaw()
+	}, nil
+}
@@ -13,7 +13,10 @@ type RexRay struct {
 	OS      OSDriverManager
 	Volume  VolumeDriverManager
 	Storage StorageDriverManager
+	Tasks   TaskManager
+	Events  EventBus
 	drivers map[string]Driver
+	audit   AuditSink
 }
 
 // New creates a new REX-Ray instance and configures it with the
@@ -34,6 +37,9 @@ func New(conf gofig.Config) *RexRay {
 		log.WithField("driverName", name).Debug("constructed driver")
 	}
 
+	r.Tasks = newTaskManager(r)
+	r.Events = events
+
 	return r
 }
 
@@ -93,6 +99,26 @@ func (r *RexRay) InitDrivers() error {
 		}
 	}
 
+	if p := r.retryPolicy(); p.enabled() {
+		for n, d := range sd {
+			sd[n] = NewRetryStorageDriver(d, p)
+		}
+	}
+
+	for n, d := range od {
+		od[n] = newMetricsOSDriver(d)
+	}
+	for n, d := range vd {
+		vd[n] = newMetricsVolumeDriver(d)
+	}
+	for n, d := range sd {
+		sd[n] = newMetricsStorageDriver(d)
+	}
+
+	if err := r.initAudit(); err != nil {
+		return err
+	}
+
 	r.OS = &odm{
 		rexray:  r,
 		drivers: od,
@@ -106,6 +132,9 @@ func (r *RexRay) InitDrivers() error {
 	r.Storage = &sdm{
 		rexray:  r,
 		drivers: sd,
+		volIdx:  map[string]StorageDriver{},
+		snapIdx: map[string]StorageDriver{},
+		cache:   newStorageCache(r),
 	}
 
 	if err := r.OS.Init(r); err != nil {
@@ -134,3 +163,15 @@ func (r *RexRay) DriverNames() <-chan string {
 	}()
 	return c
 }
+
+// StorageCapabilities returns the capabilities of the registered storage
+// drivers keyed by driver name.
+func (r *RexRay) StorageCapabilities() map[string]*StorageCapabilities {
+	caps := map[string]*StorageCapabilities{}
+	for n, d := range r.drivers {
+		if sd, ok := d.(StorageDriver); ok {
+			caps[n] = GetStorageCapabilities(sd)
+		}
+	}
+	return caps
+}
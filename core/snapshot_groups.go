@@ -0,0 +1,297 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core/errors"
+)
+
+const (
+	// SnapshotGroupLabel is the label that holds the ID of the snapshot group
+	// to which a snapshot belongs.
+	SnapshotGroupLabel = "rexray-group"
+
+	// SnapshotGroupVolumeLabel is the label that holds the name of the volume
+	// of which a grouped snapshot was taken.
+	SnapshotGroupVolumeLabel = "rexray-volume"
+
+	snapshotGroupIDLength = 8
+)
+
+func (r *sdm) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*Snapshot, error) {
+	return r.CreateSnapshotGroupContext(
+		context.Background(), runAsync, snapshotNames, description)
+}
+
+// CreateSnapshotGroupContext snapshots the volumes with the storage driver's
+// native support if all of the volumes belong to a driver that has it.
+// Otherwise, or if the driver returns ErrNotImplemented for the volumes, the
+// filesystems mounted on the volumes' devices are frozen while each volume is
+// snapshotted in turn. Every snapshot is labeled with the ID of the group and
+// the name of its volume.
+func (r *sdm) CreateSnapshotGroupContext(
+	ctx context.Context,
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*Snapshot, error) {
+
+	if len(snapshotNames) == 0 {
+		return nil, errors.ErrMissingVolumeID
+	}
+
+	ctx, cancel := r.rexray.withTimeout(
+		ctx, "rexray.storage.timeouts.snapshot")
+	defer cancel()
+
+	groupID, err := newSnapshotGroupID()
+	if err != nil {
+		return nil, err
+	}
+
+	var volumeIDs []string
+	for volumeID := range snapshotNames {
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+	sort.Strings(volumeIDs)
+
+	var d StorageDriver
+	native := true
+	labels := map[string]map[string]string{}
+
+	for _, volumeID := range volumeIDs {
+		vd, err := r.volumeDriver(ctx, volumeID)
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckStorageCapability(vd, CapabilitySnapshots); err != nil {
+			return nil, err
+		}
+		if d == nil {
+			d = vd
+		} else if vd != d {
+			native = false
+		}
+
+		vols, err := r.GetVolumeContext(ctx, volumeID, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(vols) == 0 {
+			return nil, goof.WithFieldE(
+				"volumeID", volumeID, "error getting volume",
+				errors.ErrNoVolumesReturned)
+		}
+
+		labels[volumeID] = mergeLabels(LabelsFromContext(ctx),
+			map[string]string{
+				SnapshotGroupLabel:       groupID,
+				SnapshotGroupVolumeLabel: vols[0].Name,
+			})
+	}
+
+	log.WithFields(log.Fields{
+		"groupID":   groupID,
+		"volumeIDs": volumeIDs,
+		"native":    native && GetStorageCapabilities(d).SnapshotGroups,
+	}).Info("creating snapshot group")
+
+	if native && GetStorageCapabilities(d).SnapshotGroups {
+		snapshots, err := r.createSnapshotGroupNative(
+			ctx, d, runAsync, snapshotNames, labels, description)
+		if errors.ErrCode(err) != errors.ErrCodeNotImplemented {
+			return snapshots, err
+		}
+		log.WithFields(log.Fields{
+			"groupID": groupID,
+			"error":   err}).Info("freezing filesystems for snapshot group")
+	}
+	return r.createSnapshotGroupFrozen(
+		ctx, runAsync, volumeIDs, snapshotNames, labels, description)
+}
+
+// createSnapshotGroupNative snapshots the volumes with the storage driver's
+// CreateSnapshotGroup.
+func (r *sdm) createSnapshotGroupNative(
+	ctx context.Context,
+	d StorageDriver,
+	runAsync bool,
+	snapshotNames map[string]string,
+	labels map[string]map[string]string,
+	description string) (snapshots []*Snapshot, err error) {
+
+	ignoredFlags(d, runAsync, false)
+	defer r.rexray.auditOp(ctx, "CreateSnapshotGroup", d.Name(),
+		map[string]interface{}{
+			"snapshotNames": snapshotNames,
+			"description":   description,
+			"runAsync":      runAsync,
+		}, time.Now(), &err)
+
+	ld, nativeLabels := d.(LabelStorageDriver)
+	names := map[string]string{}
+	for volumeID, name := range snapshotNames {
+		if !nativeLabels {
+			if name, err = labelName(name, labels[volumeID]); err != nil {
+				return nil, err
+			}
+		}
+		names[volumeID] = name
+	}
+
+	if err = runMutation(ctx, func() (err error) {
+		snapshots, err = d.CreateSnapshotGroup(runAsync, names, description)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, s := range snapshots {
+		r.index(r.snapIdx, s.SnapshotID, d)
+		labelSnapshot(d, s)
+		if nativeLabels {
+			sl := labels[s.VolumeID]
+			if err := ld.SetSnapshotLabels(s.SnapshotID, sl); err != nil {
+				return nil, err
+			}
+			s.Labels = mergeLabels(s.Labels, sl)
+		}
+	}
+	return snapshots, nil
+}
+
+// createSnapshotGroupFrozen freezes the filesystems mounted on the volumes'
+// devices on the local instance, snapshots each volume, and then thaws the
+// filesystems. The filesystems are thawed even if a snapshot fails or takes
+// longer than the quiesce timeout. An asynchronous snapshot group cannot be
+// frozen and is rejected.
+func (r *sdm) createSnapshotGroupFrozen(
+	ctx context.Context,
+	runAsync bool,
+	volumeIDs []string,
+	snapshotNames map[string]string,
+	labels map[string]map[string]string,
+	description string) ([]*Snapshot, error) {
+
+	if runAsync {
+		return nil, goof.WithField("volumeIDs", volumeIDs,
+			"cannot freeze an asynchronous snapshot group")
+	}
+
+	var mountPoints []string
+	for _, volumeID := range volumeIDs {
+		mounts, err := r.rexray.localMounts(ctx, volumeID)
+		if err != nil {
+			return nil, err
+		}
+		if len(mounts) == 0 {
+			log.WithField("volumeID", volumeID).Warn(
+				"volume not mounted on local instance; not freezing")
+		}
+		for _, m := range mounts {
+			mountPoints = append(mountPoints, m.mountPoint)
+		}
+	}
+
+	thaw, err := r.rexray.freeze(ctx, mountPoints)
+	if err != nil {
+		return nil, err
+	}
+	defer thaw()
+
+	// the volumes are already frozen, so each snapshot is not quiesced again
+	ctx = WithQuiesce(ctx, false)
+
+	var snapshots []*Snapshot
+	for _, volumeID := range volumeIDs {
+		vs, err := r.CreateSnapshotContext(
+			WithLabels(ctx, labels[volumeID]),
+			runAsync, snapshotNames[volumeID], volumeID, description)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, vs...)
+	}
+	return snapshots, nil
+}
+
+func (r *sdm) GetSnapshotGroup(groupID string) ([]*Snapshot, error) {
+	return r.GetSnapshotGroupContext(context.Background(), groupID)
+}
+
+func (r *sdm) GetSnapshotGroupContext(
+	ctx context.Context, groupID string) ([]*Snapshot, error) {
+
+	if groupID == "" {
+		return nil, goof.New("missing snapshot group ID")
+	}
+
+	snapshots, err := r.GetSnapshotContext(ctx, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var group []*Snapshot
+	for _, s := range snapshots {
+		if s.Labels[SnapshotGroupLabel] == groupID {
+			group = append(group, s)
+		}
+	}
+	return group, nil
+}
+
+func (r *sdm) RestoreSnapshotGroup(
+	runAsync bool, groupID, volumeNameSuffix string) ([]*Volume, error) {
+	return r.RestoreSnapshotGroupContext(
+		context.Background(), runAsync, groupID, volumeNameSuffix)
+}
+
+func (r *sdm) RestoreSnapshotGroupContext(
+	ctx context.Context,
+	runAsync bool, groupID, volumeNameSuffix string) ([]*Volume, error) {
+
+	snapshots, err := r.GetSnapshotGroupContext(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, goof.WithField(
+			"groupID", groupID, "snapshot group not found")
+	}
+
+	var volumes []*Volume
+	for _, s := range snapshots {
+		volumeName := s.Labels[SnapshotGroupVolumeLabel]
+		if volumeName == "" {
+			volumeName = s.VolumeID
+		}
+		size, _ := strconv.ParseInt(s.VolumeSize, 10, 64)
+
+		v, err := r.CreateVolumeContext(
+			ctx, runAsync, volumeName+volumeNameSuffix, "", s.SnapshotID,
+			"", 0, size, "")
+		if err != nil {
+			return volumes, err
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, nil
+}
+
+func newSnapshotGroupID() (string, error) {
+	buf := make([]byte, snapshotGroupIDLength/2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", goof.WithError("error generating snapshot group ID", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,604 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/util"
+)
+
+const (
+	// SnapshotScheduleLabel is the label that holds the name of the schedule
+	// that created a snapshot. Only snapshots with this label are pruned by
+	// the schedule.
+	SnapshotScheduleLabel = "rexray-schedule"
+
+	// SnapshotScheduleTimeLabel is the label that holds the time, in seconds
+	// since the Unix epoch, at which a scheduled snapshot was created.
+	SnapshotScheduleTimeLabel = "rexray-scheduled"
+
+	snapshotScheduleStateFile  = "snapshot-schedules.json"
+	snapshotScheduleTimeFormat = "20060102T150405Z"
+)
+
+// snapshotSchedulers are the started schedulers in the process, so that the
+// admin module may report the status of schedules run by another module.
+var (
+	snapshotSchedulersLock sync.RWMutex
+	snapshotSchedulers     = map[*SnapshotScheduler]bool{}
+)
+
+// SnapshotSchedule is a schedule on which the selected volumes are
+// snapshotted and their older scheduled snapshots are pruned.
+type SnapshotSchedule struct {
+
+	// The schedule's name.
+	Name string
+
+	// The cron expression on which the schedule runs.
+	Cron string
+
+	// A pattern, as matched by path.Match, for the names of the volumes to
+	// snapshot. Every volume matches an empty pattern.
+	VolumeName string `json:",omitempty" yaml:",omitempty"`
+
+	// The labels a volume must have to be snapshotted.
+	VolumeLabels map[string]string `json:",omitempty" yaml:",omitempty"`
+
+	// The number of the schedule's most recent snapshots of each volume that
+	// are kept. Zero keeps every snapshot.
+	RetainCount int `json:",omitempty" yaml:",omitempty"`
+
+	// The age after which the schedule's snapshots are removed. Zero keeps
+	// snapshots regardless of their age.
+	RetainAge time.Duration `json:",omitempty" yaml:",omitempty"`
+
+	// The region to which each snapshot is copied, if any.
+	CopyRegion string `json:",omitempty" yaml:",omitempty"`
+
+	cron *CronSchedule
+}
+
+// SnapshotScheduleStatus is the status of a snapshot schedule.
+type SnapshotScheduleStatus struct {
+
+	// The schedule's name.
+	Name string
+
+	// The cron expression on which the schedule runs.
+	Cron string
+
+	// Whether or not the schedule is running.
+	Running bool
+
+	// The time at which the schedule last ran.
+	LastRun time.Time
+
+	// The time at which the schedule next runs.
+	NextRun time.Time
+
+	// The IDs of the snapshots created by the last run.
+	LastSnapshots []string `json:",omitempty" yaml:",omitempty"`
+
+	// The IDs of the snapshots removed by the last run.
+	LastPruned []string `json:",omitempty" yaml:",omitempty"`
+
+	// The error returned by the last run, if any.
+	LastError string `json:",omitempty" yaml:",omitempty"`
+}
+
+// SnapshotSchedules reads the snapshot schedules named by
+// rexray.storage.snapshots.schedules from the configuration. Each schedule is
+// configured with the keys under rexray.storage.snapshots.schedule.<name>.
+func SnapshotSchedules(r *RexRay) ([]*SnapshotSchedule, error) {
+	var schedules []*SnapshotSchedule
+	for _, name := range r.Config.GetStringSlice(
+		"rexray.storage.snapshots.schedules") {
+
+		if name == "" {
+			continue
+		}
+		key := func(k string) string {
+			return fmt.Sprintf(
+				"rexray.storage.snapshots.schedule.%s.%s", name, k)
+		}
+
+		s := &SnapshotSchedule{
+			Name:        name,
+			Cron:        r.Config.GetString(key("cron")),
+			VolumeName:  r.Config.GetString(key("volumeName")),
+			RetainCount: r.Config.GetInt(key("retainCount")),
+			CopyRegion:  r.Config.GetString(key("copyRegion")),
+		}
+
+		var err error
+		if s.cron, err = ParseCronSchedule(s.Cron); err != nil {
+			return nil, goof.WithFieldE(
+				"schedule", name, "invalid snapshot schedule", err)
+		}
+		if _, err := path.Match(s.VolumeName, ""); err != nil {
+			return nil, goof.WithFieldE(
+				"schedule", name, "invalid volume name pattern", err)
+		}
+		if s.VolumeLabels, err = ParseLabels(
+			r.Config.GetStringSlice(key("volumeLabels"))); err != nil {
+			return nil, goof.WithFieldE(
+				"schedule", name, "invalid volume labels", err)
+		}
+		if s.RetainAge, err = parseRetainAge(
+			r.Config.GetString(key("retainAge"))); err != nil {
+			return nil, goof.WithFieldE(
+				"schedule", name, "invalid retention age", err)
+		}
+		if s.RetainCount < 0 {
+			return nil, goof.WithField(
+				"schedule", name, "invalid retention count")
+		}
+
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// parseRetainAge parses a duration that may also be a number of days, such
+// as 7d.
+func parseRetainAge(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || days < 0 {
+			return 0, goof.WithField("value", v, "invalid duration")
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, goof.WithField("value", v, "invalid duration")
+	}
+	return d, nil
+}
+
+// SnapshotScheduler runs snapshot schedules. The time at which each schedule
+// last ran is persisted in the REX-Ray lib directory, so a schedule whose run
+// was missed while the scheduler was stopped runs once when it is started.
+type SnapshotScheduler struct {
+	sync.RWMutex
+	rexray    *RexRay
+	schedules map[string]*SnapshotSchedule
+	status    map[string]*SnapshotScheduleStatus
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	stateLock sync.Mutex
+}
+
+// NewSnapshotScheduler returns a scheduler for the configured snapshot
+// schedules.
+func NewSnapshotScheduler(r *RexRay) (*SnapshotScheduler, error) {
+	schedules, err := SnapshotSchedules(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SnapshotScheduler{
+		rexray:    r,
+		schedules: map[string]*SnapshotSchedule{},
+		status:    map[string]*SnapshotScheduleStatus{},
+	}
+
+	state := s.readState()
+	for _, ss := range schedules {
+		st := &SnapshotScheduleStatus{
+			Name:    ss.Name,
+			Cron:    ss.Cron,
+			NextRun: ss.cron.Next(time.Now()),
+		}
+		if prev, ok := state[ss.Name]; ok {
+			st.LastRun = prev.LastRun
+			st.LastSnapshots = prev.LastSnapshots
+			st.LastPruned = prev.LastPruned
+			st.LastError = prev.LastError
+		}
+		s.schedules[ss.Name] = ss
+		s.status[ss.Name] = st
+	}
+	return s, nil
+}
+
+// Schedules returns the scheduler's schedules.
+func (s *SnapshotScheduler) Schedules() []*SnapshotSchedule {
+	var schedules []*SnapshotSchedule
+	for _, ss := range s.schedules {
+		schedules = append(schedules, ss)
+	}
+	sort.Sort(snapshotSchedulesByName(schedules))
+	return schedules
+}
+
+// Status returns the status of each of the scheduler's schedules.
+func (s *SnapshotScheduler) Status() []*SnapshotScheduleStatus {
+	s.RLock()
+	defer s.RUnlock()
+	var status []*SnapshotScheduleStatus
+	for _, st := range s.status {
+		c := *st
+		status = append(status, &c)
+	}
+	sort.Sort(snapshotScheduleStatusByName(status))
+	return status
+}
+
+// Start runs each schedule in the background until Stop is invoked. The
+// operations are performed with the provided context.
+func (s *SnapshotScheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	snapshotSchedulersLock.Lock()
+	snapshotSchedulers[s] = true
+	snapshotSchedulersLock.Unlock()
+
+	for _, ss := range s.schedules {
+		s.wg.Add(1)
+		go s.loop(ctx, ss)
+	}
+}
+
+// Stop stops running the schedules and waits for any schedule that is
+// running to return.
+func (s *SnapshotScheduler) Stop() {
+	snapshotSchedulersLock.Lock()
+	delete(snapshotSchedulers, s)
+	snapshotSchedulersLock.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *SnapshotScheduler) loop(ctx context.Context, ss *SnapshotSchedule) {
+	defer s.wg.Done()
+
+	s.RLock()
+	lastRun := s.status[ss.Name].LastRun
+	s.RUnlock()
+
+	if !lastRun.IsZero() {
+		if missed := ss.cron.Next(lastRun); !missed.IsZero() &&
+			missed.Before(time.Now()) {
+			log.WithFields(log.Fields{
+				"schedule": ss.Name,
+				"missed":   missed}).Info("running missed snapshot schedule")
+			s.Run(ctx, ss.Name)
+		}
+	}
+
+	for {
+		next := ss.cron.Next(time.Now())
+		if next.IsZero() {
+			log.WithField("schedule", ss.Name).Warn(
+				"snapshot schedule never runs")
+			return
+		}
+		s.Lock()
+		s.status[ss.Name].NextRun = next
+		s.Unlock()
+
+		timer := time.NewTimer(next.Sub(time.Now()))
+		select {
+		case <-timer.C:
+			s.Run(ctx, ss.Name)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Run runs the named schedule now. It snapshots each of the selected
+// volumes, copies each snapshot to the schedule's copy region, and then
+// removes the schedule's snapshots of the volumes that are no longer
+// retained. A schedule that is already running is not run again.
+func (s *SnapshotScheduler) Run(
+	ctx context.Context, name string) (*SnapshotScheduleStatus, error) {
+
+	ss, ok := s.schedules[name]
+	if !ok {
+		return nil, goof.WithField(
+			"schedule", name, "unknown snapshot schedule")
+	}
+
+	s.Lock()
+	st := s.status[name]
+	if st.Running {
+		s.Unlock()
+		return nil, goof.WithField(
+			"schedule", name, "snapshot schedule is already running")
+	}
+	st.Running = true
+	s.Unlock()
+
+	now := time.Now().UTC()
+	snapshots, pruned, err := s.run(ctx, ss, now)
+
+	s.Lock()
+	st.Running = false
+	st.LastRun = now
+	st.LastSnapshots = snapshots
+	st.LastPruned = pruned
+	st.LastError = ""
+	if err != nil {
+		st.LastError = err.Error()
+	}
+	c := *st
+	s.Unlock()
+
+	lf := log.Fields{
+		"schedule":  name,
+		"snapshots": len(snapshots),
+		"pruned":    len(pruned),
+	}
+	if err != nil {
+		lf["error"] = err
+		log.WithFields(lf).Error("error running snapshot schedule")
+	} else {
+		log.WithFields(lf).Info("ran snapshot schedule")
+	}
+
+	if werr := s.writeState(); werr != nil {
+		log.WithField("error", werr).Warn(
+			"error writing snapshot schedule state")
+	}
+	return &c, err
+}
+
+// run snapshots and prunes each volume selected by the schedule. An error
+// with one volume does not prevent the others from being snapshotted; the
+// first error is returned. A volume whose snapshot, or the copy of it, fails
+// is not pruned.
+func (s *SnapshotScheduler) run(
+	ctx context.Context,
+	ss *SnapshotSchedule,
+	now time.Time) (snapshotIDs, prunedIDs []string, err error) {
+
+	volumes, err := s.rexray.Storage.GetVolumeContext(
+		WithLabelSelector(ctx, ss.VolumeLabels), "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var firstErr error
+	for _, v := range volumes {
+		if ok, _ := path.Match(ss.VolumeName, v.Name); !ok &&
+			ss.VolumeName != "" {
+			continue
+		}
+
+		snapshotID, err := s.snapshot(ctx, ss, v, now)
+		if snapshotID != "" {
+			snapshotIDs = append(snapshotIDs, snapshotID)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"schedule": ss.Name,
+				"volumeID": v.VolumeID,
+				"error":    err}).Error("error creating scheduled snapshot")
+			if firstErr == nil {
+				firstErr = err
+			}
+			// the older snapshots are kept until a run succeeds
+			continue
+		}
+
+		pruned, err := s.prune(ctx, ss, v, now)
+		prunedIDs = append(prunedIDs, pruned...)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"schedule": ss.Name,
+				"volumeID": v.VolumeID,
+				"error":    err}).Error("error pruning scheduled snapshots")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return snapshotIDs, prunedIDs, firstErr
+}
+
+// snapshot snapshots the volume and copies the snapshot to the schedule's
+// copy region, if any.
+func (s *SnapshotScheduler) snapshot(
+	ctx context.Context,
+	ss *SnapshotSchedule,
+	v *Volume,
+	now time.Time) (string, error) {
+
+	name := fmt.Sprintf("%s-%s-%s",
+		v.Name, ss.Name, now.Format(snapshotScheduleTimeFormat))
+	labels := map[string]string{
+		SnapshotScheduleLabel:     ss.Name,
+		SnapshotScheduleTimeLabel: strconv.FormatInt(now.Unix(), 10),
+	}
+
+	snapshots, err := s.rexray.Storage.CreateSnapshotContext(
+		WithLabels(ctx, labels), false, name, v.VolumeID,
+		fmt.Sprintf("Scheduled snapshot (%s)", ss.Name))
+	if err != nil {
+		return "", err
+	}
+	if len(snapshots) == 0 {
+		return "", goof.WithField(
+			"volumeID", v.VolumeID, "no snapshot returned")
+	}
+	snapshot := snapshots[0]
+
+	if ss.CopyRegion != "" {
+		if _, err := s.rexray.Storage.CopySnapshotContext(
+			ctx, false, "", snapshot.SnapshotID, "", snapshot.Name,
+			ss.CopyRegion); err != nil {
+			return snapshot.SnapshotID, goof.WithFieldsE(goof.Fields{
+				"snapshotID": snapshot.SnapshotID,
+				"region":     ss.CopyRegion,
+			}, "error copying scheduled snapshot", err)
+		}
+	}
+	return snapshot.SnapshotID, nil
+}
+
+// prune removes the schedule's snapshots of the volume that are beyond the
+// retention count or older than the retention age. The newest of the
+// schedule's snapshots is never removed.
+func (s *SnapshotScheduler) prune(
+	ctx context.Context,
+	ss *SnapshotSchedule,
+	v *Volume,
+	now time.Time) ([]string, error) {
+
+	if ss.RetainCount == 0 && ss.RetainAge == 0 {
+		return nil, nil
+	}
+
+	snapshots, err := s.rexray.Storage.GetSnapshotContext(
+		ctx, v.VolumeID, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []*scheduledSnapshot
+	for _, sn := range snapshots {
+		if sn.Labels[SnapshotScheduleLabel] != ss.Name {
+			continue
+		}
+		secs, err := strconv.ParseInt(
+			sn.Labels[SnapshotScheduleTimeLabel], 10, 64)
+		if err != nil {
+			continue
+		}
+		scheduled = append(scheduled,
+			&scheduledSnapshot{sn, time.Unix(secs, 0).UTC()})
+	}
+	sort.Sort(scheduledSnapshotsByTime(scheduled))
+
+	var pruned []string
+	for i, sn := range scheduled {
+		if i == 0 {
+			continue
+		}
+		expired := ss.RetainAge > 0 && now.Sub(sn.time) > ss.RetainAge
+		excess := ss.RetainCount > 0 && i >= ss.RetainCount
+		if !expired && !excess {
+			continue
+		}
+		if err := s.rexray.Storage.RemoveSnapshot(
+			sn.SnapshotID); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, sn.SnapshotID)
+	}
+	return pruned, nil
+}
+
+func (s *SnapshotScheduler) statePath() string {
+	return util.LibFilePath(snapshotScheduleStateFile)
+}
+
+func (s *SnapshotScheduler) readState() map[string]*SnapshotScheduleStatus {
+	state := map[string]*SnapshotScheduleStatus{}
+	buf, err := ioutil.ReadFile(s.statePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithField("error", err).Warn(
+				"error reading snapshot schedule state")
+		}
+		return state
+	}
+	if err := json.Unmarshal(buf, &state); err != nil {
+		log.WithField("error", err).Warn(
+			"error reading snapshot schedule state")
+	}
+	return state
+}
+
+// writeState persists the status of the schedules by writing it to a
+// temporary file and renaming the temporary file so that readers never
+// observe partially written state.
+func (s *SnapshotScheduler) writeState() error {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	state := s.readState()
+	s.RLock()
+	for name, st := range s.status {
+		c := *st
+		c.Running = false
+		state[name] = &c
+	}
+	s.RUnlock()
+
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	filePath := s.statePath()
+	tmpPath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+// SnapshotScheduleStatuses returns the status of the schedules run by every
+// started snapshot scheduler in the process.
+func SnapshotScheduleStatuses() []*SnapshotScheduleStatus {
+	snapshotSchedulersLock.RLock()
+	defer snapshotSchedulersLock.RUnlock()
+	var status []*SnapshotScheduleStatus
+	for s := range snapshotSchedulers {
+		status = append(status, s.Status()...)
+	}
+	sort.Sort(snapshotScheduleStatusByName(status))
+	return status
+}
+
+type scheduledSnapshot struct {
+	*Snapshot
+	time time.Time
+}
+
+// scheduledSnapshotsByTime sorts scheduled snapshots newest first.
+type scheduledSnapshotsByTime []*scheduledSnapshot
+
+func (a scheduledSnapshotsByTime) Len() int      { return len(a) }
+func (a scheduledSnapshotsByTime) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a scheduledSnapshotsByTime) Less(i, j int) bool {
+	return a[i].time.After(a[j].time)
+}
+
+type snapshotSchedulesByName []*SnapshotSchedule
+
+func (a snapshotSchedulesByName) Len() int           { return len(a) }
+func (a snapshotSchedulesByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a snapshotSchedulesByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+type snapshotScheduleStatusByName []*SnapshotScheduleStatus
+
+func (a snapshotScheduleStatusByName) Len() int      { return len(a) }
+func (a snapshotScheduleStatusByName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a snapshotScheduleStatusByName) Less(i, j int) bool {
+	return a[i].Name < a[j].Name
+}
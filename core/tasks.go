@@ -0,0 +1,415 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"github.com/akutz/gotil"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/util"
+)
+
+// TaskState is the state of a task.
+type TaskState string
+
+const (
+	// TaskStateQueued is the state of a task that has not yet started.
+	TaskStateQueued TaskState = "queued"
+
+	// TaskStateRunning is the state of a task that is running.
+	TaskStateRunning TaskState = "running"
+
+	// TaskStateSucceeded is the state of a task that completed without error.
+	TaskStateSucceeded TaskState = "succeeded"
+
+	// TaskStateFailed is the state of a task that completed with an error.
+	TaskStateFailed TaskState = "failed"
+)
+
+// Task is a record of an asynchronous operation.
+type Task struct {
+
+	// The task's ID.
+	ID string
+
+	// The name of the operation the task performs.
+	Operation string
+
+	// The task's state.
+	State TaskState
+
+	// The object returned by the operation once it has succeeded.
+	Result interface{}
+
+	// The error returned by the operation if it failed.
+	Error string
+
+	// The time at which the task was queued.
+	QueueTime time.Time
+
+	// The time at which the task started running.
+	StartTime time.Time
+
+	// The time at which the task completed.
+	CompleteTime time.Time
+}
+
+// Completed returns a flag indicating whether or not the task has completed.
+func (t *Task) Completed() bool {
+	return t.State == TaskStateSucceeded || t.State == TaskStateFailed
+}
+
+// TaskFunc is the operation performed by a task. The returned object is
+// recorded as the task's result.
+type TaskFunc func(ctx context.Context) (interface{}, error)
+
+// TaskManager tracks asynchronous operations. Task records are persisted so
+// they are visible to other REX-Ray processes on the same host.
+type TaskManager interface {
+
+	// Create records a new, queued task for the named operation.
+	Create(operation string) (*Task, error)
+
+	// Run runs the operation for the queued task with the provided ID and
+	// returns once the operation has completed.
+	Run(taskID string, f TaskFunc) (*Task, error)
+
+	// Submit records a new task for the named operation and runs the
+	// operation in the background.
+	Submit(operation string, f TaskFunc) (*Task, error)
+
+	// Get returns the task with the provided ID.
+	Get(taskID string) (*Task, error)
+
+	// List returns all of the known tasks.
+	List() ([]*Task, error)
+
+	// Wait returns once the task with the provided ID has completed or the
+	// context is done, whichever happens first. The task's record is polled,
+	// so a task that is run by another process, such as the background
+	// process started for the --runasync flag, is observed as well.
+	Wait(ctx context.Context, taskID string) (*Task, error)
+
+	// Done returns a channel that receives the task with the provided ID
+	// when it is completed by this process. The channel is closed after the
+	// task is sent. Use Wait for tasks that may be run by another process.
+	Done(taskID string) <-chan *Task
+}
+
+const (
+	taskPollInterval = time.Second
+	taskIDLength     = 16
+)
+
+type tm struct {
+	rexray  *RexRay
+	sem     chan bool
+	semOnce sync.Once
+
+	sync.Mutex
+	watchers map[string][]chan *Task
+}
+
+func newTaskManager(r *RexRay) *tm {
+	return &tm{
+		rexray:   r,
+		watchers: map[string][]chan *Task{},
+	}
+}
+
+// acquire blocks until fewer than rexray.tasks.maxConcurrent tasks are
+// running and returns a function that releases the acquired slot.
+func (t *tm) acquire() func() {
+	t.semOnce.Do(func() {
+		n := t.rexray.Config.GetInt("rexray.tasks.maxConcurrent")
+		if n > 0 {
+			t.sem = make(chan bool, n)
+		}
+	})
+	if t.sem == nil {
+		return func() {}
+	}
+	t.sem <- true
+	return func() { <-t.sem }
+}
+
+func (t *tm) dir() string {
+	d := util.LibFilePath("tasks")
+	os.MkdirAll(d, 0755)
+	return d
+}
+
+func (t *tm) filePath(taskID string) string {
+	return path.Join(t.dir(), taskID+".json")
+}
+
+func (t *tm) Create(operation string) (*Task, error) {
+	t.prune()
+
+	id, err := newTaskID()
+	if err != nil {
+		return nil, err
+	}
+
+	task := &Task{
+		ID:        id,
+		Operation: operation,
+		State:     TaskStateQueued,
+		QueueTime: time.Now().UTC(),
+	}
+	if err := t.write(task); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"taskID":    task.ID,
+		"operation": operation}).Debug("created task")
+
+	return task, nil
+}
+
+func (t *tm) Run(taskID string, f TaskFunc) (*Task, error) {
+	task, err := t.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.State != TaskStateQueued {
+		return nil, goof.WithFields(goof.Fields{
+			"taskID": taskID,
+			"state":  task.State,
+		}, "task is not queued")
+	}
+
+	release := t.acquire()
+	defer release()
+
+	task.State = TaskStateRunning
+	task.StartTime = time.Now().UTC()
+	if err := t.write(task); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"taskID":    task.ID,
+		"operation": task.Operation}).Debug("running task")
+
+	result, opErr := f(context.Background())
+
+	task.CompleteTime = time.Now().UTC()
+	if opErr != nil {
+		task.State = TaskStateFailed
+		task.Error = opErr.Error()
+	} else {
+		task.State = TaskStateSucceeded
+		task.Result = result
+	}
+
+	if err := t.write(task); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"taskID":    task.ID,
+		"operation": task.Operation,
+		"state":     task.State}).Debug("completed task")
+
+	t.notify(task)
+
+	return task, nil
+}
+
+func (t *tm) Submit(operation string, f TaskFunc) (*Task, error) {
+	task, err := t.Create(operation)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if _, err := t.Run(task.ID, f); err != nil {
+			log.WithFields(log.Fields{
+				"taskID": task.ID,
+				"error":  err}).Error("error running task")
+		}
+	}()
+
+	return task, nil
+}
+
+func (t *tm) Get(taskID string) (*Task, error) {
+	if !isValidTaskID(taskID) {
+		return nil, goof.WithField("taskID", taskID, "invalid task ID")
+	}
+
+	buf, err := ioutil.ReadFile(t.filePath(taskID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, goof.WithField("taskID", taskID, "task not found")
+		}
+		return nil, goof.WithFieldE("taskID", taskID, "error reading task", err)
+	}
+
+	task := &Task{}
+	if err := json.Unmarshal(buf, task); err != nil {
+		return nil, goof.WithFieldE("taskID", taskID, "error reading task", err)
+	}
+	return task, nil
+}
+
+func (t *tm) List() ([]*Task, error) {
+	fis, err := ioutil.ReadDir(t.dir())
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*Task
+	for _, fi := range fis {
+		if !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		task, err := t.Get(strings.TrimSuffix(fi.Name(), ".json"))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"fileName": fi.Name(),
+				"error":    err}).Warn("error reading task")
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (t *tm) Wait(ctx context.Context, taskID string) (*Task, error) {
+	done := t.watch(taskID)
+	defer t.unwatch(taskID, done)
+
+	for {
+		task, err := t.Get(taskID)
+		if err != nil {
+			return nil, err
+		}
+		if task.Completed() {
+			return task, nil
+		}
+
+		select {
+		case task := <-done:
+			return task, nil
+		case <-time.After(taskPollInterval):
+		case <-ctx.Done():
+			return task, ctx.Err()
+		}
+	}
+}
+
+func (t *tm) Done(taskID string) <-chan *Task {
+	return t.watch(taskID)
+}
+
+func (t *tm) watch(taskID string) chan *Task {
+	c := make(chan *Task, 1)
+	t.Lock()
+	t.watchers[taskID] = append(t.watchers[taskID], c)
+	t.Unlock()
+	return c
+}
+
+func (t *tm) unwatch(taskID string, c chan *Task) {
+	t.Lock()
+	defer t.Unlock()
+	watchers := t.watchers[taskID]
+	for i, w := range watchers {
+		if w == c {
+			watchers = append(watchers[:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(watchers) == 0 {
+		delete(t.watchers, taskID)
+	} else {
+		t.watchers[taskID] = watchers
+	}
+}
+
+func (t *tm) notify(task *Task) {
+	t.Lock()
+	watchers := t.watchers[task.ID]
+	delete(t.watchers, task.ID)
+	t.Unlock()
+
+	for _, c := range watchers {
+		c <- task
+		close(c)
+	}
+}
+
+// write persists the task by writing it to a temporary file and renaming the
+// temporary file so that readers never observe a partially written task.
+func (t *tm) write(task *Task) error {
+	buf, err := json.Marshal(task)
+	if err != nil {
+		return goof.WithFieldE("taskID", task.ID, "error writing task", err)
+	}
+
+	filePath := t.filePath(task.ID)
+	tmpPath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, buf, 0644); err != nil {
+		return goof.WithFieldE("taskID", task.ID, "error writing task", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return goof.WithFieldE("taskID", task.ID, "error writing task", err)
+	}
+	return nil
+}
+
+// prune removes the records of tasks that completed before the retention
+// period.
+func (t *tm) prune() {
+	v := t.rexray.Config.GetString("rexray.tasks.retention")
+	if v == "" {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.WithField("value", v).Warn("invalid task retention")
+		return
+	}
+
+	tasks, err := t.List()
+	if err != nil {
+		return
+	}
+	for _, task := range tasks {
+		if task.Completed() && time.Since(task.CompleteTime) > d {
+			filePath := t.filePath(task.ID)
+			if gotil.FileExists(filePath) {
+				os.Remove(filePath)
+			}
+		}
+	}
+}
+
+func newTaskID() (string, error) {
+	buf := make([]byte, taskIDLength/2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", goof.WithError("error generating task ID", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func isValidTaskID(taskID string) bool {
+	if len(taskID) != taskIDLength {
+		return false
+	}
+	_, err := hex.DecodeString(taskID)
+	return err == nil
+}
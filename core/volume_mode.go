@@ -0,0 +1,40 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/akutz/goof"
+)
+
+// VolumeModeLabel is the label that holds the mode in which a volume is
+// presented to the consumers of the volume driver.
+const VolumeModeLabel = "rexray-mode"
+
+const (
+	// VolumeModeFilesystem is the mode of a volume whose device has a
+	// filesystem that is mounted for its consumers. It is the mode of a
+	// volume without the VolumeModeLabel.
+	VolumeModeFilesystem = "filesystem"
+
+	// VolumeModeBlock is the mode of a volume whose attached device is
+	// provided to its consumers without a filesystem.
+	VolumeModeBlock = "block"
+)
+
+// ParseVolumeMode returns the volume mode named by the provided string. An
+// empty string is the filesystem mode.
+func ParseVolumeMode(mode string) (string, error) {
+	switch strings.ToLower(mode) {
+	case "", VolumeModeFilesystem:
+		return VolumeModeFilesystem, nil
+	case VolumeModeBlock:
+		return VolumeModeBlock, nil
+	}
+	return "", goof.WithField("mode", mode, "invalid volume mode")
+}
+
+// IsBlockVolume returns a flag indicating whether or not the volume has the
+// block mode.
+func IsBlockVolume(v *Volume) bool {
+	return strings.EqualFold(v.Labels[VolumeModeLabel], VolumeModeBlock)
+}
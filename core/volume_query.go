@@ -0,0 +1,276 @@
+package core
+
+import (
+	"encoding/base64"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core/errors"
+)
+
+// VolumeQuery describes the volumes to return from QueryVolumes. Every
+// filter that is set must match for a volume to be returned.
+type VolumeQuery struct {
+
+	// The ID of the volume.
+	VolumeID string
+
+	// The name of the volume.
+	VolumeName string
+
+	// A pattern, such as db-*, that the volume's name must match. The
+	// pattern syntax is that of path.Match.
+	NamePattern string
+
+	// The volume status.
+	Status string
+
+	// The availability zone of the volume.
+	AvailabilityZone string
+
+	// The volume type.
+	VolumeType string
+
+	// The ID of an instance to which the volume is attached.
+	InstanceID string
+
+	// The labels the volume must have.
+	Labels map[string]string
+
+	// The maximum number of volumes to return. Zero returns all of the
+	// volumes.
+	Limit int
+
+	// The continuation token returned with the previous page of volumes.
+	Token string
+}
+
+// VolumeQueryResult is a page of the volumes returned by QueryVolumes.
+type VolumeQueryResult struct {
+
+	// The volumes.
+	Volumes []*Volume
+
+	// The token with which to request the next page of volumes. The token is
+	// empty if there are no more volumes.
+	NextToken string `json:",omitempty" yaml:",omitempty"`
+}
+
+// QueryStorageDriver is implemented by storage drivers that can apply some or
+// all of a volume query's filters on the storage platform. The storage driver
+// manager applies every filter again to the volumes the driver returns, so a
+// driver may ignore the filters it does not support. Limit and Token are
+// always handled by the storage driver manager.
+type QueryStorageDriver interface {
+	StorageDriver
+
+	// GetVolumesByQuery returns the volumes that match the query's filters.
+	GetVolumesByQuery(query *VolumeQuery) ([]*Volume, error)
+}
+
+// Match returns a flag indicating whether or not the volume matches all of
+// the query's filters.
+func (q *VolumeQuery) Match(v *Volume) bool {
+	if q.VolumeID != "" && v.VolumeID != q.VolumeID {
+		return false
+	}
+	if q.VolumeName != "" && v.Name != q.VolumeName {
+		return false
+	}
+	if q.NamePattern != "" {
+		if ok, _ := path.Match(q.NamePattern, v.Name); !ok {
+			return false
+		}
+	}
+	if q.Status != "" && !strings.EqualFold(v.Status, q.Status) {
+		return false
+	}
+	if q.AvailabilityZone != "" && v.AvailabilityZone != q.AvailabilityZone {
+		return false
+	}
+	if q.VolumeType != "" && v.VolumeType != q.VolumeType {
+		return false
+	}
+	if q.InstanceID != "" {
+		var attached bool
+		for _, a := range v.Attachments {
+			if a.InstanceID == q.InstanceID {
+				attached = true
+				break
+			}
+		}
+		if !attached {
+			return false
+		}
+	}
+	return MatchLabels(v.Labels, q.Labels)
+}
+
+// queryVolume is a volume returned by a driver while a query is processed.
+type queryVolume struct {
+	driverIndex int
+	driverName  string
+	volume      *Volume
+}
+
+type queryVolumes []*queryVolume
+
+func (a queryVolumes) Len() int      { return len(a) }
+func (a queryVolumes) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a queryVolumes) Less(i, j int) bool {
+	if a[i].driverIndex != a[j].driverIndex {
+		return a[i].driverIndex < a[j].driverIndex
+	}
+	return a[i].volume.VolumeID < a[j].volume.VolumeID
+}
+
+// newQueryToken returns a continuation token that resumes a query after the
+// volume.
+func newQueryToken(qv *queryVolume) string {
+	return base64.URLEncoding.EncodeToString(
+		[]byte(qv.driverName + "/" + qv.volume.VolumeID))
+}
+
+// parseQueryToken returns the name of the driver and the ID of the volume
+// after which a query resumes.
+func parseQueryToken(token string) (string, string, error) {
+	buf, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", goof.WithFieldE(
+			"token", token, "invalid continuation token", err)
+	}
+	parts := strings.SplitN(string(buf), "/", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", goof.WithField(
+			"token", token, "invalid continuation token")
+	}
+	return parts[0], parts[1], nil
+}
+
+// queryDriverVolumes gets the volumes from the driver that match the query.
+func queryDriverVolumes(
+	d StorageDriver, query *VolumeQuery) ([]*Volume, error) {
+
+	if qd, ok := d.(QueryStorageDriver); ok {
+		volumes, err := qd.GetVolumesByQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range volumes {
+			labelVolume(d, v)
+		}
+		return volumes, nil
+	}
+	return getVolume(d, query.VolumeID, query.VolumeName)
+}
+
+func (r *sdm) QueryVolumes(query *VolumeQuery) (*VolumeQueryResult, error) {
+	return r.QueryVolumesContext(context.Background(), query)
+}
+
+func (r *sdm) QueryVolumesContext(
+	ctx context.Context, query *VolumeQuery) (*VolumeQueryResult, error) {
+	if len(r.drivers) == 0 {
+		return nil, errors.ErrNoStorageDetected
+	}
+	if query == nil {
+		query = &VolumeQuery{}
+	}
+	if query.NamePattern != "" {
+		if _, err := path.Match(query.NamePattern, ""); err != nil {
+			return nil, goof.WithFieldE(
+				"namePattern", query.NamePattern, "invalid name pattern", err)
+		}
+	}
+
+	var afterVolumeID string
+	afterIndex := -1
+	if query.Token != "" {
+		afterDriver, volumeID, err := parseQueryToken(query.Token)
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range r.driverNames() {
+			if n == afterDriver {
+				afterIndex = i
+			}
+		}
+		if afterIndex < 0 {
+			return nil, goof.WithField(
+				"token", query.Token, "invalid continuation token")
+		}
+		afterVolumeID = volumeID
+	}
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.storage.timeouts.get")
+	defer cancel()
+
+	driverName := StorageDriverFromContext(ctx)
+	if driverName != "" {
+		if _, err := r.Driver(driverName); err != nil {
+			return nil, err
+		}
+	}
+
+	var firstErr error
+	var failed, queried int
+	var all queryVolumes
+
+	for i, n := range r.driverNames() {
+		if i < afterIndex ||
+			(driverName != "" && !strings.EqualFold(n, driverName)) {
+			continue
+		}
+		queried++
+		d := r.drivers[n]
+		var volumes []*Volume
+		err := runContext(ctx, func() (err error) {
+			volumes, err = queryDriverVolumes(d, query)
+			return
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"driverName": n,
+				"query":      query,
+				"error":      err}).Warn("error querying volumes")
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		for _, v := range volumes {
+			r.index(r.volIdx, v.VolumeID, d)
+			if query.Match(v) {
+				all = append(all, &queryVolume{i, n, v})
+			}
+		}
+	}
+
+	if queried > 0 && failed == queried {
+		return nil, firstErr
+	}
+
+	sort.Sort(all)
+
+	result := &VolumeQueryResult{}
+	var last *queryVolume
+	for _, qv := range all {
+		if qv.driverIndex == afterIndex &&
+			qv.volume.VolumeID <= afterVolumeID {
+			continue
+		}
+		if query.Limit > 0 && len(result.Volumes) == query.Limit {
+			result.NextToken = newQueryToken(last)
+			break
+		}
+		result.Volumes = append(result.Volumes, qv.volume)
+		last = qv
+	}
+	return result, nil
+}
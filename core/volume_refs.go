@@ -0,0 +1,208 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/util"
+)
+
+const volumeRefsFileName = "volumes.json"
+
+// volumeRefsLock serializes access to the volume reference state file among
+// all of the volume driver managers in the process.
+var volumeRefsLock sync.Mutex
+
+// MountRef is a reference held on a mounted volume.
+type MountRef struct {
+
+	// The name of the component that mounted the volume.
+	Caller string `json:",omitempty"`
+
+	// The ID the caller assigned to the mount.
+	MountID string `json:",omitempty"`
+
+	// The time at which the reference was taken.
+	Time time.Time
+}
+
+// VolumeMountRefs are the references held on a mounted volume. A volume is
+// unmounted once the last of its references is released.
+type VolumeMountRefs struct {
+
+	// The path at which the volume is mounted.
+	MountPath string `json:",omitempty"`
+
+	// The references held on the volume. A caller holds at most one
+	// reference for each of its mount IDs.
+	Refs []*MountRef
+}
+
+// index returns the index of the reference held by the caller and mount ID,
+// or -1 if there is none.
+func (vr *VolumeMountRefs) index(caller, mountID string) int {
+	for i, ref := range vr.Refs {
+		if ref.Caller == caller && ref.MountID == mountID {
+			return i
+		}
+	}
+	return -1
+}
+
+// volumeRefsState is the persisted state of the references held on all of
+// the volumes mounted by REX-Ray.
+type volumeRefsState map[string]*VolumeMountRefs
+
+type mountRefContextKey int
+
+const (
+	mountCallerContextKey mountRefContextKey = iota
+	mountIDContextKey
+)
+
+// WithMountRef returns a copy of the provided context that identifies the
+// caller mounting or unmounting a volume and the ID the caller uses for the
+// mount. The volume driver manager records both with the reference it takes
+// on the volume.
+func WithMountRef(
+	ctx context.Context, caller, mountID string) context.Context {
+	ctx = context.WithValue(ctx, mountCallerContextKey, caller)
+	return context.WithValue(ctx, mountIDContextKey, mountID)
+}
+
+// MountRefFromContext returns the caller and mount ID stored in the context
+// by WithMountRef.
+func MountRefFromContext(ctx context.Context) (caller, mountID string) {
+	caller, _ = ctx.Value(mountCallerContextKey).(string)
+	mountID, _ = ctx.Value(mountIDContextKey).(string)
+	return
+}
+
+func volumeRefsFilePath() string {
+	return util.LibFilePath(volumeRefsFileName)
+}
+
+// readVolumeRefs reads the volume reference state. The caller must hold
+// volumeRefsLock.
+func readVolumeRefs() volumeRefsState {
+	state := volumeRefsState{}
+
+	buf, err := ioutil.ReadFile(volumeRefsFilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithField("error", err).Warn("error reading volume refs")
+		}
+		return state
+	}
+
+	if err := json.Unmarshal(buf, &state); err != nil {
+		log.WithField("error", err).Warn("error reading volume refs")
+		return volumeRefsState{}
+	}
+	return state
+}
+
+// writeVolumeRefs writes the volume reference state. The caller must hold
+// volumeRefsLock.
+func writeVolumeRefs(state volumeRefsState) {
+	buf, err := json.Marshal(state)
+	if err != nil {
+		log.WithField("error", err).Error("error writing volume refs")
+		return
+	}
+
+	filePath := volumeRefsFilePath()
+	tmpPath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, buf, 0644); err != nil {
+		log.WithField("error", err).Error("error writing volume refs")
+		return
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		log.WithField("error", err).Error("error writing volume refs")
+	}
+}
+
+// updateVolumeRefs reads the volume reference state, invokes f with it, and
+// writes it back if f returns true.
+func updateVolumeRefs(f func(state volumeRefsState) bool) {
+	volumeRefsLock.Lock()
+	defer volumeRefsLock.Unlock()
+
+	state := readVolumeRefs()
+	if f(state) {
+		writeVolumeRefs(state)
+	}
+}
+
+// reconcile drops the references held on volumes that are no longer mounted.
+// A volume whose mount path is not known is kept since it cannot be checked.
+// The mount path of a volume with the block mode is a link to its device, so
+// such a volume is kept while the link resolves to a device.
+func (r *vdm) reconcile() {
+	updateVolumeRefs(func(state volumeRefsState) bool {
+		if len(state) == 0 {
+			return false
+		}
+
+		mounts, err := r.rexray.OS.GetMounts("", "")
+		if err != nil {
+			log.WithField("error", err).Warn(
+				"error getting mounts to reconcile volume refs")
+			return false
+		}
+
+		rootPath := r.rexray.Config.GetString("linux.volume.rootPath")
+
+		for volumeName, vr := range state {
+			if vr.MountPath == "" ||
+				isMountPath(mounts, vr.MountPath, rootPath) ||
+				isDeviceLink(vr.MountPath) {
+				log.WithFields(log.Fields{
+					"volumeName": volumeName,
+					"count":      len(vr.Refs),
+				}).Info("restored count")
+				continue
+			}
+			log.WithFields(log.Fields{
+				"volumeName": volumeName,
+				"mountPath":  vr.MountPath,
+			}).Info("dropped count for volume that is not mounted")
+			delete(state, volumeName)
+		}
+		return true
+	})
+}
+
+// isMountPath returns a flag indicating whether or not the mount path is a
+// mount point, or the root path inside of a mount point.
+func isMountPath(mounts MountInfoArray, mountPath, rootPath string) bool {
+	mountPoint := mountPath
+	if rootPath != "" && strings.HasSuffix(mountPath, rootPath) {
+		mountPoint = strings.TrimSuffix(mountPath, rootPath)
+	}
+	for _, m := range mounts {
+		if m.Mountpoint == mountPath || m.Mountpoint == mountPoint {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeviceLink returns a flag indicating whether or not the path is a link
+// that resolves to a device. A device's node is removed once it is detached,
+// so the link of a detached device no longer resolves.
+func isDeviceLink(p string) bool {
+	fi, err := os.Lstat(p)
+	if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	fi, err = os.Stat(p)
+	return err == nil && fi.Mode()&os.ModeDevice != 0
+}
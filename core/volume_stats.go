@@ -0,0 +1,244 @@
+package core
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// MountStats is the usage of a mounted filesystem and the I/O counters of the
+// device on which it is mounted.
+type MountStats struct {
+
+	// The size of the filesystem in bytes.
+	CapacityBytes uint64
+
+	// The number of bytes in use.
+	UsedBytes uint64
+
+	// The number of bytes available to unprivileged users.
+	AvailableBytes uint64
+
+	// The number of inodes in the filesystem.
+	Inodes uint64
+
+	// The number of inodes in use.
+	InodesUsed uint64
+
+	// The number of free inodes.
+	InodesFree uint64
+
+	// The I/O counters of the device. Devices that are not block devices,
+	// such as NFS exports, have no counters.
+	IO *DeviceIOStats `json:",omitempty" yaml:",omitempty"`
+}
+
+// DeviceIOStats are the I/O counters of a block device since it was
+// attached.
+type DeviceIOStats struct {
+
+	// The number of read requests completed and merged with other requests.
+	ReadIOs    uint64
+	ReadMerges uint64
+
+	// The number of bytes read.
+	ReadBytes uint64
+
+	// The total time, in milliseconds, read requests have waited.
+	ReadTimeMs uint64
+
+	// The number of write requests completed and merged with other requests.
+	WriteIOs    uint64
+	WriteMerges uint64
+
+	// The number of bytes written.
+	WriteBytes uint64
+
+	// The total time, in milliseconds, write requests have waited.
+	WriteTimeMs uint64
+
+	// The number of requests in progress.
+	InFlight uint64
+
+	// The time, in milliseconds, the device has had requests in progress.
+	IOTimeMs uint64
+
+	// The total time, in milliseconds, requests have been in progress,
+	// weighted by the number of requests.
+	WeightedIOTimeMs uint64
+}
+
+// VolumeStats are the statistics of a volume mounted on the local instance.
+type VolumeStats struct {
+
+	// The volume.
+	Volume *Volume
+
+	// The device of the volume on the local instance.
+	DeviceName string
+
+	// The path at which the device is mounted.
+	MountPoint string
+
+	// The usage of the filesystem and the I/O counters of the device.
+	*MountStats
+}
+
+// Stats returns the statistics of the volumes mounted on the local instance.
+func (r *vdm) Stats() ([]*VolumeStats, error) {
+	return r.StatsContext(context.Background(), "", "")
+}
+
+// StatsContext returns the statistics of the volume of volumeName or
+// volumeID or, if neither is provided, of all of the volumes mounted on the
+// local instance. A volume that is not mounted has no statistics.
+func (r *vdm) StatsContext(
+	ctx context.Context, volumeName, volumeID string) ([]*VolumeStats, error) {
+
+	ctx, cancel := r.rexray.withTimeout(ctx, "rexray.volume.timeouts.stats")
+	defer cancel()
+
+	vols, err := r.attachedVolumes(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []*VolumeStats{}
+	for _, v := range vols {
+		if volumeID != "" && v.VolumeID != volumeID {
+			continue
+		}
+		if volumeName != "" && v.Name != volumeName {
+			continue
+		}
+		for _, a := range v.Attachments {
+			if a.DeviceName == "" {
+				continue
+			}
+			mounts, err := r.rexray.OS.GetMounts(a.DeviceName, "")
+			if err != nil {
+				return nil, err
+			}
+			if len(mounts) == 0 {
+				continue
+			}
+			ms, err := r.rexray.OS.StatsContext(
+				ctx, a.DeviceName, mounts[0].Mountpoint)
+			if err != nil {
+				return nil, err
+			}
+			stats = append(stats, &VolumeStats{
+				Volume:     v,
+				DeviceName: a.DeviceName,
+				MountPoint: mounts[0].Mountpoint,
+				MountStats: ms,
+			})
+		}
+	}
+	return stats, nil
+}
+
+var volumeStatsLabels = []string{"volume_name", "volume_id", "device"}
+
+func newVolumeStatsDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "volume", name),
+		help, volumeStatsLabels, nil)
+}
+
+var (
+	volumeCapacityBytesDesc = newVolumeStatsDesc(
+		"capacity_bytes", "The size of the volume's filesystem.")
+	volumeUsedBytesDesc = newVolumeStatsDesc(
+		"used_bytes", "The bytes in use on the volume's filesystem.")
+	volumeAvailableBytesDesc = newVolumeStatsDesc(
+		"available_bytes", "The bytes available on the volume's filesystem.")
+	volumeInodesDesc = newVolumeStatsDesc(
+		"inodes", "The inodes of the volume's filesystem.")
+	volumeInodesUsedDesc = newVolumeStatsDesc(
+		"inodes_used", "The inodes in use on the volume's filesystem.")
+	volumeInodesFreeDesc = newVolumeStatsDesc(
+		"inodes_free", "The free inodes of the volume's filesystem.")
+	volumeReadIOsDesc = newVolumeStatsDesc(
+		"read_ios_total", "The read requests completed by the volume's device.")
+	volumeWriteIOsDesc = newVolumeStatsDesc(
+		"write_ios_total",
+		"The write requests completed by the volume's device.")
+	volumeReadBytesDesc = newVolumeStatsDesc(
+		"read_bytes_total", "The bytes read from the volume's device.")
+	volumeWriteBytesDesc = newVolumeStatsDesc(
+		"write_bytes_total", "The bytes written to the volume's device.")
+	volumeIOTimeDesc = newVolumeStatsDesc(
+		"io_time_seconds_total",
+		"The time the volume's device has had requests in progress.")
+)
+
+// volumeStatsCollector collects the statistics of the volumes mounted on the
+// local instance each time the metrics are gathered.
+type volumeStatsCollector struct {
+	r       *RexRay
+	timeout time.Duration
+}
+
+// NewVolumeStatsCollector returns a collector of the statistics of the
+// volumes mounted on the local instance by the volume driver manager of the
+// provided REX-Ray instance. The statistics are collected within the timeout.
+func NewVolumeStatsCollector(
+	r *RexRay, timeout time.Duration) prometheus.Collector {
+	return &volumeStatsCollector{r: r, timeout: timeout}
+}
+
+func (c *volumeStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- volumeCapacityBytesDesc
+	ch <- volumeUsedBytesDesc
+	ch <- volumeAvailableBytesDesc
+	ch <- volumeInodesDesc
+	ch <- volumeInodesUsedDesc
+	ch <- volumeInodesFreeDesc
+	ch <- volumeReadIOsDesc
+	ch <- volumeWriteIOsDesc
+	ch <- volumeReadBytesDesc
+	ch <- volumeWriteBytesDesc
+	ch <- volumeIOTimeDesc
+}
+
+func (c *volumeStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.r.Volume.StatsContext(ctx, "", "")
+	if err != nil {
+		log.WithField("error", err).Warn("error collecting volume stats")
+		return
+	}
+
+	for _, s := range stats {
+		lv := []string{s.Volume.Name, s.Volume.VolumeID, s.DeviceName}
+		gauge := func(d *prometheus.Desc, v uint64) {
+			ch <- prometheus.MustNewConstMetric(
+				d, prometheus.GaugeValue, float64(v), lv...)
+		}
+		counter := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(
+				d, prometheus.CounterValue, v, lv...)
+		}
+
+		gauge(volumeCapacityBytesDesc, s.CapacityBytes)
+		gauge(volumeUsedBytesDesc, s.UsedBytes)
+		gauge(volumeAvailableBytesDesc, s.AvailableBytes)
+		gauge(volumeInodesDesc, s.Inodes)
+		gauge(volumeInodesUsedDesc, s.InodesUsed)
+		gauge(volumeInodesFreeDesc, s.InodesFree)
+
+		if s.IO == nil {
+			continue
+		}
+		counter(volumeReadIOsDesc, float64(s.IO.ReadIOs))
+		counter(volumeWriteIOsDesc, float64(s.IO.WriteIOs))
+		counter(volumeReadBytesDesc, float64(s.IO.ReadBytes))
+		counter(volumeWriteBytesDesc, float64(s.IO.WriteBytes))
+		counter(volumeIOTimeDesc, float64(s.IO.IOTimeMs)/1000)
+	}
+}
@@ -7,4 +7,5 @@ import (
 	_ "github.com/emccode/rexray/daemon/module/admin"
 	_ "github.com/emccode/rexray/daemon/module/docker/remotevolumedriver"
 	_ "github.com/emccode/rexray/daemon/module/docker/volumedriver"
+	_ "github.com/emccode/rexray/daemon/module/snapshot"
 )
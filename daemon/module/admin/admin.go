@@ -15,7 +15,10 @@ import (
 	"github.com/akutz/gotil"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
 
+	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/daemon/module"
 )
 
@@ -23,10 +26,15 @@ const (
 	modPort        = 7979
 	modName        = "AdminModule"
 	modDescription = "The REX-Ray admin module"
+
+	// statsTimeout is the deadline for collecting the volume statistics for
+	// the metrics endpoint. It is less than the server's write timeout.
+	statsTimeout = 5 * time.Second
 )
 
 type mod struct {
 	id   int32
+	r    *core.RexRay
 	name string
 	addr string
 	desc string
@@ -41,6 +49,7 @@ func init() {
 	addr := fmt.Sprintf("tcp://:%d", modPort)
 	mc := &module.Config{
 		Address: addr,
+		Config:  gofig.New(),
 	}
 	module.RegisterModule(modName, false, newModule, []*module.Config{mc})
 }
@@ -48,6 +57,7 @@ func init() {
 func newModule(id int32, config *module.Config) (module.Module, error) {
 	return &mod{
 		id:   id,
+		r:    core.New(config.Config),
 		name: modName,
 		desc: modDescription,
 		addr: config.Address,
@@ -310,6 +320,126 @@ func moduleInstStartHandler(w http.ResponseWriter, req *http.Request) {
 	w.Write(jsonBuf)
 }
 
+func snapshotScheduleHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	jsonBuf, jsonBufErr := json.MarshalIndent(
+		core.SnapshotScheduleStatuses(), "", "  ")
+	if jsonBufErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error servicing request ERR: %v", jsonBufErr)
+		return
+	}
+
+	_, writeErr := w.Write(jsonBuf)
+	if writeErr != nil {
+		log.Printf("Error writing json buffer ERR: %v", writeErr)
+	}
+}
+
+func (m *mod) volumeStatsHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if m.r.Volume == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(getJSONError("The drivers are not initialized", nil))
+		return
+	}
+
+	stats, err := m.r.Volume.StatsContext(
+		context.Background(), req.FormValue("volumeName"), req.FormValue("volumeID"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(getJSONError("Error getting volume stats", err))
+		log.Printf("Error getting volume stats ERR: %v\n", err)
+		return
+	}
+
+	jsonBuf, jsonBufErr := json.MarshalIndent(stats, "", "  ")
+	if jsonBufErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error servicing request ERR: %v", jsonBufErr)
+		return
+	}
+
+	_, writeErr := w.Write(jsonBuf)
+	if writeErr != nil {
+		log.Printf("Error writing json buffer ERR: %v", writeErr)
+	}
+}
+
+func (m *mod) volumeRefsHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if m.r.Volume == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(getJSONError("The drivers are not initialized", nil))
+		return
+	}
+
+	jsonBuf, jsonBufErr := json.MarshalIndent(m.r.Volume.MountRefs(), "", "  ")
+	if jsonBufErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error servicing request ERR: %v", jsonBufErr)
+		return
+	}
+
+	_, writeErr := w.Write(jsonBuf)
+	if writeErr != nil {
+		log.Printf("Error writing json buffer ERR: %v", writeErr)
+	}
+}
+
+// volumeRefReleaseHandler releases the reference held on a volume by the
+// caller and mountID form values. It is used to release the references of
+// containers that exited without unmounting their volumes.
+func (m *mod) volumeRefReleaseHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if req.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if m.r.Volume == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(getJSONError("The drivers are not initialized", nil))
+		return
+	}
+
+	volumeName := mux.Vars(req)["name"]
+	caller := req.FormValue("caller")
+	mountID := req.FormValue("mountID")
+
+	log.WithFields(log.Fields{
+		"volumeName": volumeName,
+		"caller":     caller,
+		"mountID":    mountID,
+	}).Debug("received volume ref release request")
+
+	err := m.r.Volume.ReleaseMountRefContext(
+		core.WithCaller(context.Background(), fmt.Sprintf("module:%s", m.name)),
+		volumeName, caller, mountID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(getJSONError("Error releasing volume ref", err))
+		log.Printf("Error releasing volume ref ERR: %v\n", err)
+		return
+	}
+
+	jsonBuf, jsonBufErr := json.MarshalIndent(
+		m.r.Volume.MountRefs()[volumeName], "", "  ")
+	if jsonBufErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error servicing request ERR: %v", jsonBufErr)
+		return
+	}
+
+	_, writeErr := w.Write(jsonBuf)
+	if writeErr != nil {
+		log.Printf("Error writing json buffer ERR: %v", writeErr)
+	}
+}
+
 func getJSONError(msg string, err error) []byte {
 	buf, marshalErr := json.MarshalIndent(
 		&jsonError{
@@ -322,7 +452,20 @@ func getJSONError(msg string, err error) []byte {
 	return buf
 }
 
+// Start serves the admin endpoints. The volume statistics are served only if
+// the drivers can be initialized; the other endpoints are served regardless.
 func (m *mod) Start() error {
+	if err := m.r.InitDrivers(); err != nil {
+		log.WithField("error", err).Warn(
+			"error initializing drivers; volume stats are unavailable")
+	} else {
+		err := prometheus.Register(
+			core.NewVolumeStatsCollector(m.r, statsTimeout))
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok && err != nil {
+			return err
+		}
+	}
+
 	stdOut := log.StandardLogger().Writer()
 	stdErr := log.StandardLogger().Writer()
 
@@ -334,6 +477,16 @@ func (m *mod) Start() error {
 		handlers.LoggingHandler(stdOut, http.HandlerFunc(moduleInstStartHandler)))
 	r.Handle("/r/module/types",
 		handlers.LoggingHandler(stdOut, http.HandlerFunc(moduleTypeHandler)))
+	r.Handle("/r/snapshots/schedules",
+		handlers.LoggingHandler(stdOut, http.HandlerFunc(snapshotScheduleHandler)))
+	r.Handle("/r/volumes/stats",
+		handlers.LoggingHandler(stdOut, http.HandlerFunc(m.volumeStatsHandler)))
+	r.Handle("/r/volumes/refs",
+		handlers.LoggingHandler(stdOut, http.HandlerFunc(m.volumeRefsHandler)))
+	r.Handle("/r/volumes/refs/{name}/release",
+		handlers.LoggingHandler(stdOut, http.HandlerFunc(m.volumeRefReleaseHandler)))
+
+	r.Handle("/metrics", prometheus.Handler())
 
 	r.Handle("/images/rexray-banner-logo.svg",
 		handlers.LoggingHandler(stdOut, http.HandlerFunc(imagesHandler)))
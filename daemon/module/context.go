@@ -0,0 +1,51 @@
+package module
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+)
+
+// RequestContext returns a context for handling an HTTP request received by
+// the named module. The context identifies the module and the remote client,
+// if any, as the caller of the operations it is used with. The context is
+// cancelled when the client closes its connection or when the returned
+// cancel function is invoked, whichever happens first. Handlers should invoke
+// RequestContext after the request body is read and must invoke the cancel
+// function once the request is handled.
+func RequestContext(
+	w http.ResponseWriter,
+	r *http.Request,
+	name string) (context.Context, context.CancelFunc) {
+
+	ctx, cancel := context.WithCancel(
+		core.WithCaller(context.Background(), requestCaller(r, name)))
+
+	cn, ok := w.(http.CloseNotifier)
+	if !ok {
+		return ctx, cancel
+	}
+
+	closeCh := cn.CloseNotify()
+	go func() {
+		select {
+		case <-closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// requestCaller returns the audit caller for a request received by the named
+// module. Requests received over a unix socket have no remote address.
+func requestCaller(r *http.Request, name string) string {
+	if r == nil || r.RemoteAddr == "" || r.RemoteAddr == "@" {
+		return fmt.Sprintf("module:%s", name)
+	}
+	return fmt.Sprintf("module:%s@%s", name, r.RemoteAddr)
+}
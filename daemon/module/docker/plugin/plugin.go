@@ -0,0 +1,247 @@
+// Package plugin provides the types and the encoding of the Docker plug-in
+// protocol that is shared by the Docker volume driver modules.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/core/errors"
+)
+
+// ContentType is the media type of the requests and responses of the Docker
+// plug-in protocol.
+const ContentType = "application/vnd.docker.plugins.v1+json"
+
+// VolumeRequest is a request received by a Docker volume plug-in.
+type VolumeRequest struct {
+
+	// The name of the volume.
+	Name string `json:",omitempty"`
+
+	// The options with which the volume is created.
+	Opts core.VolumeOpts `json:",omitempty"`
+
+	// The ID Docker assigns to each mount of a volume. A mount and the
+	// unmount that releases it have the same ID.
+	ID string `json:",omitempty"`
+}
+
+// RemoteVolumeRequest is a request received by a Docker remote volume
+// plug-in.
+type RemoteVolumeRequest struct {
+
+	// The name of the volume.
+	Name string `json:",omitempty"`
+
+	// The options with which the volume is created.
+	Opts core.VolumeOpts `json:",omitempty"`
+
+	// The ID of the instance to which the volume is attached.
+	InstanceID string `json:"Instanceid,omitempty"`
+}
+
+// ActivateResponse is the response to a Plugin.Activate request.
+type ActivateResponse struct {
+
+	// The protocols the plug-in implements, such as VolumeDriver.
+	Implements []string
+}
+
+// Volume is a volume as it is described to Docker.
+type Volume struct {
+
+	// The name of the volume.
+	Name string
+
+	// The path at which the volume is mounted, if it is mounted.
+	Mountpoint string `json:",omitempty"`
+
+	// The details of the volume that are shown by docker volume inspect.
+	Status map[string]interface{} `json:",omitempty"`
+}
+
+// MountResponse is the response to a VolumeDriver.Mount or
+// VolumeDriver.Path request.
+type MountResponse struct {
+
+	// The path at which the volume is mounted.
+	Mountpoint string
+}
+
+// ListResponse is the response to a VolumeDriver.List request.
+type ListResponse struct {
+
+	// The volumes.
+	Volumes []*Volume
+}
+
+// GetResponse is the response to a VolumeDriver.Get request.
+type GetResponse struct {
+
+	// The volume.
+	Volume *Volume
+}
+
+// Capabilities are the capabilities of a volume plug-in.
+type Capabilities struct {
+
+	// The scope of the plug-in's volumes: local or global.
+	Scope string
+}
+
+// CapabilitiesResponse is the response to a VolumeDriver.Capabilities
+// request.
+type CapabilitiesResponse struct {
+
+	// The capabilities of the plug-in.
+	Capabilities Capabilities
+}
+
+// NetworkNameResponse is the response to a RemoteVolumeDriver.NetworkName or
+// RemoteVolumeDriver.Attach request.
+type NetworkNameResponse struct {
+
+	// The identifier of the volume on the network of the instance.
+	Networkname string
+}
+
+// ErrorResponse is the response to a request that failed. Docker shows the
+// error to its user.
+type ErrorResponse struct {
+
+	// The description of the error.
+	Err string
+}
+
+// DecodeRequest decodes the body of the request into v. An empty body is
+// decoded as an empty request. If the body cannot be decoded an error
+// response is written and false is returned.
+func DecodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil || err == io.EOF {
+		return true
+	}
+	log.WithFields(log.Fields{
+		"path":  r.URL.Path,
+		"error": err}).Error("error decoding request")
+	WriteErrorStatus(w, http.StatusBadRequest,
+		goof.WithError("invalid request", err))
+	return false
+}
+
+// WriteResponse writes v as the body of a successful response.
+func WriteResponse(w http.ResponseWriter, v interface{}) {
+	writeJSON(w, http.StatusOK, v)
+}
+
+// WriteError writes the error as the body of a failed response. The status
+// of the response is chosen by ErrorStatus and the error is described by
+// ErrorMessage.
+func WriteError(w http.ResponseWriter, err error) {
+	WriteErrorStatus(w, ErrorStatus(err), err)
+}
+
+// WriteErrorStatus writes the error as the body of a failed response with
+// the provided status, such as http.StatusBadRequest for a request that is
+// missing a parameter.
+func WriteErrorStatus(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &ErrorResponse{Err: ErrorMessage(err)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithField("error", err).Error("error encoding response")
+	}
+}
+
+// ErrorStatus returns the HTTP status of the response to a request that
+// failed with the error. Only a volume that does not exist is reported as
+// not found; the absence of a volume or storage driver is reported as a
+// service that is unavailable.
+func ErrorStatus(err error) int {
+	switch errors.ErrCode(err) {
+	case errors.ErrCodeNoVolumesReturned:
+		return http.StatusNotFound
+	case errors.ErrCodeNoVolumesDetected,
+		errors.ErrCodeNoStorageDetected:
+		return http.StatusServiceUnavailable
+	case errors.ErrCodeMissingVolumeID,
+		errors.ErrCodeMultipleVolumesReturned,
+		errors.ErrCodeInvalidVolumeSize,
+		errors.ErrCodeRunAsyncFromVolume:
+		return http.StatusBadRequest
+	case errors.ErrCodeCircuitOpen:
+		return http.StatusServiceUnavailable
+	case errors.ErrCodeNotImplemented:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorMessage returns a description of the error for Docker's user. The
+// messages of the error and of the errors it wraps are joined, followed by
+// the code of the REX-Ray error, if any, and the simple fields of the errors,
+// for example:
+//
+//	error mounting volume: no volumes detected (code=NoVolumesDetected, volumeName=db01)
+func ErrorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	code := errors.ErrCode(err)
+	var msgs []string
+	fields := map[string]string{}
+
+	for i := 0; err != nil && i < 16; i++ {
+		if msg := err.Error(); len(msgs) == 0 || msgs[len(msgs)-1] != msg {
+			msgs = append(msgs, msg)
+		}
+		f, ok := err.(interface {
+			Fields() map[string]interface{}
+		})
+		if !ok {
+			break
+		}
+		for k, v := range f.Fields() {
+			if _, ok := fields[k]; ok || k == "inner" {
+				continue
+			}
+			switch v.(type) {
+			case string, bool, int, int32, int64, uint, uint32, uint64, float64:
+				fields[k] = fmt.Sprintf("%v", v)
+			}
+		}
+		err, _ = f.Fields()["inner"].(error)
+	}
+
+	var details []string
+	if code != errors.ErrCodeUnknown {
+		details = append(details, fmt.Sprintf("code=%s", code.Name()))
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		details = append(details, fmt.Sprintf("%s=%s", k, fields[k]))
+	}
+
+	msg := strings.Join(msgs, ": ")
+	if len(details) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s (%s)", msg, strings.Join(details, ", "))
+}
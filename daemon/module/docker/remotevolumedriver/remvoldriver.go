@@ -1,8 +1,6 @@
 package remotevolumedriver
 
 import (
-	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -17,6 +15,7 @@ import (
 
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/daemon/module"
+	"github.com/emccode/rexray/daemon/module/docker/plugin"
 )
 
 const (
@@ -80,17 +79,12 @@ func newModule(id int32, cfg *module.Config) (module.Module, error) {
 }
 
 var (
-	errMissingHost      = goof.New("Missing host parameter")
-	errBadHostSpecified = goof.New("Bad host specified, ie. unix:///run/docker/plugins/rexray.sock or tcp://127.0.0.1:8080")
-	errBadProtocol      = goof.New("Bad protocol specified with host, ie. unix:// or tcp://")
+	errMissingHost       = goof.New("Missing host parameter")
+	errBadHostSpecified  = goof.New("Bad host specified, ie. unix:///run/docker/plugins/rexray.sock or tcp://127.0.0.1:8080")
+	errBadProtocol       = goof.New("Bad protocol specified with host, ie. unix:// or tcp://")
+	errMissingInstanceID = goof.New("Missing InstanceID")
 )
 
-type pluginRequest struct {
-	Name       string          `json:"Name,omitempty"`
-	Opts       core.VolumeOpts `json:"Opts,omitempty"`
-	InstanceID string          `json:"Instanceid,omitempty"`
-}
-
 func (m *mod) Start() error {
 
 	proto, addr, parseAddrErr := gotil.ParseAddress(m.Address())
@@ -124,7 +118,7 @@ func (m *mod) Start() error {
 	var specPath string
 	var startFunc func() error
 
-	mux := m.buildMux()
+	mux := module.InstrumentMux(m.name, m.buildMux())
 
 	if proto == "unix" {
 		sockFile := addr
@@ -198,107 +192,113 @@ func (m *mod) buildMux() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/Plugin.Activate", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{"Implements": ["RemoteVolumeDriver"]}`)
+		plugin.WriteResponse(w, &plugin.ActivateResponse{
+			Implements: []string{"RemoteVolumeDriver"},
+		})
 	})
 
 	mux.HandleFunc("/RemoteVolumeDriver.Create", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+		var pr plugin.RemoteVolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
-		err := m.r.Volume.Create(pr.Name, pr.Opts)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		err := m.r.Volume.CreateContext(ctx, pr.Name, pr.Opts)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{}`)
+		plugin.WriteResponse(w, &plugin.ErrorResponse{})
 	})
 
 	mux.HandleFunc("/RemoteVolumeDriver.Remove", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+		var pr plugin.RemoteVolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
-		err := m.r.Volume.Remove(pr.Name)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		err := m.r.Volume.RemoveContext(ctx, pr.Name)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{}`)
+		plugin.WriteResponse(w, &plugin.ErrorResponse{})
 	})
 
 	mux.HandleFunc("/RemoteVolumeDriver.NetworkName", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+		var pr plugin.RemoteVolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
 		if pr.InstanceID == "" {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", goof.New("Missing InstanceID").Error()), 500)
+			plugin.WriteErrorStatus(w, http.StatusBadRequest, errMissingInstanceID)
 			return
 		}
 
-		networkName, err := m.r.Volume.NetworkName(pr.Name, pr.InstanceID)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		networkName, err := m.r.Volume.NetworkNameContext(ctx, pr.Name, pr.InstanceID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, fmt.Sprintf("{\"Networkname\": \"%s\"}", networkName))
+		plugin.WriteResponse(w, &plugin.NetworkNameResponse{Networkname: networkName})
 	})
 
 	mux.HandleFunc("/RemoteVolumeDriver.Attach", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+		var pr plugin.RemoteVolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
 		if pr.InstanceID == "" {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", goof.New("Missing InstanceID").Error()), 500)
+			plugin.WriteErrorStatus(w, http.StatusBadRequest, errMissingInstanceID)
 			return
 		}
 
-		networkName, err := m.r.Volume.Attach(pr.Name, pr.InstanceID, false)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		networkName, err := m.r.Volume.AttachContext(ctx, pr.Name, pr.InstanceID, false)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, fmt.Sprintf("{\"Networkname\": \"%s\"}", networkName))
+		plugin.WriteResponse(w, &plugin.NetworkNameResponse{Networkname: networkName})
 	})
 
 	mux.HandleFunc("/RemoteVolumeDriver.Detach", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+		var pr plugin.RemoteVolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
 		if pr.InstanceID == "" {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", goof.New("Missing InstanceID").Error()), 500)
+			plugin.WriteErrorStatus(w, http.StatusBadRequest, errMissingInstanceID)
 			return
 		}
 
-		err := m.r.Volume.Detach(pr.Name, pr.InstanceID, false)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		err := m.r.Volume.DetachContext(ctx, pr.Name, pr.InstanceID, false)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{}`)
+		plugin.WriteResponse(w, &plugin.ErrorResponse{})
 	})
 
 	return mux
@@ -1,8 +1,6 @@
 package volumedriver
 
 import (
-	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -17,7 +15,9 @@ import (
 	"github.com/akutz/gotil"
 
 	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/core/errors"
 	"github.com/emccode/rexray/daemon/module"
+	"github.com/emccode/rexray/daemon/module/docker/plugin"
 )
 
 const (
@@ -76,9 +76,20 @@ var (
 	errBadProtocol      = goof.New("Bad protocol specified with host, ie. unix:// or tcp://")
 )
 
-type pluginRequest struct {
-	Name string          `json:"Name,omitempty"`
-	Opts core.VolumeOpts `json:"Opts,omitempty"`
+// volumeStatus returns the details of a volume that Docker includes in the
+// output of docker volume inspect.
+func volumeStatus(v *core.Volume) map[string]interface{} {
+	attachments := v.Attachments
+	if attachments == nil {
+		attachments = []*core.VolumeAttachment{}
+	}
+	return map[string]interface{}{
+		"volumeID":         v.VolumeID,
+		"size":             v.Size,
+		"volumeType":       v.VolumeType,
+		"availabilityZone": v.AvailabilityZone,
+		"attachments":      attachments,
+	}
 }
 
 func (m *mod) Start() error {
@@ -114,7 +125,7 @@ func (m *mod) Start() error {
 	var specPath string
 	var startFunc func() error
 
-	mux := m.buildMux()
+	mux := module.InstrumentMux(m.name, m.buildMux())
 
 	if proto == "unix" {
 		sockFile := addr
@@ -188,108 +199,180 @@ func (m *mod) buildMux() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/Plugin.Activate", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{"Implements": ["VolumeDriver"]}`)
+		plugin.WriteResponse(w, &plugin.ActivateResponse{
+			Implements: []string{"VolumeDriver"},
+		})
+	})
+
+	// the volumes are stored in the storage platform rather than on the
+	// local host, so Docker treats them as cluster-wide
+	mux.HandleFunc("/VolumeDriver.Capabilities", func(w http.ResponseWriter, r *http.Request) {
+		plugin.WriteResponse(w, &plugin.CapabilitiesResponse{
+			Capabilities: plugin.Capabilities{Scope: "global"},
+		})
+	})
+
+	mux.HandleFunc("/VolumeDriver.List", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		volumes, err := m.r.Storage.GetVolumeContext(ctx, "", "")
+		if err != nil {
+			plugin.WriteError(w, err)
+			log.WithField("error", err.Error()).Error("/VolumeDriver.List: error listing volumes")
+			return
+		}
+
+		// Docker identifies volumes by name, so volumes without a name are
+		// not listed and only the first volume with a given name is
+		res := &plugin.ListResponse{Volumes: []*plugin.Volume{}}
+		names := map[string]bool{}
+		for _, v := range volumes {
+			if v.Name == "" || names[v.Name] {
+				continue
+			}
+			names[v.Name] = true
+			res.Volumes = append(res.Volumes, &plugin.Volume{Name: v.Name})
+		}
+
+		plugin.WriteResponse(w, res)
+	})
+
+	mux.HandleFunc("/VolumeDriver.Get", func(w http.ResponseWriter, r *http.Request) {
+		var pr plugin.VolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
+			return
+		}
+
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		volumes, err := m.r.Storage.GetVolumeContext(ctx, "", pr.Name)
+		if err == nil && len(volumes) == 0 {
+			err = goof.WithFieldE(
+				"volumeName", pr.Name, "volume not found",
+				errors.ErrNoVolumesReturned)
+		}
+		if err != nil {
+			plugin.WriteError(w, err)
+			log.WithField("error", err.Error()).Error("/VolumeDriver.Get: error getting volume")
+			return
+		}
+
+		// a volume whose path cannot be determined is still described
+		mountPath, err := m.r.Volume.PathContext(ctx, pr.Name, "")
+		if err != nil {
+			log.WithFields(log.Fields{
+				"volumeName": pr.Name,
+				"error":      err.Error()}).Warn("/VolumeDriver.Get: error returning path")
+		}
+
+		plugin.WriteResponse(w, &plugin.GetResponse{
+			Volume: &plugin.Volume{
+				Name:       pr.Name,
+				Mountpoint: mountPath,
+				Status:     volumeStatus(volumes[0]),
+			},
+		})
 	})
 
 	mux.HandleFunc("/VolumeDriver.Create", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
-			log.WithField("error", err).Error("/VolumeDriver.Create: error decoding json")
+		var pr plugin.VolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
-		err := m.r.Volume.Create(pr.Name, pr.Opts)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		err := m.r.Volume.CreateContext(ctx, pr.Name, pr.Opts)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			log.WithField("error", err.Error()).Error("/VolumeDriver.Create: error creating volume")
-			log.Error(err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{}`)
+		plugin.WriteResponse(w, &plugin.ErrorResponse{})
 	})
 
 	mux.HandleFunc("/VolumeDriver.Remove", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
-			log.WithField("error", err).Error("/VolumeDriver.Remove: error decoding json")
+		var pr plugin.VolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
-		err := m.r.Volume.Remove(pr.Name)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		err := m.r.Volume.RemoveContext(ctx, pr.Name)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			log.WithField("error", err.Error()).Error("/VolumeDriver.Remove: error removing volume")
-			log.Error(err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{}`)
+		plugin.WriteResponse(w, &plugin.ErrorResponse{})
 	})
 
 	mux.HandleFunc("/VolumeDriver.Path", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
-			log.WithField("error", err).Error("/VolumeDriver.Path: error decoding json")
+		var pr plugin.VolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
-		mountPath, err := m.r.Volume.Path(pr.Name, "")
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		mountPath, err := m.r.Volume.PathContext(ctx, pr.Name, "")
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			log.WithField("error", err.Error()).Error("/VolumeDriver.Path: error returning path")
-			log.Error(err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, fmt.Sprintf("{\"Mountpoint\": \"%s\"}", mountPath))
+		plugin.WriteResponse(w, &plugin.MountResponse{Mountpoint: mountPath})
 	})
 
 	mux.HandleFunc("/VolumeDriver.Mount", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
-			log.WithField("error", err).Error("/VolumeDriver.Mount: error decoding json")
+		var pr plugin.VolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
-		mountPath, err := m.r.Volume.Mount(pr.Name, "", false, "", false)
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		ctx = core.WithMountRef(ctx, m.name, pr.ID)
+
+		mountPath, err := m.r.Volume.MountContext(ctx, pr.Name, "", false, "", false)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			log.WithField("error", err.Error()).Error("/VolumeDriver.Mount: error mounting volume")
-			log.Error(err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, fmt.Sprintf("{\"Mountpoint\": \"%s\"}", mountPath))
+		plugin.WriteResponse(w, &plugin.MountResponse{Mountpoint: mountPath})
 	})
 
 	mux.HandleFunc("/VolumeDriver.Unmount", func(w http.ResponseWriter, r *http.Request) {
-		var pr pluginRequest
-		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
-			log.WithField("error", err).Error("/VolumeDriver.Unmount: error decoding json")
+		var pr plugin.VolumeRequest
+		if !plugin.DecodeRequest(w, r, &pr) {
 			return
 		}
 
-		err := m.r.Volume.Unmount(pr.Name, "")
+		ctx, cancel := module.RequestContext(w, r, m.name)
+		defer cancel()
+
+		ctx = core.WithMountRef(ctx, m.name, pr.ID)
+
+		err := m.r.Volume.UnmountContext(ctx, pr.Name, "")
 		if err != nil {
-			http.Error(w, fmt.Sprintf("{\"Error\":\"%s\"}", err.Error()), 500)
+			plugin.WriteError(w, err)
 			log.WithField("error", err.Error()).Error("/VolumeDriver.Unmount: error unmounting volume")
-			log.Error(err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "appplication/vnd.docker.plugins.v1+json")
-		fmt.Fprintln(w, `{}`)
+		plugin.WriteResponse(w, &plugin.ErrorResponse{})
 	})
 
 	return mux
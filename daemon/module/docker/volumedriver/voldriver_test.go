@@ -0,0 +1,153 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/akutz/gofig"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/daemon/module/docker/plugin"
+	"github.com/emccode/rexray/drivers/mock"
+	"github.com/emccode/rexray/util"
+)
+
+func TestMain(m *testing.M) {
+	mock.RegisterMockDrivers()
+
+	d, err := ioutil.TempDir("", "rexray-test")
+	if err != nil {
+		panic(err)
+	}
+	util.Prefix(d)
+
+	code := m.Run()
+	os.RemoveAll(d)
+	os.Exit(code)
+}
+
+func newTestMod(t *testing.T, config map[string]interface{}) *mod {
+	c := gofig.New()
+	c.Set("rexray.osDrivers", []string{mock.MockOSDriverName})
+	c.Set("rexray.volumeDrivers", []string{mock.MockVolDriverName})
+	c.Set("rexray.storageDrivers", []string{mock.MockStorDriverName})
+	for k, v := range config {
+		c.Set(k, v)
+	}
+
+	m := &mod{name: modName, r: core.New(c)}
+	if err := m.r.InitDrivers(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func post(
+	t *testing.T, m *mod, path, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(
+		"POST", "http://test"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	m.buildMux().ServeHTTP(w, r)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestList(t *testing.T) {
+	m := newTestMod(t, map[string]interface{}{
+		"mockProvider.volumeNames": []string{"db", "", "db"},
+	})
+
+	w := post(t, m, "/VolumeDriver.List", "{}")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code=%d", w.Code)
+	}
+	var res plugin.ListResponse
+	decode(t, w, &res)
+
+	// the unnamed volume is skipped and the second db volume is not listed
+	var names []string
+	for _, v := range res.Volumes {
+		names = append(names, v.Name)
+	}
+	if strings.Join(names, ",") != "test,db" {
+		t.Fatalf("names=%v", names)
+	}
+}
+
+func TestGet(t *testing.T) {
+	m := newTestMod(t, nil)
+
+	w := post(t, m, "/VolumeDriver.Get", `{"Name":"test"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("code=%d", w.Code)
+	}
+	var res plugin.GetResponse
+	decode(t, w, &res)
+	if res.Volume == nil || res.Volume.Name != "test" ||
+		res.Volume.Status["volumeID"] != "test" {
+		t.Fatalf("volume=%+v", res.Volume)
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	m := newTestMod(t, nil)
+
+	w := post(t, m, "/VolumeDriver.Get", `{"Name":"missing"}`)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("code=%d", w.Code)
+	}
+	var res plugin.ErrorResponse
+	decode(t, w, &res)
+	if !strings.Contains(res.Err, "volume not found") ||
+		!strings.Contains(res.Err, "volumeName=missing") {
+		t.Fatalf("err=%s", res.Err)
+	}
+}
+
+func TestGetPathFailed(t *testing.T) {
+	m := newTestMod(t, map[string]interface{}{
+		"mockProvider.fail": []string{"Path"},
+	})
+
+	// a volume whose path cannot be determined is still described
+	w := post(t, m, "/VolumeDriver.Get", `{"Name":"test"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("code=%d", w.Code)
+	}
+	var res plugin.GetResponse
+	decode(t, w, &res)
+	if res.Volume == nil || res.Volume.Name != "test" ||
+		res.Volume.Mountpoint != "" {
+		t.Fatalf("volume=%+v", res.Volume)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	m := newTestMod(t, nil)
+
+	w := post(t, m, "/VolumeDriver.Capabilities", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code=%d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != plugin.ContentType {
+		t.Fatalf("contentType=%s", ct)
+	}
+	var res plugin.CapabilitiesResponse
+	decode(t, w, &res)
+	if res.Capabilities.Scope != "global" {
+		t.Fatalf("scope=%s", res.Capabilities.Scope)
+	}
+}
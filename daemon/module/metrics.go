@@ -0,0 +1,103 @@
+package module
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "rexray",
+			Subsystem: "module",
+			Name:      "request_duration_seconds",
+			Help:      "The duration of the requests served by modules.",
+			Buckets: []float64{
+				.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{"module", "endpoint"})
+
+	requests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "rexray",
+			Subsystem: "module",
+			Name:      "requests_total",
+			Help:      "The number of requests served by modules.",
+		},
+		[]string{"module", "endpoint", "code"})
+
+	requestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "rexray",
+			Subsystem: "module",
+			Name:      "requests_in_flight",
+			Help:      "The number of requests being served by modules.",
+		},
+		[]string{"module"})
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration, requests, requestsInFlight)
+}
+
+// InstrumentMux returns a handler that serves requests with the provided mux
+// and records the number, duration and status codes of the requests for each
+// of the mux's endpoints.
+func InstrumentMux(moduleName string, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight := requestsInFlight.WithLabelValues(moduleName)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		// the registered pattern rather than the request's path is recorded
+		// so that unknown paths do not create new series
+		endpoint := "other"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			endpoint = pattern
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+		start := time.Now()
+		mux.ServeHTTP(sw, r)
+
+		requestDuration.WithLabelValues(moduleName, endpoint).Observe(
+			time.Since(start).Seconds())
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		requests.WithLabelValues(
+			moduleName, endpoint, strconv.Itoa(sw.status)).Inc()
+	})
+}
+
+// statusWriter records the status code written to a response. It implements
+// http.CloseNotifier so that RequestContext can still observe the client
+// closing its connection.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
@@ -0,0 +1,104 @@
+package snapshot
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/gofig"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/daemon/module"
+)
+
+const (
+	modName        = "SnapshotScheduleModule"
+	modDescription = "The REX-Ray snapshot schedule module"
+)
+
+type mod struct {
+	id        int32
+	r         *core.RexRay
+	scheduler *core.SnapshotScheduler
+	name      string
+	addr      string
+	desc      string
+}
+
+func init() {
+	mc := &module.Config{
+		Config: gofig.New(),
+	}
+	module.RegisterModule(modName, true, newMod, []*module.Config{mc})
+}
+
+func newMod(id int32, cfg *module.Config) (module.Module, error) {
+	return &mod{
+		id:   id,
+		r:    core.New(cfg.Config),
+		name: modName,
+		desc: modDescription,
+		addr: cfg.Address,
+	}, nil
+}
+
+func (m *mod) ID() int32 {
+	return m.id
+}
+
+// Start runs the snapshot schedules named by
+// rexray.storage.snapshots.schedules. The module does nothing if there are
+// no schedules.
+func (m *mod) Start() error {
+	scheduler, err := core.NewSnapshotScheduler(m.r)
+	if err != nil {
+		return err
+	}
+	if len(scheduler.Schedules()) == 0 {
+		log.Debug("no snapshot schedules configured")
+		return nil
+	}
+
+	if err := m.r.InitDrivers(); err != nil {
+		return goof.WithFieldsE(goof.Fields{
+			"m":   m,
+			"m.r": m.r,
+		}, "error initializing drivers", err)
+	}
+
+	for _, s := range scheduler.Schedules() {
+		log.WithFields(log.Fields{
+			"schedule":    s.Name,
+			"cron":        s.Cron,
+			"volumeName":  s.VolumeName,
+			"retainCount": s.RetainCount,
+			"retainAge":   s.RetainAge,
+			"copyRegion":  s.CopyRegion,
+		}).Info("starting snapshot schedule")
+	}
+
+	m.scheduler = scheduler
+	m.scheduler.Start(core.WithCaller(
+		context.Background(), fmt.Sprintf("module:%s", m.name)))
+	return nil
+}
+
+func (m *mod) Stop() error {
+	if m.scheduler != nil {
+		m.scheduler.Stop()
+	}
+	return nil
+}
+
+func (m *mod) Name() string {
+	return m.name
+}
+
+func (m *mod) Description() string {
+	return m.desc
+}
+
+func (m *mod) Address() string {
+	return m.addr
+}
@@ -23,6 +23,11 @@ const (
 	// for testing.
 	MockStorDriverName = "mockStorageDriver"
 
+	// MockStorDriver2Name is the name of a second mock storage driver that
+	// owns a different volume than the first, used for testing the routing
+	// of calls among storage drivers.
+	MockStorDriver2Name = "mockStorageDriver2"
+
 	// BadMockOSDriverName is the name of a mock OS driver used primarily for
 	// testing.
 	BadMockOSDriverName = "badMockOSDriver"
@@ -54,6 +59,7 @@ func RegisterMockDrivers() {
 	core.RegisterDriver(MockOSDriverName, newOSDriver)
 	core.RegisterDriver(MockVolDriverName, newVolDriver)
 	core.RegisterDriver(MockStorDriverName, newStorDriver)
+	core.RegisterDriver(MockStorDriver2Name, newStorDriver2)
 	gofig.Register(mockRegistration())
 }
 
@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/akutz/goof"
+
+	"github.com/emccode/rexray/core"
+)
+
+// Call is an operation performed by one of the mock drivers.
+type Call struct {
+
+	// The name of the driver that performed the operation.
+	Driver string
+
+	// The name of the operation, such as AttachVolume.
+	Operation string
+
+	// The arguments of the operation, such as the volume ID.
+	Args []string
+}
+
+var (
+	callsLock sync.Mutex
+	calls     = map[*core.RexRay][]*Call{}
+)
+
+// Calls returns the operations performed by the mock drivers of the REX-Ray
+// instance in the order in which they completed.
+func Calls(r *core.RexRay) []*Call {
+	callsLock.Lock()
+	defer callsLock.Unlock()
+	return append([]*Call{}, calls[r]...)
+}
+
+// record records an operation performed by a mock driver of the REX-Ray
+// instance. An operation is delayed by mockProvider.delay.<operation>, and
+// it fails if it is named in mockProvider.fail. The failure is returned
+// after the operation is recorded.
+func record(r *core.RexRay, driver, op string, args ...string) error {
+	if r == nil {
+		return nil
+	}
+
+	if d := r.Config.GetString("mockProvider.delay." + op); d != "" {
+		if dur, err := time.ParseDuration(d); err == nil {
+			time.Sleep(dur)
+		}
+	}
+
+	callsLock.Lock()
+	calls[r] = append(calls[r], &Call{
+		Driver:    driver,
+		Operation: op,
+		Args:      args,
+	})
+	callsLock.Unlock()
+
+	for _, f := range r.Config.GetStringSlice("mockProvider.fail") {
+		if f == op {
+			return goof.WithFields(goof.Fields{
+				"driverName": driver,
+				"operation":  op,
+			}, "mock failure")
+		}
+	}
+	return nil
+}
@@ -2,12 +2,14 @@ package mock
 
 import (
 	"github.com/akutz/goof"
+	"github.com/docker/docker/pkg/mount"
 
 	"github.com/emccode/rexray/core"
 )
 
 type mockOSDriver struct {
 	name string
+	r    *core.RexRay
 }
 
 type badMockOSDriver struct {
@@ -15,16 +17,18 @@ type badMockOSDriver struct {
 }
 
 func newOSDriver() core.Driver {
-	var d core.OSDriver = &mockOSDriver{MockOSDriverName}
+	var d core.OSDriver = &mockOSDriver{name: MockOSDriverName}
 	return d
 }
 
 func newBadOSDriver() core.Driver {
-	var d core.OSDriver = &badMockOSDriver{mockOSDriver{BadMockOSDriverName}}
+	var d core.OSDriver = &badMockOSDriver{
+		mockOSDriver{name: BadMockOSDriverName}}
 	return d
 }
 
 func (m *mockOSDriver) Init(r *core.RexRay) error {
+	m.r = r
 	return nil
 }
 
@@ -36,8 +40,21 @@ func (m *mockOSDriver) Name() string {
 	return m.name
 }
 
-func (m *mockOSDriver) GetMounts(string, string) (core.MountInfoArray, error) {
-	return nil, nil
+// GetMounts returns a mount of the device at mockProvider.mountPoint, if it
+// is set, so that every device appears to be mounted.
+func (m *mockOSDriver) GetMounts(
+	deviceName, mountPoint string) (core.MountInfoArray, error) {
+	if m.r == nil {
+		return nil, nil
+	}
+	mp := m.r.Config.GetString("mockProvider.mountPoint")
+	if mp == "" {
+		return nil, nil
+	}
+	return core.MountInfoArray{&mount.Info{
+		Source:     deviceName,
+		Mountpoint: mp,
+	}}, nil
 }
 
 func (m *mockOSDriver) Mounted(string) (bool, error) {
@@ -55,3 +72,26 @@ func (m *mockOSDriver) Mount(string, string, string, string) error {
 func (m *mockOSDriver) Format(string, string, bool) error {
 	return nil
 }
+
+func (m *mockOSDriver) ResizeFilesystem(string, string) error {
+	return nil
+}
+
+func (m *mockOSDriver) Freeze(mountPoint string) error {
+	return record(m.r, m.name, "Freeze", mountPoint)
+}
+
+func (m *mockOSDriver) Thaw(mountPoint string) error {
+	return record(m.r, m.name, "Thaw", mountPoint)
+}
+
+func (m *mockOSDriver) Stats(string, string) (*core.MountStats, error) {
+	return &core.MountStats{
+		CapacityBytes:  1024,
+		UsedBytes:      256,
+		AvailableBytes: 768,
+		Inodes:         64,
+		InodesUsed:     16,
+		InodesFree:     48,
+	}, nil
+}
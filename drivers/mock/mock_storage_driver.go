@@ -1,13 +1,24 @@
 package mock
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/akutz/goof"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core"
 )
 
 type mockStorDriver struct {
-	name string
+	name     string
+	volumeID string
+	r        *core.RexRay
+
+	// the snapshots created with the driver, by their IDs
+	snapshots     map[string]*core.Snapshot
+	snapshotCount int
+	snapshotsLock sync.Mutex
 }
 
 type badMockStorDriver struct {
@@ -15,17 +26,25 @@ type badMockStorDriver struct {
 }
 
 func newStorDriver() core.Driver {
-	var d core.StorageDriver = &mockStorDriver{MockStorDriverName}
+	var d core.StorageDriver = &mockStorDriver{
+		name: MockStorDriverName, volumeID: "test"}
+	return d
+}
+
+func newStorDriver2() core.Driver {
+	var d core.StorageDriver = &mockStorDriver{
+		name: MockStorDriver2Name, volumeID: "test2"}
 	return d
 }
 
 func newBadStorDriver() core.Driver {
 	var d core.StorageDriver = &badMockStorDriver{
-		mockStorDriver{BadMockStorDriverName}}
+		mockStorDriver{name: BadMockStorDriverName, volumeID: "test"}}
 	return d
 }
 
 func (m *mockStorDriver) Init(r *core.RexRay) error {
+	m.r = r
 	return nil
 }
 
@@ -54,32 +73,110 @@ func (m *mockStorDriver) GetInstance() (*core.Instance, error) {
 		Region:       "test"}, nil
 }
 
+// GetVolume returns the driver's volume and a volume for each of the names
+// in mockProvider.volumeNames, which may be empty or repeated. Only the
+// volumes with the name are returned if one is provided.
 func (m *mockStorDriver) GetVolume(
 	volumeID, volumeName string) ([]*core.Volume, error) {
-	return []*core.Volume{&core.Volume{
-		Name:             "test",
-		VolumeID:         "test",
+	volumes := []*core.Volume{m.volume()}
+	if m.r != nil {
+		for i, n := range m.r.Config.GetStringSlice(
+			"mockProvider.volumeNames") {
+			volumes = append(volumes, &core.Volume{
+				Name:     n,
+				VolumeID: fmt.Sprintf("%s-%d", m.volumeID, i+1),
+				Size:     "10",
+			})
+		}
+	}
+	if volumeName == "" {
+		return volumes, nil
+	}
+	var named []*core.Volume
+	for _, v := range volumes {
+		if v.Name == volumeName {
+			named = append(named, v)
+		}
+	}
+	return named, nil
+}
+
+// volume returns the driver's volume, which is attached to the instance.
+func (m *mockStorDriver) volume() *core.Volume {
+	return &core.Volume{
+		Name:             m.volumeID,
+		VolumeID:         m.volumeID,
+		Size:             "10",
 		AvailabilityZone: "test",
-	}}, nil
+		Attachments: []*core.VolumeAttachment{&core.VolumeAttachment{
+			VolumeID:   m.volumeID,
+			InstanceID: "test",
+			DeviceName: m.volumeID,
+		}},
+	}
 }
 
 func (m *mockStorDriver) GetVolumeAttach(
 	volumeID, instanceID string) ([]*core.VolumeAttachment, error) {
-	return nil, nil
+	var atts []*core.VolumeAttachment
+	for _, a := range m.volume().Attachments {
+		if (volumeID == "" || volumeID == a.VolumeID) &&
+			(instanceID == "" || instanceID == a.InstanceID) {
+			atts = append(atts, a)
+		}
+	}
+	return atts, nil
 }
 
 func (m *mockStorDriver) CreateSnapshot(
 	runAsync bool,
 	snapshotName, volumeID, description string) ([]*core.Snapshot, error) {
-	return nil, nil
+	if err := record(
+		m.r, m.name, "CreateSnapshot", snapshotName, volumeID); err != nil {
+		return nil, err
+	}
+
+	m.snapshotsLock.Lock()
+	defer m.snapshotsLock.Unlock()
+	if m.snapshots == nil {
+		m.snapshots = map[string]*core.Snapshot{}
+	}
+	m.snapshotCount++
+	s := &core.Snapshot{
+		Name:        snapshotName,
+		VolumeID:    volumeID,
+		SnapshotID:  fmt.Sprintf("%s-snap-%d", m.volumeID, m.snapshotCount),
+		Description: description,
+	}
+	m.snapshots[s.SnapshotID] = s
+	c := *s
+	return []*core.Snapshot{&c}, nil
 }
 
 func (m *mockStorDriver) GetSnapshot(
 	volumeID, snapshotID, snapshotName string) ([]*core.Snapshot, error) {
-	return nil, nil
+	m.snapshotsLock.Lock()
+	defer m.snapshotsLock.Unlock()
+	var snapshots []*core.Snapshot
+	for _, s := range m.snapshots {
+		if (volumeID != "" && s.VolumeID != volumeID) ||
+			(snapshotID != "" && s.SnapshotID != snapshotID) ||
+			(snapshotName != "" && s.Name != snapshotName) {
+			continue
+		}
+		c := *s
+		snapshots = append(snapshots, &c)
+	}
+	return snapshots, nil
 }
 
 func (m *mockStorDriver) RemoveSnapshot(snapshotID string) error {
+	if err := record(m.r, m.name, "RemoveSnapshot", snapshotID); err != nil {
+		return err
+	}
+	m.snapshotsLock.Lock()
+	defer m.snapshotsLock.Unlock()
+	delete(m.snapshots, snapshotID)
 	return nil
 }
 
@@ -88,11 +185,18 @@ func (m *mockStorDriver) CreateVolume(
 	volumeName, volumeID, snapshotID, volumeType string,
 	IOPS, size int64,
 	availabilityZone string) (*core.Volume, error) {
-	return nil, nil
+	if err := record(m.r, m.name, "CreateVolume", volumeName); err != nil {
+		return nil, err
+	}
+	return &core.Volume{
+		Name:             volumeName,
+		VolumeID:         "test",
+		AvailabilityZone: availabilityZone,
+	}, nil
 }
 
 func (m *mockStorDriver) RemoveVolume(volumeID string) error {
-	return nil
+	return record(m.r, m.name, "RemoveVolume", volumeID)
 }
 
 func (m *mockStorDriver) GetDeviceNextAvailable() (string, error) {
@@ -101,12 +205,27 @@ func (m *mockStorDriver) GetDeviceNextAvailable() (string, error) {
 
 func (m *mockStorDriver) AttachVolume(
 	runAsync bool, volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
-	return nil, nil
+	return m.AttachVolumeMode(context.Background(), runAsync,
+		volumeID, instanceID, force, core.AccessModeReadWriteSingle)
+}
+
+// AttachVolumeMode records the access mode as the third argument of the
+// AttachVolume operation. Nothing is recorded if the context is done.
+func (m *mockStorDriver) AttachVolumeMode(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string,
+	force bool, mode core.AccessMode) ([]*core.VolumeAttachment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, record(m.r, m.name, "AttachVolume",
+		volumeID, instanceID, string(mode))
 }
 
 func (m *mockStorDriver) DetachVolume(
 	runAsync bool, volumeID string, instanceID string, force bool) error {
-	return nil
+	return record(m.r, m.name, "DetachVolume", volumeID, instanceID)
 }
 
 func (m *mockStorDriver) CopySnapshot(
@@ -114,3 +233,23 @@ func (m *mockStorDriver) CopySnapshot(
 	destinationSnapshotName, destinationRegion string) (*core.Snapshot, error) {
 	return nil, nil
 }
+
+func (m *mockStorDriver) ExpandVolume(volumeID string, newSize int64) error {
+	return record(m.r, m.name, "ExpandVolume", volumeID)
+}
+
+func (m *mockStorDriver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+	var snapshots []*core.Snapshot
+	for volumeID, snapshotName := range snapshotNames {
+		snapshots = append(snapshots, &core.Snapshot{
+			Name:        snapshotName,
+			VolumeID:    volumeID,
+			SnapshotID:  volumeID + "-snap",
+			Description: description,
+		})
+	}
+	return snapshots, nil
+}
@@ -10,6 +10,7 @@ const mockVolDriverName = "mockVolumeDriver"
 
 type mockVolDriver struct {
 	name string
+	r    *core.RexRay
 }
 
 type badMockVolDriver struct {
@@ -17,17 +18,18 @@ type badMockVolDriver struct {
 }
 
 func newVolDriver() core.Driver {
-	var d core.VolumeDriver = &mockVolDriver{mockVolDriverName}
+	var d core.VolumeDriver = &mockVolDriver{name: mockVolDriverName}
 	return d
 }
 
 func newBadVolDriver() core.Driver {
 	var d core.VolumeDriver = &badMockVolDriver{
-		mockVolDriver{BadMockVolDriverName}}
+		mockVolDriver{name: BadMockVolDriverName}}
 	return d
 }
 
 func (m *mockVolDriver) Init(r *core.RexRay) error {
+	m.r = r
 	return nil
 }
 
@@ -42,31 +44,31 @@ func (m *mockVolDriver) Name() string {
 func (m *mockVolDriver) Mount(
 	volumeName, volumeID string,
 	overwriteFs bool, newFsType string, preempt bool) (string, error) {
-	return "", nil
+	return "", record(m.r, m.name, "Mount", volumeName)
 }
 
 func (m *mockVolDriver) Unmount(volumeName, volumeID string) error {
-	return nil
+	return record(m.r, m.name, "Unmount", volumeName)
 }
 
 func (m *mockVolDriver) Path(volumeName, volumeID string) (string, error) {
-	return "", nil
+	return "", record(m.r, m.name, "Path", volumeName)
 }
 
 func (m *mockVolDriver) Create(volumeName string, opts core.VolumeOpts) error {
-	return nil
+	return record(m.r, m.name, "Create", volumeName)
 }
 
 func (m *mockVolDriver) Remove(volumeName string) error {
-	return nil
+	return record(m.r, m.name, "Remove", volumeName)
 }
 
 func (m *mockVolDriver) Attach(volumeName, instanceID string, force bool) (string, error) {
-	return "", nil
+	return "", record(m.r, m.name, "Attach", volumeName)
 }
 
 func (m *mockVolDriver) Detach(volumeName, instanceID string, force bool) error {
-	return nil
+	return record(m.r, m.name, "Detach", volumeName)
 }
 
 func (m *mockVolDriver) NetworkName(
@@ -0,0 +1,147 @@
+package linux
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Filesystem describes how the driver detects, creates, mounts, and grows a
+// type of filesystem.
+type Filesystem struct {
+
+	// The filesystem's type, as passed to mount.
+	Name string
+
+	// The magic bytes that identify the filesystem on a device and their
+	// offset. A filesystem without magic bytes is never detected; a device
+	// with it is detected as another filesystem with the same magic.
+	Magic       string
+	MagicOffset uint64
+
+	// The command and arguments that create the filesystem. The arguments of
+	// the request, the label argument, and the device are appended.
+	Mkfs []string
+
+	// The argument of the Mkfs command that sets the filesystem's label and
+	// the longest label the filesystem supports. A filesystem without a label
+	// argument is not labeled.
+	LabelArg    string
+	LabelMaxLen int
+
+	// The options with which the filesystem is always mounted. The options of
+	// the request are appended.
+	MountOptions []string
+
+	// Resize returns the command that grows the filesystem on the device,
+	// mounted at the mount point, to fill the device. A filesystem without
+	// Resize cannot be grown.
+	Resize func(deviceName, mountPoint string) *exec.Cmd
+}
+
+var (
+	filesystemsLock sync.RWMutex
+	filesystems     []*Filesystem
+)
+
+func init() {
+	resize2fs := func(deviceName, mountPoint string) *exec.Cmd {
+		return exec.Command("resize2fs", deviceName)
+	}
+
+	RegisterFilesystem(&Filesystem{
+		Name:        "ext4",
+		Magic:       "\123\357",
+		MagicOffset: 0x438,
+		Mkfs:        []string{"mkfs.ext4", "-F"},
+		LabelArg:    "-L",
+		LabelMaxLen: 16,
+		Resize:      resize2fs,
+	})
+	RegisterFilesystem(&Filesystem{
+		Name:        "ext3",
+		Mkfs:        []string{"mkfs.ext3", "-F"},
+		LabelArg:    "-L",
+		LabelMaxLen: 16,
+		Resize:      resize2fs,
+	})
+	RegisterFilesystem(&Filesystem{
+		Name:         "xfs",
+		Magic:        "XFSB",
+		MagicOffset:  0,
+		Mkfs:         []string{"mkfs.xfs", "-f"},
+		LabelArg:     "-L",
+		LabelMaxLen:  12,
+		MountOptions: []string{"nouuid"},
+		Resize: func(deviceName, mountPoint string) *exec.Cmd {
+			return exec.Command("xfs_growfs", mountPoint)
+		},
+	})
+	RegisterFilesystem(&Filesystem{
+		Name:        "btrfs",
+		Magic:       "_BHRfS_M",
+		MagicOffset: 0x10040,
+		Mkfs:        []string{"mkfs.btrfs", "-f"},
+		LabelArg:    "-L",
+		LabelMaxLen: 255,
+		Resize: func(deviceName, mountPoint string) *exec.Cmd {
+			return exec.Command(
+				"btrfs", "filesystem", "resize", "max", mountPoint)
+		},
+	})
+}
+
+// RegisterFilesystem adds a filesystem to those the driver supports. A
+// filesystem registered with the name of another replaces it.
+func RegisterFilesystem(fs *Filesystem) {
+	filesystemsLock.Lock()
+	defer filesystemsLock.Unlock()
+	for i, f := range filesystems {
+		if f.Name == fs.Name {
+			filesystems[i] = fs
+			return
+		}
+	}
+	filesystems = append(filesystems, fs)
+}
+
+// getFilesystem returns the registered filesystem with the provided type.
+func getFilesystem(fsType string) (*Filesystem, bool) {
+	filesystemsLock.RLock()
+	defer filesystemsLock.RUnlock()
+	for _, fs := range filesystems {
+		if strings.EqualFold(fs.Name, fsType) {
+			return fs, true
+		}
+	}
+	return nil, false
+}
+
+// mkfsCommand returns the command that creates the filesystem on the device
+// with the additional arguments and label.
+func (fs *Filesystem) mkfsCommand(
+	deviceName string, opts []string, fsLabel string) *exec.Cmd {
+
+	args := append([]string{}, fs.Mkfs[1:]...)
+	args = append(args, opts...)
+	if fs.LabelArg != "" && fsLabel != "" {
+		if fs.LabelMaxLen > 0 && len(fsLabel) > fs.LabelMaxLen {
+			fsLabel = fsLabel[:fs.LabelMaxLen]
+		}
+		args = append(args, fs.LabelArg, fsLabel)
+	}
+	args = append(args, deviceName)
+	return exec.Command(fs.Mkfs[0], args...)
+}
+
+// mountOptions returns the filesystem's mount options followed by the
+// comma-separated options of the request.
+func (fs *Filesystem) mountOptions(mountOptions string) string {
+	opts := append([]string{}, fs.MountOptions...)
+	for _, o := range strings.Split(mountOptions, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			opts = append(opts, o)
+		}
+	}
+	return strings.Join(opts, ",")
+}
@@ -0,0 +1,11 @@
+// +build !linux
+
+package linux
+
+import (
+	"github.com/emccode/rexray/core/errors"
+)
+
+func fsFreeze(mountPoint string, freeze bool) error {
+	return errors.ErrNotImplemented
+}
@@ -3,9 +3,12 @@ package linux
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -13,6 +16,7 @@ import (
 	"github.com/akutz/goof"
 	"github.com/docker/docker/pkg/mount"
 	"github.com/opencontainers/runc/libcontainer/label"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/core/errors"
@@ -61,9 +65,16 @@ func (d *driver) GetMounts(
 		return nil, goof.New("Cannot specify mountPoint and deviceName")
 	}
 
+	// the filesystem on an encrypted device is mounted from its mapping
+	var mappedPath string
+	if deviceName != "" {
+		mappedPath = luksMappedPath(deviceName)
+	}
+
 	var matchedMounts []*mount.Info
 	for _, mount := range mounts {
-		if mount.Mountpoint == mountPoint || mount.Source == deviceName {
+		if mount.Mountpoint == mountPoint || mount.Source == deviceName ||
+			(mappedPath != "" && mount.Source == mappedPath) {
 			matchedMounts = append(matchedMounts, mount)
 		}
 	}
@@ -75,16 +86,52 @@ func (d *driver) Mounted(mountPoint string) (bool, error) {
 }
 
 func (d *driver) Unmount(mountPoint string) error {
-	return mount.Unmount(mountPoint)
+	return d.UnmountContext(context.Background(), mountPoint)
+}
+
+// UnmountContext is Unmount with a context. If the filesystem was mounted
+// from the mapping of an encrypted device, and the mapping is not mounted
+// elsewhere, then the mapping is closed.
+func (d *driver) UnmountContext(ctx context.Context, mountPoint string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mounts, err := d.GetMounts("", mountPoint)
+	if err != nil {
+		return err
+	}
+
+	if err := mount.Unmount(mountPoint); err != nil {
+		return err
+	}
+
+	for _, m := range mounts {
+		if !isLuksMappedPath(m.Source) {
+			continue
+		}
+		inUse, err := d.GetMounts(m.Source, "")
+		if err != nil {
+			return err
+		}
+		if len(inUse) == 0 {
+			return luksClose(ctx, m.Source)
+		}
+	}
+	return nil
 }
 
 func (d *driver) isNfsDevice(device string) bool {
 	return strings.Contains(device, ":")
 }
 
-func (d *driver) nfsMount(device, target string) error {
+func (d *driver) nfsMount(
+	ctx context.Context, device, target, mountOptions string) error {
 	command := exec.Command("mount", device, target)
-	output, err := command.CombinedOutput()
+	if mountOptions != "" {
+		command = exec.Command("mount", "-o", mountOptions, device, target)
+	}
+	output, err := runCommand(ctx, command)
 	if err != nil {
 		return goof.WithError(fmt.Sprintf("failed mounting: %s", output), err)
 	}
@@ -98,10 +145,25 @@ func (d *driver) fileModeMountPath() (fileMode os.FileMode) {
 
 func (d *driver) Mount(
 	device, target, mountOptions, mountLabel string) error {
+	return d.MountContext(
+		context.Background(), device, target, mountOptions, mountLabel)
+}
+
+func (d *driver) MountContext(
+	ctx context.Context,
+	device, target, mountOptions, mountLabel string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if mode, _ := core.AccessModeFromContext(ctx); mode.ReadOnly() {
+		mountOptions = readOnlyMountOptions(mountOptions)
+	}
 
 	if d.isNfsDevice(device) {
 
-		if err := d.nfsMount(device, target); err != nil {
+		if err := d.nfsMount(ctx, device, target, mountOptions); err != nil {
 			return err
 		}
 
@@ -116,11 +178,25 @@ func (d *driver) Mount(
 		return err
 	}
 
-	options := label.FormatMountLabel("", mountLabel)
-	options = fmt.Sprintf("%s,%s", mountOptions, mountLabel)
-	if fsType == "xfs" {
-		options = fmt.Sprintf("%s,nouuid", mountOptions)
+	if fsType == luksFsType {
+		key := core.EncryptionKeyFromContext(ctx)
+		if key == nil {
+			return goof.WithFieldE(
+				"device", device, "error mounting device", errLuksKeyRequired)
+		}
+		if device, err = luksOpen(ctx, device, key); err != nil {
+			return err
+		}
+		if fsType, err = probeFsType(device); err != nil {
+			return err
+		}
+	}
+
+	options := mountOptions
+	if fs, ok := getFilesystem(fsType); ok {
+		options = fs.mountOptions(mountOptions)
 	}
+	options = label.FormatMountLabel(options, mountLabel)
 
 	if err := mount.Mount(device, target, fsType, options); err != nil {
 		return fmt.Errorf("Couldn't mount directory %s at %s: %s", device, target, err)
@@ -132,16 +208,53 @@ func (d *driver) Mount(
 	return nil
 }
 
-// Format will look for ext4/xfs and overwrite it is it doesn't exist
+// readOnlyMountOptions returns the comma-separated mount options with the
+// read-write option replaced by the read-only option.
+func readOnlyMountOptions(mountOptions string) string {
+	opts := []string{"ro"}
+	for _, o := range strings.Split(mountOptions, ",") {
+		if o = strings.TrimSpace(o); o != "" && o != "rw" && o != "ro" {
+			opts = append(opts, o)
+		}
+	}
+	return strings.Join(opts, ",")
+}
+
+// Format creates a filesystem of one of the registered types on the device if
+// the device has no filesystem or overwriteFs is specified.
 func (d *driver) Format(
 	deviceName, newFsType string, overwriteFs bool) error {
+	return d.FormatContext(
+		context.Background(), deviceName, newFsType, overwriteFs)
+}
+
+// FormatContext is Format with a context. The mkfs process is killed if the
+// context is done before the filesystem is created. The arguments and label
+// of the context's format options are passed to mkfs. If the context carries
+// an encryption key then the device is encrypted with LUKS, unless it
+// already is, and the filesystem is created on the device's mapping.
+func (d *driver) FormatContext(
+	ctx context.Context,
+	deviceName, newFsType string, overwriteFs bool) error {
 
 	var fsDetected bool
 
+	if key := core.EncryptionKeyFromContext(ctx); key != nil {
+		var err error
+		if deviceName, err = luksPrepare(
+			ctx, deviceName, key, overwriteFs); err != nil {
+			return err
+		}
+	}
+
 	fsType, err := probeFsType(deviceName)
 	if err != nil && err != errors.ErrUnknownFileSystem {
 		return err
 	}
+	if fsType == luksFsType && !overwriteFs {
+		return goof.WithFieldE("deviceName", deviceName,
+			"error formatting device", errLuksKeyRequired)
+	}
 	if fsType != "" {
 		fsDetected = true
 	}
@@ -154,27 +267,213 @@ func (d *driver) Format(
 		"driverName":  d.Name()}).Info("probe information")
 
 	if overwriteFs || !fsDetected {
-		switch newFsType {
-		case "ext4":
-			if err := exec.Command("mkfs.ext4", "-F", deviceName).Run(); err != nil {
-				return fmt.Errorf(
-					"Problem creating filesystem on %s with error %s",
-					deviceName, err)
-			}
-		case "xfs":
-			if err := exec.Command("mkfs.xfs", "-f", deviceName).Run(); err != nil {
-				return fmt.Errorf(
-					"Problem creating filesystem on %s with error %s",
-					deviceName, err)
-			}
-		default:
-			return goof.New("Unsupported FS")
+		fs, ok := getFilesystem(newFsType)
+		if !ok {
+			return goof.WithField("fsType", newFsType, "Unsupported FS")
+		}
+
+		var args []string
+		var fsLabel string
+		if opts := core.FormatOptionsFromContext(ctx); opts != nil {
+			args = strings.Fields(opts.Args)
+			fsLabel = opts.Label
+		}
+
+		cmd := fs.mkfsCommand(deviceName, args, fsLabel)
+		if out, err := runCommand(ctx, cmd); err != nil {
+			return goof.WithFieldsE(goof.Fields{
+				"deviceName": deviceName,
+				"fsType":     fs.Name,
+				"args":       cmd.Args,
+				"output":     string(out),
+			}, "error creating filesystem", err)
 		}
 	}
 
 	return nil
 }
 
+// ResizeFilesystem grows the filesystem on the device, mounted at the mount
+// point, to fill the device. The device is rescanned first so that the kernel
+// sees its new size, and the open mapping of an encrypted device is grown
+// before its filesystem.
+func (d *driver) ResizeFilesystem(deviceName, mountPoint string) error {
+
+	if err := rescanDevice(deviceName); err != nil {
+		return err
+	}
+
+	deviceName, err := luksResize(context.Background(), deviceName)
+	if err != nil {
+		return err
+	}
+
+	fsType, err := probeFsType(deviceName)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"fsType":     fsType,
+		"deviceName": deviceName,
+		"mountPoint": mountPoint,
+		"driverName": d.Name()}).Info("resizing filesystem")
+
+	fs, ok := getFilesystem(fsType)
+	if !ok || fs.Resize == nil {
+		return goof.WithField("fsType", fsType, "Unsupported FS")
+	}
+
+	if out, err := runCommand(
+		context.Background(), fs.Resize(deviceName, mountPoint)); err != nil {
+		return goof.WithFieldsE(goof.Fields{
+			"deviceName": deviceName,
+			"output":     string(out),
+		}, "error resizing filesystem", err)
+	}
+
+	return nil
+}
+
+// Freeze suspends writes to the filesystem mounted at the mount point and
+// flushes it to the device so that a snapshot of the device is consistent.
+func (d *driver) Freeze(mountPoint string) error {
+	log.WithFields(log.Fields{
+		"mountPoint": mountPoint,
+		"driverName": d.Name()}).Info("freezing filesystem")
+	if err := fsFreeze(mountPoint, true); err != nil {
+		return goof.WithFieldE(
+			"mountPoint", mountPoint, "error freezing filesystem", err)
+	}
+	return nil
+}
+
+// Thaw resumes writes to the filesystem mounted at the mount point.
+func (d *driver) Thaw(mountPoint string) error {
+	log.WithFields(log.Fields{
+		"mountPoint": mountPoint,
+		"driverName": d.Name()}).Info("thawing filesystem")
+	if err := fsFreeze(mountPoint, false); err != nil {
+		return goof.WithFieldE(
+			"mountPoint", mountPoint, "error thawing filesystem", err)
+	}
+	return nil
+}
+
+// Stats returns the usage of the filesystem mounted at the mount point and
+// the I/O counters of the device. An NFS export has no I/O counters.
+func (d *driver) Stats(
+	deviceName, mountPoint string) (*core.MountStats, error) {
+
+	stats, err := statfs(mountPoint)
+	if err != nil {
+		return nil, goof.WithFieldE(
+			"mountPoint", mountPoint, "error getting filesystem stats", err)
+	}
+	if d.isNfsDevice(deviceName) {
+		return stats, nil
+	}
+	if stats.IO, err = deviceIOStats(deviceName); err != nil {
+		return nil, goof.WithFieldE(
+			"deviceName", deviceName, "error getting device stats", err)
+	}
+	return stats, nil
+}
+
+// deviceIOStats returns the I/O counters of the device from the kernel's
+// stat file for the device. The file's sector counts are always in units of
+// 512 bytes.
+func deviceIOStats(deviceName string) (*core.DeviceIOStats, error) {
+	devicePath, err := filepath.EvalSymlinks(deviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	statPath := fmt.Sprintf(
+		"/sys/class/block/%s/stat", filepath.Base(devicePath))
+	buf, err := ioutil.ReadFile(statPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(buf))
+	if len(fields) < 11 {
+		return nil, goof.WithField(
+			"statPath", statPath, "invalid device stat file")
+	}
+	var v [11]uint64
+	for i := range v {
+		if v[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return nil, goof.WithFieldE(
+				"statPath", statPath, "invalid device stat file", err)
+		}
+	}
+
+	return &core.DeviceIOStats{
+		ReadIOs:          v[0],
+		ReadMerges:       v[1],
+		ReadBytes:        v[2] * 512,
+		ReadTimeMs:       v[3],
+		WriteIOs:         v[4],
+		WriteMerges:      v[5],
+		WriteBytes:       v[6] * 512,
+		WriteTimeMs:      v[7],
+		InFlight:         v[8],
+		IOTimeMs:         v[9],
+		WeightedIOTimeMs: v[10],
+	}, nil
+}
+
+// rescanDevice asks the kernel to read the size of a SCSI device again. Other
+// devices, such as Xen and NVMe devices, are resized by the kernel without a
+// rescan.
+func rescanDevice(deviceName string) error {
+	devicePath, err := filepath.EvalSymlinks(deviceName)
+	if err != nil {
+		return err
+	}
+
+	rescanPath := fmt.Sprintf(
+		"/sys/class/block/%s/device/rescan", filepath.Base(devicePath))
+	if _, err := os.Stat(rescanPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	log.WithField("deviceName", devicePath).Debug("rescanning device")
+	return ioutil.WriteFile(rescanPath, []byte("1"), 0200)
+}
+
+// runCommand runs the command and returns its combined output. If the
+// context is done before the command exits then the command's process is
+// killed and the context's error is returned.
+func runCommand(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-waitCh:
+		return out.Bytes(), err
+	case <-ctx.Done():
+		if err := cmd.Process.Kill(); err != nil {
+			log.WithFields(log.Fields{
+				"cmd":   cmd.Path,
+				"error": err}).Warn("error killing process")
+		}
+		<-waitCh
+		return out.Bytes(), ctx.Err()
+	}
+}
+
 // from github.com/docker/docker/daemon/graphdriver/devmapper/
 // this should be abstracted outside of graphdriver but within Docker package,
 // here temporarily
@@ -186,10 +485,16 @@ type probeData struct {
 
 func probeFsType(device string) (string, error) {
 	probes := []probeData{
-		{"btrfs", "_BHRfS_M", 0x10040},
-		{"ext4", "\123\357", 0x438},
-		{"xfs", "XFSB", 0},
+		{luksFsType, "LUKS\xba\xbe", 0},
+	}
+	filesystemsLock.RLock()
+	for _, fs := range filesystems {
+		if fs.Magic != "" {
+			probes = append(probes,
+				probeData{fs.Name, fs.Magic, fs.MagicOffset})
+		}
 	}
+	filesystemsLock.RUnlock()
 
 	maxLen := uint64(0)
 	for _, p := range probes {
@@ -0,0 +1,156 @@
+package linux
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/goof"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core/errors"
+)
+
+const (
+	// luksFsType is the type probeFsType returns for a LUKS device.
+	luksFsType = "crypto_LUKS"
+
+	// luksNamePrefix prefixes the names of the dm-crypt mappings opened by
+	// the driver, so that only those are closed when they are unmounted.
+	luksNamePrefix = "rexray-"
+
+	devMapperPath = "/dev/mapper"
+)
+
+var errLuksKeyRequired = goof.New(
+	"device is encrypted; an encryption key is required")
+
+// luksName returns the name of the dm-crypt mapping for the device. The name
+// is derived from the kernel's name for the device so that the mapping of a
+// device can be found without any other state.
+func luksName(deviceName string) string {
+	devicePath, err := filepath.EvalSymlinks(deviceName)
+	if err != nil {
+		devicePath = deviceName
+	}
+	return luksNamePrefix + filepath.Base(devicePath)
+}
+
+// luksMappedPath returns the path of the dm-crypt mapping for the device.
+func luksMappedPath(deviceName string) string {
+	return filepath.Join(devMapperPath, luksName(deviceName))
+}
+
+// isLuksMappedPath returns a flag indicating whether or not the path is that
+// of a dm-crypt mapping opened by the driver.
+func isLuksMappedPath(p string) bool {
+	return strings.HasPrefix(p, filepath.Join(devMapperPath, luksNamePrefix))
+}
+
+// luksFormat initializes a LUKS header on the device with the key.
+func luksFormat(ctx context.Context, deviceName string, key []byte) error {
+	log.WithField("deviceName", deviceName).Info("encrypting device")
+	cmd := exec.Command("cryptsetup", "-q", "luksFormat",
+		"--key-file=-", deviceName)
+	cmd.Stdin = bytes.NewReader(key)
+	if out, err := runCommand(ctx, cmd); err != nil {
+		return goof.WithFieldsE(goof.Fields{
+			"deviceName": deviceName,
+			"output":     string(out),
+		}, "error encrypting device", err)
+	}
+	return nil
+}
+
+// luksOpen opens the dm-crypt mapping of the LUKS device with the key and
+// returns the mapping's path. A mapping that is already open is returned.
+func luksOpen(
+	ctx context.Context, deviceName string, key []byte) (string, error) {
+
+	mappedPath := luksMappedPath(deviceName)
+	if _, err := os.Stat(mappedPath); err == nil {
+		return mappedPath, nil
+	}
+
+	log.WithFields(log.Fields{
+		"deviceName": deviceName,
+		"mappedPath": mappedPath}).Info("opening encrypted device")
+	cmd := exec.Command("cryptsetup", "open", "--type", "luks",
+		"--key-file=-", deviceName, luksName(deviceName))
+	cmd.Stdin = bytes.NewReader(key)
+	if out, err := runCommand(ctx, cmd); err != nil {
+		return "", goof.WithFieldsE(goof.Fields{
+			"deviceName": deviceName,
+			"output":     string(out),
+		}, "error opening encrypted device", err)
+	}
+	return mappedPath, nil
+}
+
+// luksClose closes the dm-crypt mapping at the path.
+func luksClose(ctx context.Context, mappedPath string) error {
+	log.WithField("mappedPath", mappedPath).Info("closing encrypted device")
+	cmd := exec.Command("cryptsetup", "close", filepath.Base(mappedPath))
+	if out, err := runCommand(ctx, cmd); err != nil {
+		return goof.WithFieldsE(goof.Fields{
+			"mappedPath": mappedPath,
+			"output":     string(out),
+		}, "error closing encrypted device", err)
+	}
+	return nil
+}
+
+// luksResize grows the dm-crypt mapping of the device, if one is open, to
+// fill the device and returns the mapping's path. The device is returned if
+// it has no open mapping.
+func luksResize(ctx context.Context, deviceName string) (string, error) {
+	mappedPath := luksMappedPath(deviceName)
+	if _, err := os.Stat(mappedPath); err != nil {
+		return deviceName, nil
+	}
+	cmd := exec.Command("cryptsetup", "resize", filepath.Base(mappedPath))
+	if out, err := runCommand(ctx, cmd); err != nil {
+		return "", goof.WithFieldsE(goof.Fields{
+			"mappedPath": mappedPath,
+			"output":     string(out),
+		}, "error resizing encrypted device", err)
+	}
+	return mappedPath, nil
+}
+
+// luksPrepare returns the device on which the filesystem is created for a
+// device that is encrypted with the key. A device without a LUKS header, or
+// whose header is overwritten, is encrypted first. A device with an
+// unencrypted filesystem is never encrypted unless it is overwritten.
+func luksPrepare(
+	ctx context.Context,
+	deviceName string, key []byte, overwriteFs bool) (string, error) {
+
+	fsType, err := probeFsType(deviceName)
+	if err != nil && err != errors.ErrUnknownFileSystem {
+		return "", err
+	}
+
+	switch {
+	case fsType == luksFsType && !overwriteFs:
+	case fsType == "" || overwriteFs:
+		mappedPath := luksMappedPath(deviceName)
+		if _, err := os.Stat(mappedPath); err == nil {
+			if err := luksClose(ctx, mappedPath); err != nil {
+				return "", err
+			}
+		}
+		if err := luksFormat(ctx, deviceName, key); err != nil {
+			return "", err
+		}
+	default:
+		return "", goof.WithFields(goof.Fields{
+			"deviceName": deviceName,
+			"fsType":     fsType,
+		}, "device has an unencrypted filesystem")
+	}
+	return luksOpen(ctx, deviceName, key)
+}
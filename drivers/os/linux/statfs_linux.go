@@ -0,0 +1,24 @@
+package linux
+
+import (
+	"syscall"
+
+	"github.com/emccode/rexray/core"
+)
+
+// statfs returns the usage of the filesystem mounted at the mount point.
+func statfs(mountPoint string) (*core.MountStats, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mountPoint, &st); err != nil {
+		return nil, err
+	}
+	bsize := uint64(st.Bsize)
+	return &core.MountStats{
+		CapacityBytes:  st.Blocks * bsize,
+		UsedBytes:      (st.Blocks - st.Bfree) * bsize,
+		AvailableBytes: st.Bavail * bsize,
+		Inodes:         st.Files,
+		InodesUsed:     st.Files - st.Ffree,
+		InodesFree:     st.Ffree,
+	}, nil
+}
@@ -0,0 +1,12 @@
+// +build !linux
+
+package linux
+
+import (
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/core/errors"
+)
+
+func statfs(mountPoint string) (*core.MountStats, error) {
+	return nil, errors.ErrNotImplemented
+}
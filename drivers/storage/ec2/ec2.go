@@ -2,17 +2,21 @@ package ec2
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/akutz/gofig"
 	"github.com/akutz/goof"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/core/errors"
@@ -104,6 +108,20 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		Snapshots:         true,
+		CopySnapshot:      true,
+		Async:             true,
+		ForceAttach:       true,
+		IOPS:              true,
+		VolumeTypes:       true,
+		AvailabilityZones: true,
+		ExpandVolume:      true,
+		SnapshotGroups:    true,
+	}
+}
+
 func (d *driver) GetVolumeMapping() ([]*core.BlockDevice, error) {
 	blockDevices, err := d.getBlockDevices(d.instanceDocument.InstanceID)
 	if err != nil {
@@ -214,6 +232,14 @@ func (d *driver) getInstance() (ec2.Instance, error) {
 func (d *driver) CreateSnapshot(
 	runAsync bool,
 	snapshotName, volumeID, description string) ([]*core.Snapshot, error) {
+	return d.CreateSnapshotContext(context.Background(),
+		runAsync, snapshotName, volumeID, description)
+}
+
+func (d *driver) CreateSnapshotContext(
+	ctx context.Context,
+	runAsync bool,
+	snapshotName, volumeID, description string) ([]*core.Snapshot, error) {
 
 	resp, err := d.ec2Instance.CreateSnapshot(volumeID, description)
 	if err != nil {
@@ -230,7 +256,7 @@ func (d *driver) CreateSnapshot(
 
 	if !runAsync {
 		log.Println("Waiting for snapshot to complete")
-		err = d.waitSnapshotComplete(resp.Snapshot.Id)
+		err = d.waitSnapshotComplete(ctx, resp.Snapshot.Id)
 		if err != nil {
 			return nil, err
 		}
@@ -291,6 +317,7 @@ func (d *driver) GetSnapshot(
 			StartTime:   snapshot.StartTime,
 			Description: snapshot.Description,
 			Status:      snapshot.Status,
+			Labels:      getLabels(snapshot.Tags),
 		}
 		snapshotsInt = append(snapshotsInt, snapshotSD)
 	}
@@ -375,6 +402,15 @@ func getLocalDevices() (deviceNames []string, err error) {
 func (d *driver) CreateVolume(
 	runAsync bool, volumeName, volumeID, snapshotID, volumeType string,
 	IOPS, size int64, availabilityZone string) (*core.Volume, error) {
+	return d.CreateVolumeContext(context.Background(),
+		runAsync, volumeName, volumeID, snapshotID, volumeType,
+		IOPS, size, availabilityZone)
+}
+
+func (d *driver) CreateVolumeContext(
+	ctx context.Context,
+	runAsync bool, volumeName, volumeID, snapshotID, volumeType string,
+	IOPS, size int64, availabilityZone string) (*core.Volume, error) {
 
 	volumes, err := d.GetVolume("", volumeName)
 	if err != nil {
@@ -386,7 +422,7 @@ func (d *driver) CreateVolume(
 	}
 
 	resp, err := d.createVolume(
-		runAsync, volumeName, volumeID, snapshotID, volumeType,
+		ctx, runAsync, volumeName, volumeID, snapshotID, volumeType,
 		IOPS, size, availabilityZone)
 
 	if err != nil {
@@ -404,6 +440,7 @@ func (d *driver) CreateVolume(
 }
 
 func (d *driver) createVolume(
+	ctx context.Context,
 	runAsync bool, volumeName, volumeID, snapshotID, volumeType string,
 	IOPS, size int64,
 	availabilityZone string) (*ec2.CreateVolumeResp, error) {
@@ -437,7 +474,7 @@ func (d *driver) createVolume(
 	}
 
 	var resp *ec2.CreateVolumeResp
-	if resp, err = d.createVolumeCreateVolume(options); err != nil {
+	if resp, err = d.createVolumeCreateVolume(ctx, options); err != nil {
 		return &ec2.CreateVolumeResp{}, err
 	}
 
@@ -446,7 +483,7 @@ func (d *driver) createVolume(
 	}
 
 	if err = d.createVolumeWait(
-		runAsync, snapshotID, volumeID, resp); err != nil {
+		ctx, runAsync, snapshotID, volumeID, resp); err != nil {
 		return &ec2.CreateVolumeResp{}, err
 	}
 
@@ -476,13 +513,16 @@ func (d *driver) createVolumeEnsureAvailabilityZone(
 }
 
 func (d *driver) createVolumeCreateVolume(
+	ctx context.Context,
 	options *ec2.CreateVolume) (resp *ec2.CreateVolumeResp, err error) {
 	for {
 		resp, err = d.ec2Instance.CreateVolume(options)
 		if err != nil {
 			if err.Error() ==
 				"Snapshot is in invalid state - pending (IncorrectState)" {
-				time.Sleep(1 * time.Second)
+				if err = waitInterval(ctx); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, err
@@ -504,13 +544,14 @@ func (d *driver) createVolumeCreateTags(
 }
 
 func (d *driver) createVolumeWait(
+	ctx context.Context,
 	runAsync bool, snapshotID, volumeID string,
 	resp *ec2.CreateVolumeResp) (err error) {
 	if runAsync {
 		return
 	}
 	log.Println("Waiting for volume creation to complete")
-	if err = d.waitVolumeComplete(resp.VolumeId); err != nil {
+	if err = d.waitVolumeComplete(ctx, resp.VolumeId); err != nil {
 		return
 	}
 
@@ -525,15 +566,43 @@ func (d *driver) createVolumeWait(
 
 func (d *driver) getVolume(
 	volumeID, volumeName string) ([]ec2.Volume, error) {
+	return d.getVolumeByQuery(
+		&core.VolumeQuery{VolumeID: volumeID, VolumeName: volumeName})
+}
+
+// getVolumeByQuery returns the volumes that match the query's filters. The
+// name pattern is only pushed down if it uses no more than the * and ?
+// wildcards supported by EC2 filters.
+func (d *driver) getVolumeByQuery(
+	query *core.VolumeQuery) ([]ec2.Volume, error) {
 
 	filter := ec2.NewFilter()
-	if volumeName != "" {
-		filter.Add("tag:Name", fmt.Sprintf("%s", volumeName))
+	switch {
+	case query.VolumeName != "":
+		filter.Add("tag:Name", fmt.Sprintf("%s", query.VolumeName))
+	case query.NamePattern != "" &&
+		!strings.ContainsAny(query.NamePattern, "[\\"):
+		filter.Add("tag:Name", query.NamePattern)
+	}
+	if query.Status != "" {
+		filter.Add("status", query.Status)
+	}
+	if query.AvailabilityZone != "" {
+		filter.Add("availability-zone", query.AvailabilityZone)
+	}
+	if query.VolumeType != "" {
+		filter.Add("volume-type", query.VolumeType)
+	}
+	if query.InstanceID != "" {
+		filter.Add("attachment.instance-id", query.InstanceID)
+	}
+	for k, v := range query.Labels {
+		filter.Add("tag:"+k, v)
 	}
 
 	volumeList := []string{}
-	if volumeID != "" {
-		volumeList = append(volumeList, volumeID)
+	if query.VolumeID != "" {
+		volumeList = append(volumeList, query.VolumeID)
 	}
 
 	resp, err := d.ec2Instance.Volumes(volumeList, filter)
@@ -553,6 +622,41 @@ func getName(tags []ec2.Tag) string {
 	return ""
 }
 
+// getLabels returns the tags other than the Name tag.
+func getLabels(tags []ec2.Tag) map[string]string {
+	var labels map[string]string
+	for _, tag := range tags {
+		if tag.Key == "Name" {
+			continue
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[tag.Key] = tag.Value
+	}
+	return labels
+}
+
+func (d *driver) createLabelTags(
+	resourceID string, labels map[string]string) error {
+	var tags []ec2.Tag
+	for k, v := range labels {
+		tags = append(tags, ec2.Tag{Key: k, Value: v})
+	}
+	_, err := d.ec2Instance.CreateTags([]string{resourceID}, tags)
+	return err
+}
+
+func (d *driver) SetVolumeLabels(
+	volumeID string, labels map[string]string) error {
+	return d.createLabelTags(volumeID, labels)
+}
+
+func (d *driver) SetSnapshotLabels(
+	snapshotID string, labels map[string]string) error {
+	return d.createLabelTags(snapshotID, labels)
+}
+
 func (d *driver) GetVolume(
 	volumeID, volumeName string) ([]*core.Volume, error) {
 
@@ -561,6 +665,21 @@ func (d *driver) GetVolume(
 		return []*core.Volume{}, err
 	}
 
+	return toCoreVolumes(volumes), nil
+}
+
+func (d *driver) GetVolumesByQuery(
+	query *core.VolumeQuery) ([]*core.Volume, error) {
+
+	volumes, err := d.getVolumeByQuery(query)
+	if err != nil {
+		return []*core.Volume{}, err
+	}
+
+	return toCoreVolumes(volumes), nil
+}
+
+func toCoreVolumes(volumes []ec2.Volume) []*core.Volume {
 	var volumesSD []*core.Volume
 	for _, volume := range volumes {
 		var attachmentsSD []*core.VolumeAttachment
@@ -585,11 +704,12 @@ func (d *driver) GetVolume(
 			IOPS:             volume.IOPS,
 			Size:             volume.Size,
 			Attachments:      attachmentsSD,
+			Labels:           getLabels(volume.Tags),
 		}
 		volumesSD = append(volumesSD, volumeSD)
 	}
 
-	return volumesSD, nil
+	return volumesSD
 }
 
 func (d *driver) GetVolumeAttach(
@@ -618,7 +738,19 @@ func (d *driver) GetVolumeAttach(
 	return volumes[0].Attachments, nil
 }
 
-func (d *driver) waitSnapshotComplete(snapshotID string) error {
+// waitInterval blocks for the interval between polls of an operation's
+// status, returning early with the context's error if it is done.
+func waitInterval(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(1 * time.Second):
+		return nil
+	}
+}
+
+func (d *driver) waitSnapshotComplete(
+	ctx context.Context, snapshotID string) error {
 	for {
 
 		snapshots, err := d.getSnapshot("", snapshotID, "")
@@ -630,13 +762,16 @@ func (d *driver) waitSnapshotComplete(snapshotID string) error {
 		if snapshot.Status == "completed" {
 			break
 		}
-		time.Sleep(1 * time.Second)
+		if err := waitInterval(ctx); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (d *driver) waitVolumeComplete(volumeID string) error {
+func (d *driver) waitVolumeComplete(
+	ctx context.Context, volumeID string) error {
 	if volumeID == "" {
 		return errors.ErrMissingVolumeID
 	}
@@ -650,13 +785,16 @@ func (d *driver) waitVolumeComplete(volumeID string) error {
 		if volumes[0].Status == "available" {
 			break
 		}
-		time.Sleep(1 * time.Second)
+		if err := waitInterval(ctx); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (d *driver) waitVolumeAttach(volumeID, instanceID string) error {
+func (d *driver) waitVolumeAttach(
+	ctx context.Context, volumeID, instanceID string) error {
 	if volumeID == "" {
 		return errors.ErrMissingVolumeID
 	}
@@ -674,13 +812,16 @@ func (d *driver) waitVolumeAttach(volumeID, instanceID string) error {
 		if volume[0].Status == "attached" {
 			break
 		}
-		time.Sleep(1 * time.Second)
+		if err := waitInterval(ctx); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (d *driver) waitVolumeDetach(volumeID string) error {
+func (d *driver) waitVolumeDetach(
+	ctx context.Context, volumeID string) error {
 	if volumeID == "" {
 		return errors.ErrMissingVolumeID
 	}
@@ -694,13 +835,24 @@ func (d *driver) waitVolumeDetach(volumeID string) error {
 		if len(volume) == 0 {
 			break
 		}
-		time.Sleep(1 * time.Second)
+		if err := waitInterval(ctx); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (d *driver) RemoveVolume(volumeID string) error {
+	return d.RemoveVolumeContext(context.Background(), volumeID)
+}
+
+func (d *driver) RemoveVolumeContext(
+	ctx context.Context, volumeID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if volumeID == "" {
 		return errors.ErrMissingVolumeID
 	}
@@ -717,6 +869,14 @@ func (d *driver) RemoveVolume(volumeID string) error {
 func (d *driver) AttachVolume(
 	runAsync bool,
 	volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
+	return d.AttachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (d *driver) AttachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
 
 	if volumeID == "" {
 		return nil, errors.ErrMissingVolumeID
@@ -728,7 +888,8 @@ func (d *driver) AttachVolume(
 	}
 
 	if force {
-		if err := d.DetachVolume(false, volumeID, "", true); err != nil {
+		if err := d.DetachVolumeContext(
+			ctx, false, volumeID, "", true); err != nil {
 			return nil, err
 		}
 	}
@@ -742,7 +903,7 @@ func (d *driver) AttachVolume(
 
 	if !runAsync {
 		log.Println("Waiting for volume attachment to complete")
-		err = d.waitVolumeAttach(volumeID, instanceID)
+		err = d.waitVolumeAttach(ctx, volumeID, instanceID)
 		if err != nil {
 			return nil, err
 		}
@@ -761,6 +922,14 @@ func (d *driver) AttachVolume(
 func (d *driver) DetachVolume(
 	runAsync bool,
 	volumeID, blank string, force bool) error {
+	return d.DetachVolumeContext(
+		context.Background(), runAsync, volumeID, blank, force)
+}
+
+func (d *driver) DetachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, blank string, force bool) error {
 
 	if volumeID == "" {
 		return errors.ErrMissingVolumeID
@@ -782,7 +951,7 @@ func (d *driver) DetachVolume(
 
 	if !runAsync {
 		log.Println("Waiting for volume detachment to complete")
-		err = d.waitVolumeDetach(volumeID)
+		err = d.waitVolumeDetach(ctx, volumeID)
 		if err != nil {
 			return err
 		}
@@ -795,6 +964,16 @@ func (d *driver) DetachVolume(
 func (d *driver) CopySnapshot(runAsync bool,
 	volumeID, snapshotID, snapshotName, destinationSnapshotName,
 	destinationRegion string) (*core.Snapshot, error) {
+	return d.CopySnapshotContext(context.Background(),
+		runAsync, volumeID, snapshotID, snapshotName,
+		destinationSnapshotName, destinationRegion)
+}
+
+func (d *driver) CopySnapshotContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, snapshotID, snapshotName, destinationSnapshotName,
+	destinationRegion string) (*core.Snapshot, error) {
 
 	if volumeID == "" && snapshotID == "" && snapshotName == "" {
 		return nil, goof.New("Missing volumeID, snapshotID, or snapshotName")
@@ -851,7 +1030,7 @@ func (d *driver) CopySnapshot(runAsync bool,
 
 	if !runAsync {
 		log.Println("Waiting for snapshot copy to complete")
-		err = d.waitSnapshotComplete(resp.SnapshotId)
+		err = d.waitSnapshotComplete(ctx, resp.SnapshotId)
 		if err != nil {
 			return nil, err
 		}
@@ -865,6 +1044,224 @@ func (d *driver) CopySnapshot(runAsync bool,
 	return snapshot[0], nil
 }
 
+// apiVersion is the version of the EC2 API used for the actions that the
+// goamz client does not support, such as ModifyVolume and CreateSnapshots.
+const apiVersion = "2016-11-15"
+
+type ec2ErrorResp struct {
+	Errors []struct {
+		Code    string
+		Message string
+	} `xml:"Errors>Error"`
+}
+
+// apiRequest signs and sends a request for an EC2 API action and decodes
+// the response into resp.
+func (d *driver) apiRequest(
+	ctx context.Context,
+	params url.Values, fields goof.Fields, resp interface{}) error {
+
+	params.Set("Version", apiVersion)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/?%s",
+		d.ec2Instance.Region.EC2Endpoint, params.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	req.Cancel = ctx.Done()
+	aws.NewV4Signer(
+		d.ec2Instance.Auth, "ec2", d.ec2Instance.Region).Sign(req)
+
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp ec2ErrorResp
+		if xml.Unmarshal(body, &errResp) == nil && len(errResp.Errors) > 0 {
+			fields["code"] = errResp.Errors[0].Code
+			return goof.WithFields(eff(fields), errResp.Errors[0].Message)
+		}
+		fields["status"] = httpResp.Status
+		return goof.WithFields(
+			eff(fields), "error calling "+params.Get("Action"))
+	}
+
+	if resp == nil {
+		return nil
+	}
+	return xml.Unmarshal(body, resp)
+}
+
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	return d.ExpandVolumeContext(context.Background(), volumeID, newSize)
+}
+
+// ExpandVolumeContext grows the volume with ModifyVolume and waits for the
+// volume to report its new size. The volume is usable, and its filesystem may
+// be grown, while EC2 optimizes the volume in the background.
+func (d *driver) ExpandVolumeContext(
+	ctx context.Context, volumeID string, newSize int64) error {
+
+	if volumeID == "" {
+		return errors.ErrMissingVolumeID
+	}
+
+	params := url.Values{}
+	params.Set("Action", "ModifyVolume")
+	params.Set("VolumeId", volumeID)
+	params.Set("Size", strconv.FormatInt(newSize, 10))
+
+	if err := d.apiRequest(ctx, params,
+		goof.Fields{"volumeID": volumeID}, nil); err != nil {
+		return err
+	}
+
+	log.Println("Waiting for volume expansion to complete")
+	return d.waitVolumeSize(ctx, volumeID, newSize)
+}
+
+type createSnapshotsResp struct {
+	Snapshots []struct {
+		SnapshotID string `xml:"snapshotId"`
+		VolumeID   string `xml:"volumeId"`
+	} `xml:"snapshotSet>item"`
+}
+
+// CreateSnapshotGroup snapshots the volumes with CreateSnapshots, which takes
+// the snapshots of an instance's volumes at the same point in time. The
+// instance's other volumes are excluded. Volumes that are not all attached
+// to the same instance cannot be snapshotted together, and ErrNotImplemented
+// is returned for them.
+func (d *driver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+
+	ctx := context.Background()
+
+	var instanceID string
+	for volumeID := range snapshotNames {
+		volumes, err := d.getVolume(volumeID, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(volumes) == 0 {
+			return nil, errors.ErrNoVolumesReturned
+		}
+		fields := eff(goof.Fields{"volumeID": volumeID})
+		if len(volumes[0].Attachments) == 0 {
+			return nil, goof.WithFieldsE(fields,
+				"volume not attached", errors.ErrNotImplemented)
+		}
+		attachedTo := volumes[0].Attachments[0].InstanceId
+		if instanceID != "" && attachedTo != instanceID {
+			return nil, goof.WithFieldsE(fields,
+				"volumes attached to different instances",
+				errors.ErrNotImplemented)
+		}
+		instanceID = attachedTo
+	}
+
+	resp, err := d.ec2Instance.DescribeInstances(
+		[]string{instanceID}, &ec2.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Reservations) == 0 ||
+		len(resp.Reservations[0].Instances) == 0 {
+		return nil, goof.WithFields(eff(goof.Fields{
+			"instanceID": instanceID}), "instance not found")
+	}
+	instance := resp.Reservations[0].Instances[0]
+
+	params := url.Values{}
+	params.Set("Action", "CreateSnapshots")
+	params.Set("InstanceSpecification.InstanceId", instanceID)
+	params.Set("InstanceSpecification.ExcludeBootVolume", "true")
+	params.Set("Description", description)
+
+	excluded := 0
+	for _, bd := range instance.BlockDevices {
+		if _, ok := snapshotNames[bd.EBS.VolumeId]; ok {
+			if bd.DeviceName == instance.RootDeviceName {
+				params.Set(
+					"InstanceSpecification.ExcludeBootVolume", "false")
+			}
+			continue
+		}
+		if bd.DeviceName == instance.RootDeviceName {
+			continue
+		}
+		excluded++
+		params.Set(fmt.Sprintf(
+			"InstanceSpecification.ExcludeDataVolumeId.%d", excluded),
+			bd.EBS.VolumeId)
+	}
+
+	var snapshotsResp createSnapshotsResp
+	if err := d.apiRequest(ctx, params, goof.Fields{
+		"instanceID": instanceID}, &snapshotsResp); err != nil {
+		return nil, err
+	}
+
+	var snapshots []*core.Snapshot
+	for _, s := range snapshotsResp.Snapshots {
+		if name := snapshotNames[s.VolumeID]; name != "" {
+			if _, err := d.ec2Instance.CreateTags(
+				[]string{s.SnapshotID},
+				[]ec2.Tag{{"Name", name}}); err != nil {
+				return nil, err
+			}
+		}
+
+		if !runAsync {
+			log.Println("Waiting for snapshot to complete")
+			if err := d.waitSnapshotComplete(ctx, s.SnapshotID); err != nil {
+				return nil, err
+			}
+		}
+
+		snapshot, err := d.GetSnapshot("", s.SnapshotID, "")
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot...)
+	}
+
+	return snapshots, nil
+}
+
+func (d *driver) waitVolumeSize(
+	ctx context.Context, volumeID string, size int64) error {
+	sizes := strconv.FormatInt(size, 10)
+	for {
+		volumes, err := d.getVolume(volumeID, "")
+		if err != nil {
+			return err
+		}
+		if len(volumes) == 0 {
+			return errors.ErrNoVolumesReturned
+		}
+
+		if volumes[0].Size == sizes {
+			break
+		}
+		if err := waitInterval(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func configRegistration() *gofig.Registration {
 	r := gofig.NewRegistration("Amazon EC2")
 	r.Key(gofig.String, "", "", "", "aws.accessKey")
@@ -144,6 +144,17 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		Snapshots:         true,
+		Async:             true,
+		ForceAttach:       true,
+		VolumeTypes:       true,
+		AvailabilityZones: true,
+		ExpandVolume:      true,
+	}
+}
+
 func (d *driver) GetVolumeMapping() ([]*core.BlockDevice, error) {
 	log.WithField("provider", providerName).Debug("GetVolumeMapping")
 
@@ -296,6 +307,7 @@ func (d *driver) GetSnapshot(
 			VolumeSize: strconv.FormatInt(snapshot.DiskSizeGb, 10),
 			StartTime:  snapshot.CreationTimestamp,
 			Status:     snapshot.Status,
+			Labels:     snapshot.Labels,
 		}
 		snapshots = append(snapshots, snapshotSD)
 	}
@@ -325,6 +337,52 @@ func (d *driver) RemoveSnapshot(snapshotID string) error {
 	}
 	return nil
 }
+func mergeLabels(a, b map[string]string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range a {
+		labels[k] = v
+	}
+	for k, v := range b {
+		labels[k] = v
+	}
+	return labels
+}
+
+func (d *driver) SetVolumeLabels(
+	volumeID string, labels map[string]string) error {
+	log.WithField("provider", providerName).Debugf("SetVolumeLabels :%s", volumeID)
+	disk, err := d.client.Disks.Get(d.project, d.zone, volumeID).Do()
+	if err != nil {
+		return goof.WithError("problem getting volume", err)
+	}
+	req := &compute.ZoneSetLabelsRequest{
+		Labels:           mergeLabels(disk.Labels, labels),
+		LabelFingerprint: disk.LabelFingerprint,
+	}
+	operation, err := d.client.Disks.SetLabels(d.project, d.zone, volumeID, req).Do()
+	if err != nil {
+		return goof.WithError("problem setting volume labels", err)
+	}
+	return d.waitUntilOperationIsFinished(operation)
+}
+
+func (d *driver) SetSnapshotLabels(
+	snapshotID string, labels map[string]string) error {
+	log.WithField("provider", providerName).Debugf("SetSnapshotLabels :%s", snapshotID)
+	snapshot, err := d.client.Snapshots.Get(d.project, snapshotID).Do()
+	if err != nil {
+		return goof.WithError("problem getting snapshot", err)
+	}
+	req := &compute.GlobalSetLabelsRequest{
+		Labels:           mergeLabels(snapshot.Labels, labels),
+		LabelFingerprint: snapshot.LabelFingerprint,
+	}
+	if _, err := d.client.Snapshots.SetLabels(d.project, snapshotID, req).Do(); err != nil {
+		return goof.WithError("problem setting snapshot labels", err)
+	}
+	return nil
+}
+
 func getLocalDevices() (deviceNames []string, err error) {
 	file := "/proc/partitions"
 	contentBytes, err := ioutil.ReadFile(file)
@@ -531,6 +589,7 @@ func (d *driver) GetVolume(
 			IOPS:             0,
 			Size:             strconv.FormatInt(disk.SizeGb, 10),
 			Attachments:      diskAttachments,
+			Labels:           disk.Labels,
 		}
 		volumesSD = append(volumesSD, volumeSD)
 
@@ -693,6 +752,27 @@ func (d *driver) CopySnapshot(runAsync bool,
 	return nil, nil
 }
 
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	log.WithField("provider", providerName).Debugf(
+		"ExpandVolume :%s %d", volumeID, newSize)
+	if volumeID == "" {
+		return errors.ErrMissingVolumeID
+	}
+	operation, err := d.client.Disks.Resize(d.project, d.zone, volumeID,
+		&compute.DisksResizeRequest{SizeGb: newSize}).Do()
+	if err != nil {
+		return goof.WithError("problem expanding volume", err)
+	}
+	return d.waitUntilOperationIsFinished(operation)
+}
+
+func (d *driver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+	return nil, errors.ErrNotImplemented
+}
+
 func configRegistration() *gofig.Registration {
 	r := gofig.NewRegistration("Google GCE")
 	r.Key(gofig.String, "", "", "", "gce.keyfile")
@@ -15,6 +15,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/akutz/gofig"
 	"github.com/akutz/goof"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/core/errors"
@@ -122,6 +123,13 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		ForceAttach: true,
+		MultiAttach: true,
+	}
+}
+
 // Create an instance ID from a list of client IP addresses
 func createInstanceId(clients []string) string {
 	return strings.Join(clients, idDelimiter)
@@ -406,6 +414,18 @@ func (d *driver) GetVolumeAttach(volumeID, instanceID string) ([]*core.VolumeAtt
 func (d *driver) AttachVolume(
 	notused bool,
 	volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
+	return d.AttachVolumeMode(context.Background(), notused,
+		volumeID, instanceID, force, core.AccessModeReadWriteSingle)
+}
+
+// AttachVolumeMode adds the instance's addresses to the clients of a volume's
+// export. The existing clients of a volume with one of the many instance
+// access modes are retained.
+func (d *driver) AttachVolumeMode(
+	ctx context.Context,
+	notused bool,
+	volumeID, instanceID string,
+	force bool, mode core.AccessMode) ([]*core.VolumeAttachment, error) {
 
 	// sanity check the input
 	if volumeID == "" {
@@ -431,21 +451,31 @@ func (d *driver) AttachVolume(
 		return nil, goof.WithError("problem getting export client", err)
 	}
 
+	newClients := parseInstanceId(instanceID)
+
 	// clear out any existing clients if necessary.  if force is false and
-	// we have existing clients, we need to exit.
+	// we have existing clients, we need to exit.  volumes shared by many
+	// instances keep their existing clients.
 	if len(clients) > 0 {
-		if force == false {
+		switch {
+		case mode.Many():
+			newClients = mergeClients(clients, newClients)
+		case force == false:
 			return nil, goof.New("Volume already attached to another host")
+		default:
+			// remove all clients
+			err = d.client.ClearExportClients(volumeID)
+			if err != nil {
+				return nil, err
+			}
 		}
+	}
 
-		// remove all clients
-		err = d.client.ClearExportClients(volumeID)
-		if err != nil {
-			return nil, err
-		}
+	if err = ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	err = d.client.SetExportClients(volumeID, parseInstanceId(instanceID))
+	err = d.client.SetExportClients(volumeID, newClients)
 	if err != nil {
 		return nil, err
 	}
@@ -459,7 +489,11 @@ func (d *driver) AttachVolume(
 
 }
 
-func (d *driver) DetachVolume(notUsed bool, volumeID string, blank string, notused bool) error {
+// DetachVolume removes the instance's addresses, or those of the local
+// instance if no instance is provided, from the clients of a volume's export.
+// The volume is unexported when it has no other clients, when the instance is
+// not one of its clients, or when force is set.
+func (d *driver) DetachVolume(notUsed bool, volumeID string, instanceID string, force bool) error {
 	if volumeID == "" {
 		return errors.ErrMissingVolumeID
 	}
@@ -473,6 +507,30 @@ func (d *driver) DetachVolume(notUsed bool, volumeID string, blank string, notus
 		return errors.ErrNoVolumesReturned
 	}
 
+	if !force {
+		if instanceID == "" {
+			instance, err := d.GetInstance()
+			if err != nil {
+				return err
+			}
+			instanceID = instance.InstanceID
+		}
+
+		clients, err := d.client.GetExportClients(volumeID)
+		if err != nil {
+			return goof.WithError("problem getting export client", err)
+		}
+
+		remaining := removeClients(clients, parseInstanceId(instanceID))
+		if len(remaining) > 0 && len(remaining) < len(clients) {
+			if err := d.client.SetExportClients(
+				volumeID, remaining); err != nil {
+				return goof.WithError("problem setting export clients", err)
+			}
+			return nil
+		}
+	}
+
 	if err := d.client.UnexportVolume(volumeID); err != nil {
 		return goof.WithError("problem unexporting volume", err)
 	}
@@ -480,6 +538,38 @@ func (d *driver) DetachVolume(notUsed bool, volumeID string, blank string, notus
 	return nil
 }
 
+// mergeClients returns the clients followed by those of the new clients
+// that are not already among them.
+func mergeClients(clients, newClients []string) []string {
+	merged := append([]string{}, clients...)
+	for _, nc := range newClients {
+		if !containsClient(merged, nc) {
+			merged = append(merged, nc)
+		}
+	}
+	return merged
+}
+
+// removeClients returns the clients that are not among the removed clients.
+func removeClients(clients, removed []string) []string {
+	var remaining []string
+	for _, c := range clients {
+		if !containsClient(removed, c) {
+			remaining = append(remaining, c)
+		}
+	}
+	return remaining
+}
+
+func containsClient(clients []string, client string) bool {
+	for _, c := range clients {
+		if c == client {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *driver) CopySnapshot(
 	runAsync bool,
 	volumeID, snapshotID, snapshotName,
@@ -487,6 +577,17 @@ func (d *driver) CopySnapshot(
 	return nil, errors.ErrNotImplemented
 }
 
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	return errors.ErrNotImplemented
+}
+
+func (d *driver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+	return nil, errors.ErrNotImplemented
+}
+
 func (d *driver) GetDeviceNextAvailable() (string, error) {
 	return "", errors.ErrNotImplemented
 }
@@ -14,6 +14,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/akutz/gofig"
 	"github.com/akutz/goof"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/core/errors"
@@ -145,6 +146,18 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		Snapshots:         true,
+		Async:             true,
+		ForceAttach:       true,
+		MultiAttach:       true,
+		VolumeTypes:       true,
+		AvailabilityZones: true,
+		ExpandVolume:      true,
+	}
+}
+
 func (d *driver) newCmd(name string, args ...string) *exec.Cmd {
 	return newCmd(d.r.Config, name, args...)
 }
@@ -303,7 +316,8 @@ func getInstanceAvailabilityZone() (string, error) {
 }
 
 func (d *driver) getVolume(
-	volumeID, volumeName string) (volumesRet []volumes.Volume, err error) {
+	volumeID, volumeName string,
+	listOpts *volumes.ListOpts) (volumesRet []volumes.Volume, err error) {
 
 	if volumeID != "" {
 		volume, err := volumes.Get(d.clientBlockStorage, volumeID).Extract()
@@ -316,10 +330,6 @@ func (d *driver) getVolume(
 		}
 		volumesRet = append(volumesRet, *volume)
 	} else {
-		listOpts := &volumes.ListOpts{
-		//Name:       volumeName,
-		}
-
 		allPages, err := volumes.List(d.clientBlockStorage, listOpts).AllPages()
 		if err != nil {
 			return []volumes.Volume{},
@@ -360,7 +370,11 @@ func (d *driver) getVolume(
 func (d *driver) GetVolume(
 	volumeID, volumeName string) ([]*core.Volume, error) {
 
-	volumesRet, err := d.getVolume(volumeID, volumeName)
+	listOpts := &volumes.ListOpts{
+	//Name:       volumeName,
+	}
+
+	volumesRet, err := d.getVolume(volumeID, volumeName, listOpts)
 	if err != nil {
 		return []*core.Volume{},
 			goof.WithFieldsE(eff(goof.Fields{
@@ -369,6 +383,32 @@ func (d *driver) GetVolume(
 				"error getting volume", err)
 	}
 
+	return toCoreVolumes(volumesRet), nil
+}
+
+// GetVolumesByQuery pushes the query's status and label filters down to the
+// volume list request.
+func (d *driver) GetVolumesByQuery(
+	query *core.VolumeQuery) ([]*core.Volume, error) {
+
+	listOpts := &volumes.ListOpts{
+		Status:   query.Status,
+		Metadata: query.Labels,
+	}
+
+	volumesRet, err := d.getVolume(query.VolumeID, query.VolumeName, listOpts)
+	if err != nil {
+		return []*core.Volume{},
+			goof.WithFieldsE(eff(goof.Fields{
+				"volumeId":   query.VolumeID,
+				"volumeName": query.VolumeName}),
+				"error getting volume", err)
+	}
+
+	return toCoreVolumes(volumesRet), nil
+}
+
+func toCoreVolumes(volumesRet []volumes.Volume) []*core.Volume {
 	var volumesSD []*core.Volume
 	for _, volume := range volumesRet {
 		var attachmentsSD []*core.VolumeAttachment
@@ -391,11 +431,12 @@ func (d *driver) GetVolume(
 			IOPS:             0,
 			Size:             strconv.Itoa(volume.Size),
 			Attachments:      attachmentsSD,
+			Labels:           volume.Metadata,
 		}
 		volumesSD = append(volumesSD, volumeSD)
 	}
 
-	return volumesSD, nil
+	return volumesSD
 }
 
 func (d *driver) GetVolumeAttach(
@@ -417,15 +458,14 @@ func (d *driver) GetVolumeAttach(
 	}
 
 	if instanceID != "" {
-		var attached bool
+		// a multiattach volume is also attached to other instances
+		volumeAttachments := []*core.VolumeAttachment{}
 		for _, volumeAttachment := range volume[0].Attachments {
 			if volumeAttachment.InstanceID == instanceID {
-				return volume[0].Attachments, nil
+				volumeAttachments = append(volumeAttachments, volumeAttachment)
 			}
 		}
-		if !attached {
-			return []*core.VolumeAttachment{}, nil
-		}
+		return volumeAttachments, nil
 	}
 	return volume[0].Attachments, nil
 }
@@ -494,6 +534,7 @@ func (d *driver) GetSnapshot(
 			StartTime:   snapshot.CreatedAt,
 			Description: snapshot.Description,
 			Status:      snapshot.Status,
+			Labels:      getSnapshotLabels(snapshot.Metadata),
 		}
 		snapshotsInt = append(snapshotsInt, snapshotSD)
 	}
@@ -550,6 +591,45 @@ func (d *driver) CreateSnapshot(
 
 }
 
+func getSnapshotLabels(metadata map[string]interface{}) map[string]string {
+	var labels map[string]string
+	for k, v := range metadata {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[k] = fmt.Sprintf("%v", v)
+	}
+	return labels
+}
+
+// SetSnapshotLabels adds the labels to the snapshot's metadata.
+func (d *driver) SetSnapshotLabels(
+	snapshotID string, labels map[string]string) error {
+
+	fields := eff(goof.Fields{"snapshotId": snapshotID})
+
+	snapshot, err := snapshots.Get(d.clientBlockStorage, snapshotID).Extract()
+	if err != nil {
+		return goof.WithFieldsE(fields, "error getting snapshot", err)
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range snapshot.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range labels {
+		metadata[k] = v
+	}
+
+	opts := snapshots.UpdateMetadataOpts{Metadata: metadata}
+	if _, err := snapshots.UpdateMetadata(
+		d.clientBlockStorage, snapshotID, opts).ExtractMetadata(); err != nil {
+		return goof.WithFieldsE(fields, "error setting snapshot labels", err)
+	}
+
+	return nil
+}
+
 func (d *driver) RemoveSnapshot(snapshotID string) error {
 	resp := snapshots.Delete(d.clientBlockStorage, snapshotID)
 	if resp.Err != nil {
@@ -742,6 +822,34 @@ func (d *driver) createVolumeHandleVolumeID(
 	return volume, nil
 }
 
+// SetVolumeLabels adds the labels to the volume's metadata.
+func (d *driver) SetVolumeLabels(
+	volumeID string, labels map[string]string) error {
+
+	fields := eff(goof.Fields{"volumeId": volumeID})
+
+	volume, err := volumes.Get(d.clientBlockStorage, volumeID).Extract()
+	if err != nil {
+		return goof.WithFieldsE(fields, "error getting volume", err)
+	}
+
+	metadata := map[string]string{}
+	for k, v := range volume.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range labels {
+		metadata[k] = v
+	}
+
+	opts := volumes.UpdateOpts{Metadata: metadata}
+	if _, err := volumes.Update(
+		d.clientBlockStorage, volumeID, opts).Extract(); err != nil {
+		return goof.WithFieldsE(fields, "error setting volume labels", err)
+	}
+
+	return nil
+}
+
 func (d *driver) RemoveVolume(volumeID string) error {
 	fields := eff(map[string]interface{}{
 		"volumeId": volumeID,
@@ -758,6 +866,50 @@ func (d *driver) RemoveVolume(volumeID string) error {
 	return nil
 }
 
+// ExpandVolume grows the volume to newSize GB with the Cinder os-extend
+// volume action and waits until the volume reports its new size.
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	fields := eff(goof.Fields{
+		"volumeId": volumeID,
+		"newSize":  newSize,
+	})
+	if volumeID == "" {
+		return goof.WithFields(fields, "volumeId is required")
+	}
+
+	body := map[string]interface{}{
+		"os-extend": map[string]interface{}{
+			"new_size": newSize,
+		},
+	}
+	url := d.clientBlockStoragev2.ServiceURL("volumes", volumeID, "action")
+	if _, err := d.clientBlockStoragev2.Post(url, body, nil,
+		&gophercloud.RequestOpts{OkCodes: []int{202}}); err != nil {
+		return goof.WithFieldsE(fields, "error expanding volume", err)
+	}
+
+	for i := 0; i < 120; i++ {
+		volume, err := volumes.Get(d.clientBlockStorage, volumeID).Extract()
+		if err != nil {
+			return goof.WithFieldsE(fields, "error getting volume", err)
+		}
+		if int64(volume.Size) >= newSize {
+			log.WithFields(fields).Debug("expanded volume")
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	return goof.WithFields(fields, "timed out waiting for volume to expand")
+}
+
+func (d *driver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+	return nil, errors.ErrNotImplemented
+}
+
 func (d *driver) GetDeviceNextAvailable() (string, error) {
 	letters := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p"}
 	blockDeviceNames := make(map[string]bool)
@@ -821,11 +973,23 @@ func getLocalDevices() (deviceNames []string, err error) {
 
 func (d *driver) AttachVolume(
 	runAsync bool, volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
+	return d.AttachVolumeMode(context.Background(), runAsync,
+		volumeID, instanceID, force, core.AccessModeReadWriteSingle)
+}
+
+// AttachVolumeMode attaches a volume with one of the many instance access
+// modes to the instance while leaving it attached to the other instances.
+// Such a volume must have a volume type that supports multiattach.
+func (d *driver) AttachVolumeMode(
+	ctx context.Context,
+	runAsync bool, volumeID, instanceID string,
+	force bool, mode core.AccessMode) ([]*core.VolumeAttachment, error) {
 
 	fields := eff(map[string]interface{}{
 		"runAsync":   runAsync,
 		"volumeId":   volumeID,
 		"instanceId": instanceID,
+		"accessMode": mode,
 	})
 
 	nextDeviceName, err := d.GetDeviceNextAvailable()
@@ -834,7 +998,7 @@ func (d *driver) AttachVolume(
 			fields, "error getting next available device", err)
 	}
 
-	if force {
+	if force && !mode.Many() {
 		if err := d.DetachVolume(false, volumeID, "", true); err != nil {
 			return nil, err
 		}
@@ -853,7 +1017,7 @@ func (d *driver) AttachVolume(
 
 	if !runAsync {
 		log.WithFields(fields).Debug("waiting for volume to attach")
-		err = d.waitVolumeAttach(volumeID)
+		err = d.waitVolumeAttach(volumeID, instanceID)
 		if err != nil {
 			return nil, goof.WithFieldsE(
 				fields, "error waiting for volume to detach", err)
@@ -894,7 +1058,21 @@ func (d *driver) DetachVolume(
 		return nil
 	}
 
-	fields["instanceId"] = volume[0].Attachments[0].InstanceID
+	// detach the volume from the instance, or from the local instance if no
+	// instance is provided and the volume is attached to it, rather than from
+	// one of the other instances to which a multiattach volume is attached.
+	attachment := volume[0].Attachments[0]
+	if instanceID == "" {
+		instanceID = d.instanceID
+	}
+	for _, a := range volume[0].Attachments {
+		if a.InstanceID == instanceID {
+			attachment = a
+			break
+		}
+	}
+
+	fields["instanceId"] = attachment.InstanceID
 	if force {
 		if resp := volumeactions.ForceDetach(d.clientBlockStoragev2, volumeID); resp.Err != nil {
 			log.Info(fmt.Sprintf("%+v", resp.Err))
@@ -902,14 +1080,14 @@ func (d *driver) DetachVolume(
 		}
 	} else {
 		if resp := volumeattach.Delete(
-			d.client, volume[0].Attachments[0].InstanceID, volumeID); resp.Err != nil {
+			d.client, attachment.InstanceID, volumeID); resp.Err != nil {
 			return goof.WithFieldsE(fields, "error detaching volume", resp.Err)
 		}
 	}
 
 	if !runAsync {
 		log.WithFields(fields).Debug("waiting for volume to detach")
-		err = d.waitVolumeDetach(volumeID)
+		err = d.waitVolumeDetach(volumeID, attachment.InstanceID, force)
 		if err != nil {
 			return goof.WithFieldsE(
 				fields, "error waiting for volume to detach", err)
@@ -920,10 +1098,12 @@ func (d *driver) DetachVolume(
 	return nil
 }
 
-func (d *driver) waitVolumeAttach(volumeID string) error {
+// waitVolumeAttach waits until the volume is attached to the instance.
+func (d *driver) waitVolumeAttach(volumeID, instanceID string) error {
 
 	fields := eff(map[string]interface{}{
-		"volumeId": volumeID,
+		"volumeId":   volumeID,
+		"instanceId": instanceID,
 	})
 
 	if volumeID == "" {
@@ -934,7 +1114,8 @@ func (d *driver) waitVolumeAttach(volumeID string) error {
 		if err != nil {
 			return goof.WithFieldsE(fields, "error getting volume", err)
 		}
-		if volume[0].Status == "in-use" {
+		if volume[0].Status == "in-use" &&
+			isAttachedTo(volume[0], instanceID) {
 			break
 		}
 		time.Sleep(1 * time.Second)
@@ -943,10 +1124,14 @@ func (d *driver) waitVolumeAttach(volumeID string) error {
 	return nil
 }
 
-func (d *driver) waitVolumeDetach(volumeID string) error {
+// waitVolumeDetach waits until the volume is no longer attached to the
+// instance or, if all is set, to any instance.
+func (d *driver) waitVolumeDetach(
+	volumeID, instanceID string, all bool) error {
 
 	fields := eff(map[string]interface{}{
-		"volumeId": volumeID,
+		"volumeId":   volumeID,
+		"instanceId": instanceID,
 	})
 
 	if volumeID == "" {
@@ -957,7 +1142,8 @@ func (d *driver) waitVolumeDetach(volumeID string) error {
 		if err != nil {
 			return goof.WithFieldsE(fields, "error getting volume", err)
 		}
-		if len(volume[0].Attachments) == 0 {
+		if len(volume[0].Attachments) == 0 ||
+			(!all && !isAttachedTo(volume[0], instanceID)) {
 			break
 		}
 
@@ -967,6 +1153,17 @@ func (d *driver) waitVolumeDetach(volumeID string) error {
 	return nil
 }
 
+// isAttachedTo returns a flag indicating whether or not the volume is
+// attached to the instance.
+func isAttachedTo(volume *core.Volume, instanceID string) bool {
+	for _, a := range volume.Attachments {
+		if a.InstanceID == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *driver) CopySnapshot(
 	runAsync bool, volumeID, snapshotID, snapshotName, destinationSnapshotName,
 	destinationRegion string) (*core.Snapshot, error) {
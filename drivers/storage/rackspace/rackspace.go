@@ -123,6 +123,16 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		Snapshots:         true,
+		Async:             true,
+		VolumeTypes:       true,
+		AvailabilityZones: true,
+		ExpandVolume:      true,
+	}
+}
+
 func (d *driver) newCmd(name string, args ...string) *exec.Cmd {
 	return newCmd(d.r.Config, name, args...)
 }
@@ -265,7 +275,8 @@ func getInstanceRegion(cfg gofig.Config) (string, error) {
 }
 
 func (d *driver) getVolume(
-	volumeID, volumeName string) (volumesRet []volumes.Volume, err error) {
+	volumeID, volumeName string,
+	listOpts *volumes.ListOpts) (volumesRet []volumes.Volume, err error) {
 
 	if volumeID != "" {
 		volume, err := volumes.Get(d.clientBlockStorage, volumeID).Extract()
@@ -278,10 +289,6 @@ func (d *driver) getVolume(
 		}
 		volumesRet = append(volumesRet, *volume)
 	} else {
-		listOpts := &volumes.ListOpts{
-		//Name:       volumeName,
-		}
-
 		allPages, err := volumes.List(d.clientBlockStorage, listOpts).AllPages()
 		if err != nil {
 			return []volumes.Volume{},
@@ -322,7 +329,11 @@ func (d *driver) getVolume(
 func (d *driver) GetVolume(
 	volumeID, volumeName string) ([]*core.Volume, error) {
 
-	volumesRet, err := d.getVolume(volumeID, volumeName)
+	listOpts := &volumes.ListOpts{
+	//Name:       volumeName,
+	}
+
+	volumesRet, err := d.getVolume(volumeID, volumeName, listOpts)
 	if err != nil {
 		return []*core.Volume{},
 			goof.WithFieldsE(eff(goof.Fields{
@@ -331,6 +342,32 @@ func (d *driver) GetVolume(
 				"error getting volume", err)
 	}
 
+	return toCoreVolumes(volumesRet), nil
+}
+
+// GetVolumesByQuery pushes the query's status and label filters down to the
+// volume list request.
+func (d *driver) GetVolumesByQuery(
+	query *core.VolumeQuery) ([]*core.Volume, error) {
+
+	listOpts := &volumes.ListOpts{
+		Status:   query.Status,
+		Metadata: query.Labels,
+	}
+
+	volumesRet, err := d.getVolume(query.VolumeID, query.VolumeName, listOpts)
+	if err != nil {
+		return []*core.Volume{},
+			goof.WithFieldsE(eff(goof.Fields{
+				"volumeId":   query.VolumeID,
+				"volumeName": query.VolumeName}),
+				"error getting volume", err)
+	}
+
+	return toCoreVolumes(volumesRet), nil
+}
+
+func toCoreVolumes(volumesRet []volumes.Volume) []*core.Volume {
 	var volumesSD []*core.Volume
 	for _, volume := range volumesRet {
 		var attachmentsSD []*core.VolumeAttachment
@@ -353,11 +390,12 @@ func (d *driver) GetVolume(
 			IOPS:             0,
 			Size:             strconv.Itoa(volume.Size),
 			Attachments:      attachmentsSD,
+			Labels:           volume.Metadata,
 		}
 		volumesSD = append(volumesSD, volumeSD)
 	}
 
-	return volumesSD, nil
+	return volumesSD
 }
 
 func (d *driver) GetVolumeAttach(
@@ -456,6 +494,7 @@ func (d *driver) GetSnapshot(
 			StartTime:   snapshot.CreatedAt,
 			Description: snapshot.Description,
 			Status:      snapshot.Status,
+			Labels:      getSnapshotLabels(snapshot.Metadata),
 		}
 		snapshotsInt = append(snapshotsInt, snapshotSD)
 	}
@@ -512,6 +551,45 @@ func (d *driver) CreateSnapshot(
 
 }
 
+func getSnapshotLabels(metadata map[string]interface{}) map[string]string {
+	var labels map[string]string
+	for k, v := range metadata {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[k] = fmt.Sprintf("%v", v)
+	}
+	return labels
+}
+
+// SetSnapshotLabels adds the labels to the snapshot's metadata.
+func (d *driver) SetSnapshotLabels(
+	snapshotID string, labels map[string]string) error {
+
+	fields := eff(goof.Fields{"snapshotId": snapshotID})
+
+	snapshot, err := snapshots.Get(d.clientBlockStorage, snapshotID).Extract()
+	if err != nil {
+		return goof.WithFieldsE(fields, "error getting snapshot", err)
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range snapshot.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range labels {
+		metadata[k] = v
+	}
+
+	opts := snapshots.UpdateMetadataOpts{Metadata: metadata}
+	if _, err := snapshots.UpdateMetadata(
+		d.clientBlockStorage, snapshotID, opts).ExtractMetadata(); err != nil {
+		return goof.WithFieldsE(fields, "error setting snapshot labels", err)
+	}
+
+	return nil
+}
+
 func (d *driver) RemoveSnapshot(snapshotID string) error {
 	resp := snapshots.Delete(d.clientBlockStorage, snapshotID)
 	if resp.Err != nil {
@@ -696,6 +774,34 @@ func (d *driver) createVolumeHandleVolumeID(
 	return volume, nil
 }
 
+// SetVolumeLabels adds the labels to the volume's metadata.
+func (d *driver) SetVolumeLabels(
+	volumeID string, labels map[string]string) error {
+
+	fields := eff(goof.Fields{"volumeId": volumeID})
+
+	volume, err := volumes.Get(d.clientBlockStorage, volumeID).Extract()
+	if err != nil {
+		return goof.WithFieldsE(fields, "error getting volume", err)
+	}
+
+	metadata := map[string]string{}
+	for k, v := range volume.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range labels {
+		metadata[k] = v
+	}
+
+	opts := volumes.UpdateOpts{Metadata: metadata}
+	if _, err := volumes.Update(
+		d.clientBlockStorage, volumeID, opts).Extract(); err != nil {
+		return goof.WithFieldsE(fields, "error setting volume labels", err)
+	}
+
+	return nil
+}
+
 func (d *driver) RemoveVolume(volumeID string) error {
 	fields := eff(map[string]interface{}{
 		"volumeId": volumeID,
@@ -712,6 +818,50 @@ func (d *driver) RemoveVolume(volumeID string) error {
 	return nil
 }
 
+// ExpandVolume grows the volume to newSize GB with the os-extend volume
+// action and waits until the volume reports its new size.
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	fields := eff(goof.Fields{
+		"volumeId": volumeID,
+		"newSize":  newSize,
+	})
+	if volumeID == "" {
+		return goof.WithFields(fields, "volumeId is required")
+	}
+
+	body := map[string]interface{}{
+		"os-extend": map[string]interface{}{
+			"new_size": newSize,
+		},
+	}
+	url := d.clientBlockStorage.ServiceURL("volumes", volumeID, "action")
+	if _, err := d.clientBlockStorage.Post(url, body, nil,
+		&gophercloud.RequestOpts{OkCodes: []int{202}}); err != nil {
+		return goof.WithFieldsE(fields, "error expanding volume", err)
+	}
+
+	for i := 0; i < 120; i++ {
+		volume, err := volumes.Get(d.clientBlockStorage, volumeID).Extract()
+		if err != nil {
+			return goof.WithFieldsE(fields, "error getting volume", err)
+		}
+		if int64(volume.Size) >= newSize {
+			log.WithFields(fields).Debug("expanded volume")
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	return goof.WithFields(fields, "timed out waiting for volume to expand")
+}
+
+func (d *driver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+	return nil, errors.ErrNotImplemented
+}
+
 func (d *driver) GetDeviceNextAvailable() (string, error) {
 	letters := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p"}
 	blockDeviceNames := make(map[string]bool)
@@ -8,6 +8,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/akutz/gofig"
 	"github.com/akutz/goof"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/goscaleio"
 	types "github.com/emccode/goscaleio/types/v1"
@@ -136,6 +137,17 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		Snapshots:      true,
+		ForceAttach:    true,
+		MultiAttach:    true,
+		VolumeTypes:    true,
+		ExpandVolume:   true,
+		SnapshotGroups: true,
+	}
+}
+
 func (d *driver) getInstance() (*goscaleio.Sdc, error) {
 	return d.sdc, nil
 }
@@ -333,6 +345,18 @@ func (d *driver) GetSnapshot(
 func (d *driver) CreateSnapshot(
 	notUsed bool,
 	snapshotName, volumeID, description string) ([]*core.Snapshot, error) {
+	return d.CreateSnapshotContext(context.Background(),
+		notUsed, snapshotName, volumeID, description)
+}
+
+func (d *driver) CreateSnapshotContext(
+	ctx context.Context,
+	notUsed bool,
+	snapshotName, volumeID, description string) ([]*core.Snapshot, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	snapshotDef := &types.SnapshotDef{
 		VolumeID:     volumeID,
@@ -367,6 +391,43 @@ func (d *driver) CreateSnapshot(
 
 }
 
+// CreateSnapshotGroup snapshots the volumes in a single ScaleIO snapshot
+// consistency group so that the snapshots share a point in time.
+func (d *driver) CreateSnapshotGroup(
+	notUsed bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+
+	var snapshotDefs []*types.SnapshotDef
+	for volumeID, snapshotName := range snapshotNames {
+		snapshotDefs = append(snapshotDefs, &types.SnapshotDef{
+			VolumeID:     volumeID,
+			SnapshotName: snapshotName,
+		})
+	}
+
+	snapshotVolumes, err := d.system.CreateSnapshotConsistencyGroup(
+		&types.SnapshotVolumesParam{SnapshotDefs: snapshotDefs})
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*core.Snapshot
+	for _, snapshotID := range snapshotVolumes.VolumeIDList {
+		snapshot, err := d.GetSnapshot("", snapshotID, "")
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot...)
+	}
+
+	log.WithFields(log.Fields{
+		"provider":        providerName,
+		"snapshotGroupID": snapshotVolumes.SnapshotGroupID,
+		"snapshots":       snapshots}).Debug("created snapshot group")
+	return snapshots, nil
+}
+
 func (d *driver) createVolume(
 	notUsed bool,
 	volumeName, volumeID, snapshotID, volumeType string,
@@ -401,6 +462,20 @@ func (d *driver) CreateVolume(
 	notUsed bool,
 	volumeName, volumeID, snapshotID, volumeType string,
 	IOPS, size int64, availabilityZone string) (*core.Volume, error) {
+	return d.CreateVolumeContext(context.Background(),
+		notUsed, volumeName, volumeID, snapshotID, volumeType,
+		IOPS, size, availabilityZone)
+}
+
+func (d *driver) CreateVolumeContext(
+	ctx context.Context,
+	notUsed bool,
+	volumeName, volumeID, snapshotID, volumeType string,
+	IOPS, size int64, availabilityZone string) (*core.Volume, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	resp, err := d.createVolume(
 		notUsed, volumeName, volumeID, snapshotID,
@@ -424,6 +499,11 @@ func (d *driver) CreateVolume(
 }
 
 func (d *driver) RemoveVolume(volumeID string) error {
+	return d.RemoveVolumeContext(context.Background(), volumeID)
+}
+
+func (d *driver) RemoveVolumeContext(
+	ctx context.Context, volumeID string) error {
 
 	fields := eff(map[string]interface{}{
 		"volumeId": volumeID,
@@ -440,6 +520,10 @@ func (d *driver) RemoveVolume(volumeID string) error {
 		return goof.WithFieldsE(fields, "error getting volume", err)
 	}
 
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
 	targetVolume := goscaleio.NewVolume(d.client)
 	targetVolume.Volume = volumes[0]
 
@@ -467,26 +551,47 @@ func (d *driver) GetDeviceNextAvailable() (string, error) {
 func (d *driver) AttachVolume(
 	runAsync bool,
 	volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
+	return d.AttachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (d *driver) AttachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
+	mode, _ := core.AccessModeFromContext(ctx)
+	return d.AttachVolumeMode(ctx, runAsync, volumeID, instanceID, force, mode)
+}
+
+// AttachVolumeMode maps a volume with one of the many instance access modes
+// to the SDC while leaving it mapped to the other SDCs.
+func (d *driver) AttachVolumeMode(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string,
+	force bool, mode core.AccessMode) ([]*core.VolumeAttachment, error) {
 
 	fields := eff(map[string]interface{}{
 		"runAsync":   runAsync,
 		"volumeId":   volumeID,
 		"instanceId": instanceID,
+		"accessMode": mode,
 	})
 
 	if volumeID == "" {
 		return nil, goof.WithFields(fields, "volumeId is required")
 	}
 
-	if force {
-		if err := d.DetachVolume(false, volumeID, "", true); err != nil {
+	if force && !mode.Many() {
+		if err := d.DetachVolumeContext(
+			ctx, false, volumeID, "", true); err != nil {
 			return nil, err
 		}
 	}
 
 	mapVolumeSdcParam := &types.MapVolumeSdcParam{
 		SdcID: d.sdc.Sdc.ID,
-		AllowMultipleMappings: "false",
+		AllowMultipleMappings: strconv.FormatBool(mode.Many()),
 		AllSdcs:               "",
 	}
 
@@ -507,7 +612,7 @@ func (d *driver) AttachVolume(
 		return nil, goof.WithFieldsE(fields, "error mapping volume sdc", err)
 	}
 
-	_, err = waitMount(volumes[0].ID)
+	_, err = waitMount(ctx, volumes[0].ID)
 	if err != nil {
 		fields["volumeId"] = volumes[0].ID
 		return nil, goof.WithFieldsE(
@@ -530,6 +635,13 @@ func (d *driver) AttachVolume(
 
 func (d *driver) DetachVolume(
 	runAsync bool, volumeID string, blank string, force bool) error {
+	return d.DetachVolumeContext(
+		context.Background(), runAsync, volumeID, blank, force)
+}
+
+func (d *driver) DetachVolumeContext(
+	ctx context.Context,
+	runAsync bool, volumeID string, blank string, force bool) error {
 
 	fields := eff(map[string]interface{}{
 		"runAsync": runAsync,
@@ -550,6 +662,10 @@ func (d *driver) DetachVolume(
 		return goof.WithFields(fields, "no volumes returned")
 	}
 
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
 	targetVolume := goscaleio.NewVolume(d.client)
 	targetVolume.Volume = volumes[0]
 
@@ -581,7 +697,64 @@ func (d *driver) CopySnapshot(
 	return nil, goof.New("This driver does not implement CopySnapshot")
 }
 
-func waitMount(volumeID string) (*goscaleio.SdcMappedVolume, error) {
+func (d *driver) CopySnapshotContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, snapshotID,
+	snapshotName, destinationSnapshotName,
+	destinationRegion string) (*core.Snapshot, error) {
+	return d.CopySnapshot(runAsync, volumeID, snapshotID,
+		snapshotName, destinationSnapshotName, destinationRegion)
+}
+
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	return d.ExpandVolumeContext(context.Background(), volumeID, newSize)
+}
+
+func (d *driver) ExpandVolumeContext(
+	ctx context.Context, volumeID string, newSize int64) error {
+
+	fields := eff(map[string]interface{}{
+		"volumeId": volumeID,
+		"newSize":  newSize,
+	})
+
+	if volumeID == "" {
+		return goof.WithFields(fields, "volumeId is required")
+	}
+
+	volumes, err := d.getVolume(volumeID, "", false)
+	if err != nil {
+		return goof.WithFieldsE(fields, "error getting volume", err)
+	}
+
+	if len(volumes) == 0 {
+		return goof.WithFields(fields, "no volumes returned")
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	targetVolume := goscaleio.NewVolume(d.client)
+	targetVolume.Volume = volumes[0]
+
+	if err = targetVolume.SetVolumeSize(
+		strconv.FormatInt(newSize, 10)); err != nil {
+		return goof.WithFieldsE(fields, "error expanding volume", err)
+	}
+
+	log.WithFields(fields).Debug("expanded volume")
+	return nil
+}
+
+// waitMount polls the local volume mappings until the volume's device
+// appears, the wait times out, or the context is done.
+func waitMount(
+	ctx context.Context, volumeID string) (*goscaleio.SdcMappedVolume, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	timeout := make(chan bool, 1)
 	go func() {
@@ -616,7 +789,12 @@ func waitMount(volumeID string) (*goscaleio.SdcMappedVolume, error) {
 				successCh <- sdcMappedVolume
 				return
 			}
-			time.Sleep(100 * time.Millisecond)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
 		}
 
 	}(volumeID)
@@ -631,6 +809,8 @@ func waitMount(volumeID string) (*goscaleio.SdcMappedVolume, error) {
 		return sdcMappedVolume, nil
 	case err := <-errorCh:
 		return &goscaleio.SdcMappedVolume{}, err
+	case <-ctx.Done():
+		return &goscaleio.SdcMappedVolume{}, ctx.Err()
 	case <-timeout:
 		return &goscaleio.SdcMappedVolume{}, goof.WithFields(
 			ef(), "timed out waiting for mount")
@@ -120,6 +120,12 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		ForceAttach: true,
+	}
+}
+
 func (d *driver) GetInstance() (*core.Instance, error) {
 
 	instance := &core.Instance{
@@ -455,6 +461,17 @@ func (d *driver) CopySnapshot(
 	return nil, errors.ErrNotImplemented
 }
 
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	return errors.ErrNotImplemented
+}
+
+func (d *driver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+	return nil, errors.ErrNotImplemented
+}
+
 func (d *driver) GetDeviceNextAvailable() (string, error) {
 	return "", errors.ErrNotImplemented
 }
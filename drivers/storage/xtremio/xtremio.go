@@ -1,8 +1,12 @@
 package xtremio
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,6 +21,7 @@ import (
 	"github.com/akutz/gofig"
 	"github.com/akutz/goof"
 	xtio "github.com/emccode/goxtremio"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/core/errors"
@@ -157,6 +162,15 @@ func (d *driver) Name() string {
 	return providerName
 }
 
+func (d *driver) Capabilities() *core.StorageCapabilities {
+	return &core.StorageCapabilities{
+		Snapshots:    true,
+		Async:        true,
+		ForceAttach:  true,
+		ExpandVolume: true,
+	}
+}
+
 func (d *driver) getVolumesSig() (string, error) {
 	volumes, err := d.client.GetVolumes()
 	if err != nil {
@@ -509,6 +523,20 @@ func (d *driver) CreateVolume(
 	notUsed bool,
 	volumeName, volumeID, snapshotID, NUvolumeType string,
 	NUIOPS, size int64, NUavailabilityZone string) (*core.Volume, error) {
+	return d.CreateVolumeContext(context.Background(),
+		notUsed, volumeName, volumeID, snapshotID, NUvolumeType,
+		NUIOPS, size, NUavailabilityZone)
+}
+
+func (d *driver) CreateVolumeContext(
+	ctx context.Context,
+	notUsed bool,
+	volumeName, volumeID, snapshotID, NUvolumeType string,
+	NUIOPS, size int64, NUavailabilityZone string) (*core.Volume, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	fields := eff(map[string]interface{}{
 		"volumeID":   volumeID,
@@ -563,6 +591,16 @@ func (d *driver) CreateVolume(
 }
 
 func (d *driver) RemoveVolume(volumeID string) error {
+	return d.RemoveVolumeContext(context.Background(), volumeID)
+}
+
+func (d *driver) RemoveVolumeContext(
+	ctx context.Context, volumeID string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	fields := eff(map[string]interface{}{
 		"volumeID": volumeID,
 	})
@@ -677,6 +715,18 @@ func getIndex(href string) string {
 func (d *driver) CreateSnapshot(
 	notUsed bool,
 	snapshotName, volumeID, description string) ([]*core.Snapshot, error) {
+	return d.CreateSnapshotContext(context.Background(),
+		notUsed, snapshotName, volumeID, description)
+}
+
+func (d *driver) CreateSnapshotContext(
+	ctx context.Context,
+	notUsed bool,
+	snapshotName, volumeID, description string) ([]*core.Snapshot, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	volume, err := d.client.GetVolume(volumeID, "")
 	if err != nil {
@@ -735,7 +785,11 @@ func (d *driver) GetVolumeAttach(volumeID, instanceID string) ([]*core.VolumeAtt
 	return volume[0].Attachments, nil
 }
 
-func (d *driver) waitAttach(volumeID string) (*core.BlockDevice, error) {
+func (d *driver) waitAttach(
+	ctx context.Context, volumeID string) (*core.BlockDevice, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	volumes, err := d.GetVolume(volumeID, "")
 	if err != nil {
@@ -776,7 +830,12 @@ func (d *driver) waitAttach(volumeID string) (*core.BlockDevice, error) {
 					return
 				}
 			}
-			time.Sleep(100 * time.Millisecond)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
 		}
 	}(volumeID)
 
@@ -787,6 +846,8 @@ func (d *driver) waitAttach(volumeID string) (*core.BlockDevice, error) {
 		return blockDevice, nil
 	case err := <-errorCh:
 		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	case <-timeout:
 		return nil, goof.New("timed out waiting for mount")
 	}
@@ -796,6 +857,14 @@ func (d *driver) waitAttach(volumeID string) (*core.BlockDevice, error) {
 func (d *driver) AttachVolume(
 	runAsync bool,
 	volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
+	return d.AttachVolumeContext(
+		context.Background(), runAsync, volumeID, instanceID, force)
+}
+
+func (d *driver) AttachVolumeContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, instanceID string, force bool) ([]*core.VolumeAttachment, error) {
 
 	if volumeID == "" {
 		return nil, errors.ErrMissingVolumeID
@@ -813,7 +882,8 @@ func (d *driver) AttachVolume(
 	if len(volumes[0].Attachments) > 0 && !force {
 		return nil, goof.New("Volume already attached to another host")
 	} else if len(volumes[0].Attachments) > 0 && force {
-		if err := d.DetachVolume(false, volumeID, "", true); err != nil {
+		if err := d.DetachVolumeContext(
+			ctx, false, volumeID, "", true); err != nil {
 			return nil, err
 		}
 	}
@@ -849,7 +919,7 @@ func (d *driver) AttachVolume(
 	}
 
 	if !runAsync {
-		_, err := d.waitAttach(volumeID)
+		_, err := d.waitAttach(ctx, volumeID)
 		if err != nil {
 			return nil, err
 		}
@@ -896,6 +966,13 @@ func (d *driver) getLunMaps(initiatorName, volumeID string) (xtio.Refs, error) {
 }
 
 func (d *driver) DetachVolume(notUsed bool, volumeID string, blank string, notused bool) error {
+	return d.DetachVolumeContext(
+		context.Background(), notUsed, volumeID, blank, notused)
+}
+
+func (d *driver) DetachVolumeContext(
+	ctx context.Context,
+	notUsed bool, volumeID string, blank string, notused bool) error {
 	if volumeID == "" {
 		return errors.ErrMissingVolumeID
 	}
@@ -939,6 +1016,10 @@ func (d *driver) DetachVolume(notUsed bool, volumeID string, blank string, notus
 			continue
 		}
 
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		index := getIndex(lunMaps[0].Href)
 		if err = d.client.DeleteLunMap(index, ""); err != nil {
 			return goof.WithFieldE("index", index, "error deleting lun map", err)
@@ -956,10 +1037,87 @@ func (d *driver) CopySnapshot(
 	return nil, errors.ErrNotImplemented
 }
 
+func (d *driver) CopySnapshotContext(
+	ctx context.Context,
+	runAsync bool,
+	volumeID, snapshotID, snapshotName,
+	destinationSnapshotName, destinationRegion string) (*core.Snapshot, error) {
+	return d.CopySnapshot(runAsync, volumeID, snapshotID, snapshotName,
+		destinationSnapshotName, destinationRegion)
+}
+
 func (d *driver) GetDeviceNextAvailable() (string, error) {
 	return "", errors.ErrNotImplemented
 }
 
+func (d *driver) ExpandVolume(volumeID string, newSize int64) error {
+	return d.ExpandVolumeContext(context.Background(), volumeID, newSize)
+}
+
+// ExpandVolumeContext grows the volume by modifying its size with the XMS
+// REST API, which the goxtremio client does not expose. The size is in KB,
+// as it is when the volume is created.
+func (d *driver) ExpandVolumeContext(
+	ctx context.Context, volumeID string, newSize int64) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fields := eff(map[string]interface{}{
+		"volumeID": volumeID,
+		"newSize":  newSize,
+	})
+
+	if volumeID == "" {
+		return errors.ErrMissingVolumeID
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"vol-size": strconv.FormatInt(newSize*1024*1024, 10),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/types/volumes/%s",
+		strings.TrimSuffix(d.endpoint(), "/"), volumeID),
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(d.userName(), d.password())
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: d.insecure()},
+		},
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return goof.WithFieldsE(fields, "error expanding volume", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		fields["status"] = res.Status
+		fields["response"] = string(msg)
+		return goof.WithFields(fields, "error expanding volume")
+	}
+
+	log.WithFields(fields).Debug("expanded volume")
+	return nil
+}
+
+func (d *driver) CreateSnapshotGroup(
+	runAsync bool,
+	snapshotNames map[string]string,
+	description string) ([]*core.Snapshot, error) {
+	return nil, errors.ErrNotImplemented
+}
+
 func (d *driver) endpoint() string {
 	return d.r.Config.GetString("xtremio.endpoint")
 }
@@ -9,6 +9,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/akutz/gofig"
 	"github.com/akutz/goof"
+	"golang.org/x/net/context"
 
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/util"
@@ -17,6 +18,8 @@ import (
 const (
 	providerName            = "docker"
 	defaultVolumeSize int64 = 16
+	labelOptPrefix          = "label."
+	mountOptsLabelSep       = ";"
 )
 
 type driver struct {
@@ -24,7 +27,8 @@ type driver struct {
 }
 
 var (
-	mountDirectoryPath string
+	mountDirectoryPath  string
+	deviceDirectoryPath string
 )
 
 func init() {
@@ -32,6 +36,8 @@ func init() {
 	gofig.Register(configRegistration())
 	mountDirectoryPath = util.LibFilePath("volumes")
 	os.MkdirAll(mountDirectoryPath, 0755)
+	deviceDirectoryPath = util.LibFilePath("devices")
+	os.MkdirAll(deviceDirectoryPath, 0755)
 }
 
 func newDriver() core.Driver {
@@ -52,6 +58,16 @@ func getVolumeMountPath(name string) (string, error) {
 	return fmt.Sprintf("%s/%s", mountDirectoryPath, name), nil
 }
 
+// getVolumeDevicePath returns the path of the link to the device of a volume
+// with the block mode.
+func getVolumeDevicePath(name string) (string, error) {
+	if name == "" {
+		return "", goof.New("Missing volume name")
+	}
+
+	return fmt.Sprintf("%s/%s", deviceDirectoryPath, name), nil
+}
+
 // Name will return the name of the volume driver manager
 func (d *driver) Name() string {
 	return providerName
@@ -59,6 +75,15 @@ func (d *driver) Name() string {
 
 // Mount will perform the steps to get an existing Volume with or without a fileystem mounted to a guest
 func (d *driver) Mount(volumeName, volumeID string, overwriteFs bool, newFsType string, preempt bool) (string, error) {
+	return d.MountContext(context.Background(),
+		volumeName, volumeID, overwriteFs, newFsType, preempt)
+}
+
+// MountContext is Mount with a context.
+func (d *driver) MountContext(
+	ctx context.Context,
+	volumeName, volumeID string,
+	overwriteFs bool, newFsType string, preempt bool) (string, error) {
 	log.WithFields(log.Fields{
 		"volumeName":  volumeName,
 		"volumeID":    volumeID,
@@ -72,9 +97,18 @@ func (d *driver) Mount(volumeName, volumeID string, overwriteFs bool, newFsType
 	var instance *core.Instance
 
 	if vols, volAttachments, instance, err = d.prefixToMountUnmount(
-		volumeName, volumeID); err != nil {
+		ctx, volumeName, volumeID); err != nil {
+		return "", err
+	}
+
+	mode, err := volumeAccessMode(ctx, vols[0])
+	if err != nil {
 		return "", err
 	}
+	ctx = core.WithAccessMode(ctx, mode)
+	if mode.Many() {
+		preempt = false
+	}
 
 	if len(volAttachments) == 0 {
 		mp, err := getVolumeMountPath(vols[0].Name)
@@ -83,10 +117,10 @@ func (d *driver) Mount(volumeName, volumeID string, overwriteFs bool, newFsType
 		}
 
 		log.Debug("performing precautionary unmount")
-		_ = d.r.OS.Unmount(mp)
+		_ = d.r.OS.UnmountContext(ctx, mp)
 
-		volAttachments, err = d.r.Storage.AttachVolume(
-			false, vols[0].VolumeID, instance.InstanceID, preempt)
+		volAttachments, err = d.r.Storage.AttachVolumeContext(
+			ctx, false, vols[0].VolumeID, instance.InstanceID, preempt)
 		if err != nil {
 			return "", err
 		}
@@ -96,10 +130,18 @@ func (d *driver) Mount(volumeName, volumeID string, overwriteFs bool, newFsType
 		return "", goof.New("Volume did not attach")
 	}
 
+	if ctx, err = d.withEncryptionKey(ctx, vols[0]); err != nil {
+		return "", err
+	}
+
 	if volAttachments[0].DeviceName == "" {
 		return "", goof.New("no device name returned")
 	}
 
+	if core.IsBlockVolume(vols[0]) {
+		return linkDevice(vols[0].Name, volAttachments[0].DeviceName)
+	}
+
 	mounts, err := d.r.OS.GetMounts(volAttachments[0].DeviceName, "")
 	if err != nil {
 		return "", err
@@ -116,9 +158,12 @@ func (d *driver) Mount(volumeName, volumeID string, overwriteFs bool, newFsType
 		newFsType = "ext4"
 	}
 
-	if err := d.r.OS.Format(
-		volAttachments[0].DeviceName, newFsType, overwriteFs); err != nil {
-		return "", err
+	if !mode.ReadOnly() {
+		if err := d.r.OS.FormatContext(
+			withFormatLabel(ctx, vols[0]),
+			volAttachments[0].DeviceName, newFsType, overwriteFs); err != nil {
+			return "", err
+		}
 	}
 
 	mountPath, err := getVolumeMountPath(vols[0].Name)
@@ -130,8 +175,15 @@ func (d *driver) Mount(volumeName, volumeID string, overwriteFs bool, newFsType
 		return "", err
 	}
 
-	if err := d.r.OS.Mount(
-		volAttachments[0].DeviceName, mountPath, "", ""); err != nil {
+	mountOpts := core.MountOptionsFromContext(ctx)
+	if mountOpts == "" {
+		mountOpts = strings.Replace(
+			vols[0].Labels[core.VolumeMountOptionsLabel],
+			mountOptsLabelSep, ",", -1)
+	}
+
+	if err := d.r.OS.MountContext(
+		ctx, volAttachments[0].DeviceName, mountPath, mountOpts, ""); err != nil {
 		return "", err
 	}
 
@@ -140,6 +192,12 @@ func (d *driver) Mount(volumeName, volumeID string, overwriteFs bool, newFsType
 
 // Unmount will perform the steps to unmount and existing volume and detach
 func (d *driver) Unmount(volumeName, volumeID string) error {
+	return d.UnmountContext(context.Background(), volumeName, volumeID)
+}
+
+// UnmountContext is Unmount with a context.
+func (d *driver) UnmountContext(
+	ctx context.Context, volumeName, volumeID string) error {
 
 	log.WithFields(log.Fields{
 		"volumeName": volumeName,
@@ -151,7 +209,7 @@ func (d *driver) Unmount(volumeName, volumeID string) error {
 	var volAttachments []*core.VolumeAttachment
 
 	if vols, volAttachments, _, err = d.prefixToMountUnmount(
-		volumeName, volumeID); err != nil {
+		ctx, volumeName, volumeID); err != nil {
 		return err
 	}
 
@@ -159,57 +217,143 @@ func (d *driver) Unmount(volumeName, volumeID string) error {
 		return nil
 	}
 
-	mounts, err := d.r.OS.GetMounts(volAttachments[0].DeviceName, "")
+	if core.IsBlockVolume(vols[0]) {
+		if err := unlinkDevice(vols[0].Name); err != nil {
+			return err
+		}
+	} else {
+		mounts, err := d.r.OS.GetMounts(volAttachments[0].DeviceName, "")
+		if err != nil {
+			return err
+		}
+
+		if len(mounts) > 0 {
+			err := d.r.OS.UnmountContext(ctx, mounts[0].Mountpoint)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = d.r.Storage.DetachVolumeContext(
+		ctx, false, vols[0].VolumeID, "", false)
 	if err != nil {
 		return err
 	}
+	return nil
+}
 
-	if len(mounts) > 0 {
-		err := d.r.OS.Unmount(mounts[0].Mountpoint)
-		if err != nil {
-			return err
+// linkDevice links the volume's device path to the device of a volume with
+// the block mode and returns the path. A link to another device is replaced.
+func linkDevice(volumeName, deviceName string) (string, error) {
+	devicePath, err := getVolumeDevicePath(volumeName)
+	if err != nil {
+		return "", err
+	}
+
+	if target, err := os.Readlink(devicePath); err == nil {
+		if target == deviceName {
+			return devicePath, nil
+		}
+		if err := os.Remove(devicePath); err != nil {
+			return "", err
 		}
 	}
 
-	err = d.r.Storage.DetachVolume(false, vols[0].VolumeID, "", false)
+	log.WithFields(log.Fields{
+		"deviceName": deviceName,
+		"devicePath": devicePath}).Info("linking volume device")
+	if err := os.Symlink(deviceName, devicePath); err != nil {
+		return "", goof.WithFieldsE(goof.Fields{
+			"deviceName": deviceName,
+			"devicePath": devicePath,
+		}, "error linking volume device", err)
+	}
+	return devicePath, nil
+}
+
+// unlinkDevice removes the link to the device of a volume with the block
+// mode.
+func unlinkDevice(volumeName string) error {
+	devicePath, err := getVolumeDevicePath(volumeName)
 	if err != nil {
 		return err
 	}
+	if err := os.Remove(devicePath); err != nil && !os.IsNotExist(err) {
+		return goof.WithFieldE(
+			"devicePath", devicePath, "error unlinking volume device", err)
+	}
 	return nil
 }
 
-func (d *driver) getInstance() (*core.Instance, error) {
-	instances, err := d.r.Storage.GetInstances()
+// volumeAccessMode returns the access mode in the context or, if there is
+// none, the mode of the volume's access mode label.
+func volumeAccessMode(
+	ctx context.Context, volume *core.Volume) (core.AccessMode, error) {
+
+	if mode, ok := core.AccessModeFromContext(ctx); ok {
+		return mode, nil
+	}
+	return core.ParseAccessMode(volume.Labels[core.VolumeAccessModeLabel])
+}
+
+// withFormatLabel returns a copy of the context whose format options label
+// the filesystem with the volume's name unless they provide another label.
+func withFormatLabel(ctx context.Context, volume *core.Volume) context.Context {
+	opts := &core.FormatOptions{Label: volume.Name}
+	if o := core.FormatOptionsFromContext(ctx); o != nil {
+		opts.Args = o.Args
+		if o.Label != "" {
+			opts.Label = o.Label
+		}
+	}
+	return core.WithFormatOptions(ctx, opts)
+}
+
+// withEncryptionKey returns a copy of the context that carries the volume's
+// encryption key if the volume has the encrypted label or the context
+// requests encryption. Otherwise the context is returned.
+func (d *driver) withEncryptionKey(
+	ctx context.Context, volume *core.Volume) (context.Context, error) {
+
+	encrypted, _ := core.EncryptionFromContext(ctx)
+	if !encrypted && volume.Labels[core.VolumeEncryptedLabel] != "true" {
+		return ctx, nil
+	}
+
+	key, err := d.r.EncryptionKey(ctx, volume.VolumeID)
 	if err != nil {
 		return nil, err
 	}
+	return core.WithEncryptionKey(ctx, key), nil
+}
 
-	switch {
-	case len(instances) == 0:
+// getInstance returns the instance of the storage driver that owns the
+// volume with the provided ID.
+func (d *driver) getInstance(
+	ctx context.Context, volumeID string) (*core.Instance, error) {
+	instance, err := d.r.Storage.GetVolumeInstanceContext(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	if instance == nil {
 		return nil, goof.New("No instances")
-	case len(instances) > 1:
-		return nil,
-			goof.New("Too many instances returned, limit the storagedrivers")
 	}
-
-	return instances[0], nil
+	return instance, nil
 }
 
 func (d *driver) prefixToMountUnmount(
+	ctx context.Context,
 	volumeName,
 	volumeID string) ([]*core.Volume, []*core.VolumeAttachment, *core.Instance, error) {
 	if volumeName == "" && volumeID == "" {
 		return nil, nil, nil, goof.New("Missing volume name or ID")
 	}
 
-	var instance *core.Instance
 	var err error
-	if instance, err = d.getInstance(); err != nil {
-		return nil, nil, nil, err
-	}
-
 	var vols []*core.Volume
-	if vols, err = d.r.Storage.GetVolume(volumeID, volumeName); err != nil {
+	if vols, err = d.r.Storage.GetVolumeContext(
+		ctx, volumeID, volumeName); err != nil {
 		return nil, nil, nil, err
 	}
 
@@ -220,9 +364,14 @@ func (d *driver) prefixToMountUnmount(
 		return nil, nil, nil, goof.New("Multiple volumes returned by name")
 	}
 
+	var instance *core.Instance
+	if instance, err = d.getInstance(ctx, vols[0].VolumeID); err != nil {
+		return nil, nil, nil, err
+	}
+
 	var volAttachments []*core.VolumeAttachment
-	if volAttachments, err = d.r.Storage.GetVolumeAttach(
-		vols[0].VolumeID, instance.InstanceID); err != nil {
+	if volAttachments, err = d.r.Storage.GetVolumeAttachContext(
+		ctx, vols[0].VolumeID, instance.InstanceID); err != nil {
 		return nil, nil, nil, err
 	}
 
@@ -231,6 +380,12 @@ func (d *driver) prefixToMountUnmount(
 
 // Path returns the mounted path of the volume
 func (d *driver) Path(volumeName, volumeID string) (string, error) {
+	return d.PathContext(context.Background(), volumeName, volumeID)
+}
+
+// PathContext is Path with a context.
+func (d *driver) PathContext(
+	ctx context.Context, volumeName, volumeID string) (string, error) {
 	log.WithFields(log.Fields{
 		"volumeName": volumeName,
 		"volumeID":   volumeID,
@@ -239,19 +394,7 @@ func (d *driver) Path(volumeName, volumeID string) (string, error) {
 		return "", goof.New("Missing volume name or ID")
 	}
 
-	instances, err := d.r.Storage.GetInstances()
-	if err != nil {
-		return "", err
-	}
-
-	switch {
-	case len(instances) == 0:
-		return "", goof.New("No instances")
-	case len(instances) > 1:
-		return "", goof.New("Too many instances returned, limit the storagedrivers")
-	}
-
-	volumes, err := d.r.Storage.GetVolume(volumeID, volumeName)
+	volumes, err := d.r.Storage.GetVolumeContext(ctx, volumeID, volumeName)
 	if err != nil {
 		return "", err
 	}
@@ -263,7 +406,13 @@ func (d *driver) Path(volumeName, volumeID string) (string, error) {
 		return "", goof.New("Multiple volumes returned by name")
 	}
 
-	volumeAttachment, err := d.r.Storage.GetVolumeAttach(volumes[0].VolumeID, instances[0].InstanceID)
+	instance, err := d.getInstance(ctx, volumes[0].VolumeID)
+	if err != nil {
+		return "", err
+	}
+
+	volumeAttachment, err := d.r.Storage.GetVolumeAttachContext(
+		ctx, volumes[0].VolumeID, instance.InstanceID)
 	if err != nil {
 		return "", err
 	}
@@ -272,6 +421,17 @@ func (d *driver) Path(volumeName, volumeID string) (string, error) {
 		return "", nil
 	}
 
+	if core.IsBlockVolume(volumes[0]) {
+		devicePath, err := getVolumeDevicePath(volumes[0].Name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Lstat(devicePath); err != nil {
+			return "", nil
+		}
+		return devicePath, nil
+	}
+
 	mounts, err := d.r.OS.GetMounts(volumeAttachment[0].DeviceName, "")
 	if err != nil {
 		return "", err
@@ -286,6 +446,13 @@ func (d *driver) Path(volumeName, volumeID string) (string, error) {
 
 // Create will create a remote volume
 func (d *driver) Create(volumeName string, volumeOpts core.VolumeOpts) error {
+	return d.CreateContext(context.Background(), volumeName, volumeOpts)
+}
+
+// CreateContext is Create with a context.
+func (d *driver) CreateContext(
+	ctx context.Context,
+	volumeName string, volumeOpts core.VolumeOpts) error {
 	log.WithFields(log.Fields{
 		"volumeName": volumeName,
 		"volumeOpts": volumeOpts,
@@ -297,31 +464,80 @@ func (d *driver) Create(volumeName string, volumeOpts core.VolumeOpts) error {
 
 	var err error
 
-	if err = d.createGetInstance(); err != nil {
+	if err = d.createGetInstance(ctx); err != nil {
 		return err
 	}
 
+	labels := createInitLabels(volumeOpts)
+
 	for k, v := range volumeOpts {
 		volumeOpts[strings.ToLower(k)] = v
 	}
+	if err = core.CheckVolumeOpts(d.r.Storage, volumeOpts); err != nil {
+		return err
+	}
 	newFsType := volumeOpts["newfstype"]
 
+	mode, err := createInitMode(volumeOpts)
+	if err != nil {
+		return err
+	}
+	if mode == core.VolumeModeBlock {
+		labels[core.VolumeModeLabel] = mode
+	}
+
+	accessMode, err := core.ParseAccessMode(volumeOpts["accessmode"])
+	if err != nil {
+		return err
+	}
+	if mode == core.VolumeModeBlock && accessMode.ReadOnly() {
+		return goof.WithField("accessMode", accessMode,
+			"access mode is not supported by volumes with the block mode")
+	}
+	if volumeOpts["accessmode"] != "" {
+		labels[core.VolumeAccessModeLabel] = string(accessMode)
+	}
+
+	// a volume is formatted when it is created even if it is later mounted
+	// read-only
+	if accessMode.Many() {
+		ctx = core.WithAccessMode(ctx, core.AccessModeReadWriteMany)
+	} else {
+		ctx = core.WithAccessMode(ctx, core.AccessModeReadWriteSingle)
+	}
+
+	if encrypted, _ := strconv.ParseBool(volumeOpts["encrypted"]); encrypted {
+		labels[core.VolumeEncryptedLabel] = "true"
+		ctx = core.WithEncryption(ctx, true)
+	}
+
+	fsOpts := volumeOpts["fsopts"]
+	if fsOpts != "" {
+		ctx = core.WithFormatOptions(ctx, &core.FormatOptions{Args: fsOpts})
+	}
+	if mountOpts := volumeOpts["mountopts"]; mountOpts != "" {
+		labels[core.VolumeMountOptionsLabel] = strings.Replace(
+			mountOpts, ",", mountOptsLabelSep, -1)
+		ctx = core.WithMountOptions(ctx, mountOpts)
+	}
+
 	var overwriteFs bool
 	var volumes []*core.Volume
 
-	volumes, overwriteFs, err = d.createGetVolumes(volumeName, volumeOpts)
+	volumes, overwriteFs, err = d.createGetVolumes(
+		ctx, volumeName, volumeOpts)
 	if err != nil {
 		return err
 	}
 
 	if len(volumes) > 0 {
-		return nil
+		return d.createExpandVolume(ctx, volumes[0], volumeOpts)
 	}
 
 	var volFrom *core.Volume
 	var volumeID string
 	if volFrom, err = d.createInitVolume(
-		volumeName, volumeOpts); err != nil {
+		ctx, volumeName, volumeOpts); err != nil {
 		return err
 	} else if volFrom != nil {
 		volumeID = volFrom.VolumeID
@@ -329,7 +545,7 @@ func (d *driver) Create(volumeName string, volumeOpts core.VolumeOpts) error {
 
 	var snapFrom *core.Snapshot
 	var snapshotID string
-	if snapFrom, err = d.createGetSnapshot(volumeOpts); err != nil {
+	if snapFrom, err = d.createGetSnapshot(ctx, volumeOpts); err != nil {
 		return err
 	} else if snapFrom != nil {
 		snapshotID = snapFrom.SnapshotID
@@ -341,15 +557,18 @@ func (d *driver) Create(volumeName string, volumeOpts core.VolumeOpts) error {
 	availabilityZone := createInitAvailabilityZone(volumeOpts)
 
 	if len(volumes) == 0 {
-		if _, err = d.r.Storage.CreateVolume(
+		if _, err = d.r.Storage.CreateVolumeContext(
+			core.WithLabels(ctx, labels),
 			false, volumeName, volumeID, snapshotID,
 			volumeType, IOPS, size, availabilityZone); err != nil {
 			return err
 		}
 	}
 
-	if newFsType != "" || overwriteFs {
-		_, err = d.Mount(volumeName, "", overwriteFs, newFsType, false)
+	if mode == core.VolumeModeFilesystem &&
+		(newFsType != "" || overwriteFs || fsOpts != "") {
+		_, err = d.MountContext(
+			ctx, volumeName, "", overwriteFs, newFsType, false)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"volumeName":  volumeName,
@@ -357,7 +576,7 @@ func (d *driver) Create(volumeName string, volumeOpts core.VolumeOpts) error {
 				"newFsType":   newFsType,
 				"driverName":  d.Name()}).Error("Failed to create or mount file system")
 		}
-		err = d.Unmount(volumeName, "")
+		err = d.UnmountContext(ctx, volumeName, "")
 		if err != nil {
 			return err
 		}
@@ -366,7 +585,40 @@ func (d *driver) Create(volumeName string, volumeOpts core.VolumeOpts) error {
 	return nil
 }
 
+// createInitMode returns the volume mode provided with the volume options. A
+// volume with the block mode has no filesystem, so it may not be created with
+// the options that create, encrypt, or mount one.
+func createInitMode(volumeOpts core.VolumeOpts) (string, error) {
+	mode, err := core.ParseVolumeMode(volumeOpts["mode"])
+	if err != nil || mode != core.VolumeModeBlock {
+		return mode, err
+	}
+	for _, k := range []string{
+		"newfstype", "overwritefs", "fsopts", "mountopts", "encrypted"} {
+		if volumeOpts[k] != "" {
+			return "", goof.WithField("option", k,
+				"option is not supported by volumes with the block mode")
+		}
+	}
+	return mode, nil
+}
+
+// createInitLabels returns the labels provided with the volume options in
+// the form label.key=value. The keys are extracted before the options are
+// lower-cased so that the labels' keys retain their case.
+func createInitLabels(volumeOpts core.VolumeOpts) map[string]string {
+	labels := map[string]string{}
+	for k, v := range volumeOpts {
+		if len(k) > len(labelOptPrefix) &&
+			strings.EqualFold(k[:len(labelOptPrefix)], labelOptPrefix) {
+			labels[k[len(labelOptPrefix):]] = v
+		}
+	}
+	return labels
+}
+
 func (d *driver) createInitVolume(
+	ctx context.Context,
 	volumeName string,
 	volumeOpts core.VolumeOpts) (*core.Volume, error) {
 
@@ -382,7 +634,8 @@ func (d *driver) createInitVolume(
 
 	var err error
 	var volumes []*core.Volume
-	if volumes, err = d.r.Storage.GetVolume(optVolumeID, optVolumeName); err != nil {
+	if volumes, err = d.r.Storage.GetVolumeContext(
+		ctx, optVolumeID, optVolumeName); err != nil {
 		return nil, err
 	}
 
@@ -399,6 +652,7 @@ func (d *driver) createInitVolume(
 }
 
 func (d *driver) createGetSnapshot(
+	ctx context.Context,
 	volumeOpts core.VolumeOpts) (*core.Snapshot, error) {
 
 	var optSnapshotName string
@@ -414,8 +668,8 @@ func (d *driver) createGetSnapshot(
 	var err error
 	var snapshots []*core.Snapshot
 
-	if snapshots, err = d.r.Storage.GetSnapshot(
-		"", optSnapshotID, optSnapshotName); err != nil {
+	if snapshots, err = d.r.Storage.GetSnapshotContext(
+		ctx, "", optSnapshotID, optSnapshotName); err != nil {
 		return nil, err
 	}
 
@@ -431,32 +685,30 @@ func (d *driver) createGetSnapshot(
 	return snapshots[0], nil
 }
 
-func (d *driver) createGetInstance() error {
+func (d *driver) createGetInstance(ctx context.Context) error {
 	var err error
 	var instances []*core.Instance
 
-	if instances, err = d.r.Storage.GetInstances(); err != nil {
+	if instances, err = d.r.Storage.GetInstancesContext(ctx); err != nil {
 		return err
 	}
 
-	switch {
-	case len(instances) == 0:
+	if len(instances) == 0 {
 		return goof.New("No instances")
-	case len(instances) > 1:
-		return goof.New(
-			"Too many instances returned, limit the storagedrivers")
 	}
 
 	return nil
 }
 
 func (d *driver) createGetVolumes(
+	ctx context.Context,
 	volumeName string,
 	volumeOpts core.VolumeOpts) ([]*core.Volume, bool, error) {
 	var err error
 	var volumes []*core.Volume
 
-	if volumes, err = d.r.Storage.GetVolume("", volumeName); err != nil {
+	if volumes, err = d.r.Storage.GetVolumeContext(
+		ctx, "", volumeName); err != nil {
 		return nil, false, err
 	}
 
@@ -497,6 +749,30 @@ func createInitIOPS(volumeOpts core.VolumeOpts, volume *core.Volume) int64 {
 	return 0
 }
 
+// createExpandVolume expands an existing volume when the create request
+// includes the expand=true option and a size larger than the volume's.
+func (d *driver) createExpandVolume(
+	ctx context.Context,
+	volume *core.Volume, volumeOpts core.VolumeOpts) error {
+
+	if expand, _ := strconv.ParseBool(volumeOpts["expand"]); !expand {
+		return nil
+	}
+
+	size, err := strconv.ParseInt(volumeOpts["size"], 10, 64)
+	if err != nil || size <= 0 {
+		return goof.WithField("size", volumeOpts["size"],
+			"expand requires a valid size")
+	}
+
+	currentSize, _ := strconv.ParseInt(volume.Size, 10, 64)
+	if size <= currentSize {
+		return nil
+	}
+
+	return d.r.Volume.ExpandContext(ctx, volume.Name, volume.VolumeID, size)
+}
+
 func createInitSize(volumeOpts core.VolumeOpts, volume *core.Volume, snapshot *core.Snapshot) int64 {
 	if ok, i := createInitInt64("size", "", volumeOpts); ok {
 		return i
@@ -557,6 +833,11 @@ func createInitString(optKey, envVar string,
 
 // Remove will remove a remote volume
 func (d *driver) Remove(volumeName string) error {
+	return d.RemoveContext(context.Background(), volumeName)
+}
+
+// RemoveContext is Remove with a context.
+func (d *driver) RemoveContext(ctx context.Context, volumeName string) error {
 	log.WithFields(log.Fields{
 		"volumeName": volumeName,
 		"driverName": d.Name()}).Info("removing volume")
@@ -565,19 +846,7 @@ func (d *driver) Remove(volumeName string) error {
 		return goof.New("Missing volume name")
 	}
 
-	instances, err := d.r.Storage.GetInstances()
-	if err != nil {
-		return err
-	}
-
-	switch {
-	case len(instances) == 0:
-		return goof.New("No instances")
-	case len(instances) > 1:
-		return goof.New("Too many instances returned, limit the storagedrivers")
-	}
-
-	volumes, err := d.r.Storage.GetVolume("", volumeName)
+	volumes, err := d.r.Storage.GetVolumeContext(ctx, "", volumeName)
 	if err != nil {
 		return err
 	}
@@ -589,12 +858,12 @@ func (d *driver) Remove(volumeName string) error {
 		return goof.New("Multiple volumes returned by name")
 	}
 
-	err = d.Unmount("", volumes[0].VolumeID)
+	err = d.UnmountContext(ctx, "", volumes[0].VolumeID)
 	if err != nil {
 		return err
 	}
 
-	err = d.r.Storage.RemoveVolume(volumes[0].VolumeID)
+	err = d.r.Storage.RemoveVolumeContext(ctx, volumes[0].VolumeID)
 	if err != nil {
 		return err
 	}
@@ -604,12 +873,20 @@ func (d *driver) Remove(volumeName string) error {
 
 // Attach will attach a volume to an instance
 func (d *driver) Attach(volumeName, instanceID string, force bool) (string, error) {
+	return d.AttachContext(
+		context.Background(), volumeName, instanceID, force)
+}
+
+// AttachContext is Attach with a context.
+func (d *driver) AttachContext(
+	ctx context.Context,
+	volumeName, instanceID string, force bool) (string, error) {
 	log.WithFields(log.Fields{
 		"volumeName": volumeName,
 		"instanceID": instanceID,
 		"driverName": d.Name()}).Info("attaching volume")
 
-	volumes, err := d.r.Storage.GetVolume("", volumeName)
+	volumes, err := d.r.Storage.GetVolumeContext(ctx, "", volumeName)
 	if err != nil {
 		return "", err
 	}
@@ -621,12 +898,13 @@ func (d *driver) Attach(volumeName, instanceID string, force bool) (string, erro
 		return "", goof.New("Multiple volumes returned by name")
 	}
 
-	_, err = d.r.Storage.AttachVolume(true, volumes[0].VolumeID, instanceID, force)
+	_, err = d.r.Storage.AttachVolumeContext(
+		ctx, true, volumes[0].VolumeID, instanceID, force)
 	if err != nil {
 		return "", err
 	}
 
-	volumes, err = d.r.Storage.GetVolume("", volumeName)
+	volumes, err = d.r.Storage.GetVolumeContext(ctx, "", volumeName)
 	if err != nil {
 		return "", err
 	}
@@ -636,27 +914,43 @@ func (d *driver) Attach(volumeName, instanceID string, force bool) (string, erro
 
 // Remove will remove a remote volume
 func (d *driver) Detach(volumeName, instanceID string, force bool) error {
+	return d.DetachContext(
+		context.Background(), volumeName, instanceID, force)
+}
+
+// DetachContext is Detach with a context.
+func (d *driver) DetachContext(
+	ctx context.Context,
+	volumeName, instanceID string, force bool) error {
 	log.WithFields(log.Fields{
 		"volumeName": volumeName,
 		"instanceID": instanceID,
 		"driverName": d.Name()}).Info("detaching volume")
 
-	volume, err := d.r.Storage.GetVolume("", volumeName)
+	volume, err := d.r.Storage.GetVolumeContext(ctx, "", volumeName)
 	if err != nil {
 		return err
 	}
 
-	return d.r.Storage.DetachVolume(true, volume[0].VolumeID, instanceID, force)
+	return d.r.Storage.DetachVolumeContext(
+		ctx, true, volume[0].VolumeID, instanceID, force)
 }
 
 // NetworkName will return relevant information about how a volume can be discovered on an OS
 func (d *driver) NetworkName(volumeName, instanceID string) (string, error) {
+	return d.NetworkNameContext(
+		context.Background(), volumeName, instanceID)
+}
+
+// NetworkNameContext is NetworkName with a context.
+func (d *driver) NetworkNameContext(
+	ctx context.Context, volumeName, instanceID string) (string, error) {
 	log.WithFields(log.Fields{
 		"volumeName": volumeName,
 		"instanceID": instanceID,
 		"driverName": d.Name()}).Info("returning network name")
 
-	volumes, err := d.r.Storage.GetVolume("", volumeName)
+	volumes, err := d.r.Storage.GetVolumeContext(ctx, "", volumeName)
 	if err != nil {
 		return "", err
 	}
@@ -668,8 +962,8 @@ func (d *driver) NetworkName(volumeName, instanceID string) (string, error) {
 		return "", goof.New("Multiple volumes returned by name")
 	}
 
-	volumeAttachment, err := d.r.Storage.GetVolumeAttach(
-		volumes[0].VolumeID, instanceID)
+	volumeAttachment, err := d.r.Storage.GetVolumeAttachContext(
+		ctx, volumes[0].VolumeID, instanceID)
 	if err != nil {
 		return "", err
 	}
@@ -678,7 +972,7 @@ func (d *driver) NetworkName(volumeName, instanceID string) (string, error) {
 		return "", goof.New("Volume not attached")
 	}
 
-	volumes, err = d.r.Storage.GetVolume("", volumeName)
+	volumes, err = d.r.Storage.GetVolumeContext(ctx, "", volumeName)
 	if err != nil {
 		return "", err
 	}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	glog "github.com/akutz/golf/logrus"
 	"github.com/akutz/gotil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"golang.org/x/net/context"
 	"gopkg.in/yaml.v1"
 
 	"github.com/emccode/rexray/core"
@@ -70,6 +72,16 @@ type CLI struct {
 	volumeMountCmd           *cobra.Command
 	volumeUnmountCmd         *cobra.Command
 	volumePathCmd            *cobra.Command
+	volumeExpandCmd          *cobra.Command
+	volumeStatsCmd           *cobra.Command
+	taskCmd                  *cobra.Command
+	taskGetCmd               *cobra.Command
+	taskWaitCmd              *cobra.Command
+	auditCmd                 *cobra.Command
+	snapshotGroupCmd         *cobra.Command
+	snapshotGroupCreateCmd   *cobra.Command
+	snapshotGroupGetCmd      *cobra.Command
+	snapshotGroupRestoreCmd  *cobra.Command
 
 	outputFormat            string
 	client                  string
@@ -100,6 +112,28 @@ type CLI struct {
 	moduleInstanceAddress   string
 	moduleInstanceStart     bool
 	moduleConfig            []string
+	provider                string
+	taskID                  string
+	taskTimeout             time.Duration
+	all                     bool
+	labels                  []string
+	volumeStatus            string
+	namePattern             string
+	limit                   int
+	token                   string
+	auditSince              time.Duration
+	auditCaller             string
+	auditOperation          string
+	auditDriver             string
+	auditFailed             bool
+	volumeIDs               []string
+	groupID                 string
+	volumeNameSuffix        string
+	quiesce                 bool
+	encrypted               bool
+	fsOpts                  string
+	mountOpts               string
+	accessMode              string
 }
 
 const (
@@ -153,6 +187,8 @@ func NewWithArgs(a ...string) *CLI {
 	c.initDeviceCmdsAndFlags()
 	c.initVolumeCmdsAndFlags()
 	c.initSnapshotCmdsAndFlags()
+	c.initTaskCmdsAndFlags()
+	c.initAuditCmdsAndFlags()
 
 	c.initServiceCmdsAndFlags()
 	c.initModuleCmdsAndFlags()
@@ -286,6 +322,7 @@ func (c *CLI) preRun(cmd *cobra.Command, args []string) {
 
 	if c.isInitDriverManagersCmd(cmd) {
 		if err := c.r.InitDrivers(); err != nil {
+			c.failTask(err)
 
 			if term.IsTerminal() {
 				printColorizedError(err)
@@ -378,6 +415,10 @@ func (c *CLI) isInitDriverManagersCmd(cmd *cobra.Command) bool {
 		cmd != c.adapterGetTypesCmd &&
 		cmd != c.versionCmd &&
 		cmd != c.envCmd &&
+		cmd != c.taskCmd &&
+		cmd != c.taskGetCmd &&
+		cmd != c.taskWaitCmd &&
+		cmd != c.auditCmd &&
 		c.isServiceCmd(cmd) &&
 		c.isModuleCmd(cmd)
 }
@@ -406,3 +447,35 @@ func (c *CLI) logLevel() string {
 func (c *CLI) host() string {
 	return c.r.Config.GetString("rexray.host")
 }
+
+// volumeLabels returns the labels provided with the --label flag.
+func (c *CLI) volumeLabels() map[string]string {
+	labels, err := core.ParseLabels(c.labels)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return labels
+}
+
+// storageContext returns a copy of the provided context that directs the
+// storage driver manager to the storage driver named by the --provider flag.
+func (c *CLI) storageContext(ctx context.Context) context.Context {
+	if c.provider == "" {
+		return ctx
+	}
+	c.storage()
+	return core.WithStorageDriver(ctx, c.provider)
+}
+
+// storage returns the storage driver named by the --provider flag or, if the
+// flag is not set, the storage driver manager.
+func (c *CLI) storage() core.StorageDriver {
+	if c.provider == "" {
+		return c.r.Storage
+	}
+	d, err := c.r.Storage.Driver(c.provider)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return d
+}
@@ -28,12 +28,15 @@ func (c *CLI) initAdapterCmds() {
 
 	c.adapterGetTypesCmd = &cobra.Command{
 		Use:     "types",
-		Short:   "List the available adapter types",
+		Short:   "List the available adapter types and their capabilities",
 		Aliases: []string{"ls", "list"},
 		Run: func(cmd *cobra.Command, args []string) {
-			for n := range c.r.DriverNames() {
-				fmt.Printf("Storage Driver: %v\n", n)
+			caps := c.r.StorageCapabilities()
+			out, err := c.marshalOutput(&caps)
+			if err != nil {
+				log.Fatal(err)
 			}
+			fmt.Println(out)
 		},
 	}
 	c.adapterCmd.AddCommand(c.adapterGetTypesCmd)
@@ -61,5 +64,6 @@ func (c *CLI) initAdapterCmds() {
 }
 
 func (c *CLI) initAdapterFlags() {
+	c.addOutputFormatFlag(c.adapterGetTypesCmd.Flags())
 	c.addOutputFormatFlag(c.adapterGetInstancesCmd.Flags())
 }
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/emccode/rexray/core"
+)
+
+func (c *CLI) initAuditCmdsAndFlags() {
+	c.initAuditCmds()
+	c.initAuditFlags()
+}
+
+func (c *CLI) initAuditCmds() {
+
+	c.auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Query the audit log of operations that change storage",
+		Run: func(cmd *cobra.Command, args []string) {
+			if isHelpFlags(cmd) {
+				cmd.Usage()
+				return
+			}
+
+			query := &core.AuditQuery{
+				Caller:    c.auditCaller,
+				Operation: c.auditOperation,
+				Driver:    c.auditDriver,
+				VolumeID:  c.volumeID,
+				Failed:    c.auditFailed,
+				Limit:     c.limit,
+			}
+			if c.auditSince > 0 {
+				query.Since = time.Now().Add(-c.auditSince)
+			}
+
+			records, err := c.r.ReadAudit(query)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if len(records) > 0 {
+				out, err := c.marshalOutput(&records)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(out)
+			}
+		},
+	}
+	c.c.AddCommand(c.auditCmd)
+}
+
+func (c *CLI) initAuditFlags() {
+	c.auditCmd.Flags().DurationVar(&c.auditSince, "since", 0,
+		"Only return records of operations started within this duration")
+	c.auditCmd.Flags().StringVar(&c.auditCaller, "caller", "",
+		"Only return records of operations requested by this caller")
+	c.auditCmd.Flags().StringVar(&c.auditOperation, "operation", "",
+		"Only return records of this operation")
+	c.auditCmd.Flags().StringVar(&c.auditDriver, "driver", "",
+		"Only return records of operations performed by this driver")
+	c.auditCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
+	c.auditCmd.Flags().BoolVar(&c.auditFailed, "failed", false,
+		"Only return records of failed operations")
+	c.auditCmd.Flags().IntVar(&c.limit, "limit", 0,
+		"The maximum number of the most recent records to return")
+
+	c.addOutputFormatFlag(c.auditCmd.Flags())
+}
@@ -5,6 +5,9 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
 )
 
 func (c *CLI) initSnapshotCmdsAndFlags() {
@@ -60,8 +63,17 @@ func (c *CLI) initSnapshotCmds() {
 				log.Fatalf("missing --volumeid")
 			}
 
-			snapshot, err := c.r.Storage.CreateSnapshot(
-				c.runAsync, c.snapshotName, c.volumeID, c.description)
+			labels := c.volumeLabels()
+
+			snapshot, err := c.runTask("CreateSnapshot",
+				func(ctx context.Context) (interface{}, error) {
+					ctx = core.WithLabels(ctx, labels)
+					if cmd.Flags().Changed("quiesce") {
+						ctx = core.WithQuiesce(ctx, c.quiesce)
+					}
+					return c.r.Storage.CreateSnapshotContext(
+						ctx, false, c.snapshotName, c.volumeID, c.description)
+				})
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -104,9 +116,12 @@ func (c *CLI) initSnapshotCmds() {
 				log.Fatalf("missing --volumeid or --snapshotid or --volumename")
 			}
 
-			snapshot, err := c.r.Storage.CopySnapshot(
-				c.runAsync, c.volumeID, c.snapshotID,
-				c.snapshotName, c.destinationSnapshotName, c.destinationRegion)
+			snapshot, err := c.runTask("CopySnapshot",
+				func(ctx context.Context) (interface{}, error) {
+					return c.r.Storage.CopySnapshotContext(
+						ctx, false, c.volumeID, c.snapshotID, c.snapshotName,
+						c.destinationSnapshotName, c.destinationRegion)
+				})
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -119,6 +134,103 @@ func (c *CLI) initSnapshotCmds() {
 		},
 	}
 	c.snapshotCmd.AddCommand(c.snapshotCopyCmd)
+
+	c.snapshotGroupCmd = &cobra.Command{
+		Use:   "group",
+		Short: "Manage snapshots of several volumes taken at the same time",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+	c.snapshotCmd.AddCommand(c.snapshotGroupCmd)
+
+	c.snapshotGroupCreateCmd = &cobra.Command{
+		Use:     "create",
+		Short:   "Snapshot several volumes at the same point in time",
+		Aliases: []string{"new"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			if len(c.volumeIDs) == 0 {
+				log.Fatalf("missing --volumeid")
+			}
+
+			snapshotNames := map[string]string{}
+			for _, volumeID := range c.volumeIDs {
+				snapshotNames[volumeID] = c.snapshotName
+			}
+			labels := c.volumeLabels()
+
+			snapshots, err := c.runTask("CreateSnapshotGroup",
+				func(ctx context.Context) (interface{}, error) {
+					return c.r.Storage.CreateSnapshotGroupContext(
+						core.WithLabels(ctx, labels),
+						false, snapshotNames, c.description)
+				})
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			out, err := c.marshalOutput(&snapshots)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(out)
+		},
+	}
+	c.snapshotGroupCmd.AddCommand(c.snapshotGroupCreateCmd)
+
+	c.snapshotGroupGetCmd = &cobra.Command{
+		Use:     "get",
+		Short:   "Get the snapshots in a snapshot group",
+		Aliases: []string{"ls", "list"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			if c.groupID == "" {
+				log.Fatalf("missing --groupid")
+			}
+
+			snapshots, err := c.r.Storage.GetSnapshotGroup(c.groupID)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if len(snapshots) > 0 {
+				out, err := c.marshalOutput(&snapshots)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(out)
+			}
+		},
+	}
+	c.snapshotGroupCmd.AddCommand(c.snapshotGroupGetCmd)
+
+	c.snapshotGroupRestoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Create a volume from each snapshot in a snapshot group",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			if c.groupID == "" {
+				log.Fatalf("missing --groupid")
+			}
+
+			volumes, err := c.runTask("RestoreSnapshotGroup",
+				func(ctx context.Context) (interface{}, error) {
+					return c.r.Storage.RestoreSnapshotGroupContext(
+						ctx, false, c.groupID, c.volumeNameSuffix)
+				})
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			out, err := c.marshalOutput(&volumes)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(out)
+		},
+	}
+	c.snapshotGroupCmd.AddCommand(c.snapshotGroupRestoreCmd)
 }
 
 func (c *CLI) initSnapshotFlags() {
@@ -129,6 +241,10 @@ func (c *CLI) initSnapshotFlags() {
 	c.snapshotCreateCmd.Flags().StringVar(&c.snapshotName, "snapshotname", "", "snapshotname")
 	c.snapshotCreateCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
 	c.snapshotCreateCmd.Flags().StringVar(&c.description, "description", "", "description")
+	c.snapshotCreateCmd.Flags().StringSliceVar(&c.labels, "label", nil,
+		"A label for the snapshot, in the form key=value")
+	c.snapshotCreateCmd.Flags().BoolVar(&c.quiesce, "quiesce", false,
+		"Freeze the volume's mounted filesystem while it is snapshotted")
 	c.snapshotRemoveCmd.Flags().StringVar(&c.snapshotID, "snapshotid", "", "snapshotid")
 	c.snapshotCopyCmd.Flags().BoolVar(&c.runAsync, "runasync", false, "runasync")
 	c.snapshotCopyCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
@@ -137,8 +253,24 @@ func (c *CLI) initSnapshotFlags() {
 	c.snapshotCopyCmd.Flags().StringVar(&c.destinationSnapshotName, "destinationsnapshotname", "", "destinationsnapshotname")
 	c.snapshotCopyCmd.Flags().StringVar(&c.destinationRegion, "destinationregion", "", "destinationregion")
 
+	c.snapshotGroupCreateCmd.Flags().BoolVar(&c.runAsync, "runasync", false, "runasync")
+	c.snapshotGroupCreateCmd.Flags().StringSliceVar(&c.volumeIDs, "volumeid", nil,
+		"The ID of a volume to snapshot")
+	c.snapshotGroupCreateCmd.Flags().StringVar(&c.snapshotName, "snapshotname", "", "snapshotname")
+	c.snapshotGroupCreateCmd.Flags().StringVar(&c.description, "description", "", "description")
+	c.snapshotGroupCreateCmd.Flags().StringSliceVar(&c.labels, "label", nil,
+		"A label for the snapshots, in the form key=value")
+	c.snapshotGroupGetCmd.Flags().StringVar(&c.groupID, "groupid", "", "groupid")
+	c.snapshotGroupRestoreCmd.Flags().BoolVar(&c.runAsync, "runasync", false, "runasync")
+	c.snapshotGroupRestoreCmd.Flags().StringVar(&c.groupID, "groupid", "", "groupid")
+	c.snapshotGroupRestoreCmd.Flags().StringVar(&c.volumeNameSuffix, "suffix", "",
+		"A suffix for the names of the restored volumes")
+
 	c.addOutputFormatFlag(c.snapshotCmd.Flags())
 	c.addOutputFormatFlag(c.snapshotGetCmd.Flags())
 	c.addOutputFormatFlag(c.snapshotCopyCmd.Flags())
 	c.addOutputFormatFlag(c.snapshotCreateCmd.Flags())
+	c.addOutputFormatFlag(c.snapshotGroupCreateCmd.Flags())
+	c.addOutputFormatFlag(c.snapshotGroupGetCmd.Flags())
+	c.addOutputFormatFlag(c.snapshotGroupRestoreCmd.Flags())
 }
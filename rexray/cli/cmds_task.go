@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/akutz/gotil"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+)
+
+// taskIDEnvVar is the environment variable used to hand a queued task to the
+// background REX-Ray process that runs it.
+const taskIDEnvVar = "REXRAY_TASK_ID"
+
+func (c *CLI) initTaskCmdsAndFlags() {
+	c.initTaskCmds()
+	c.initTaskFlags()
+}
+
+func (c *CLI) initTaskCmds() {
+
+	c.taskCmd = &cobra.Command{
+		Use:   "task",
+		Short: "The task manager",
+		Run: func(cmd *cobra.Command, args []string) {
+			if isHelpFlags(cmd) {
+				cmd.Usage()
+			} else {
+				c.taskGetCmd.Run(c.taskGetCmd, args)
+			}
+		},
+	}
+	c.c.AddCommand(c.taskCmd)
+
+	c.taskGetCmd = &cobra.Command{
+		Use:     "get",
+		Short:   "Get one or more tasks",
+		Aliases: []string{"ls", "list"},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			if c.taskID != "" {
+				task, err := c.r.Tasks.Get(c.taskID)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				out, err := c.marshalOutput(&task)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(out)
+				return
+			}
+
+			allTasks, err := c.r.Tasks.List()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if len(allTasks) > 0 {
+				out, err := c.marshalOutput(&allTasks)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(out)
+			}
+		},
+	}
+	c.taskCmd.AddCommand(c.taskGetCmd)
+
+	c.taskWaitCmd = &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for a task to complete",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			if c.taskID == "" {
+				log.Fatalf("missing --taskid")
+			}
+
+			ctx := context.Background()
+			if c.taskTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.taskTimeout)
+				defer cancel()
+			}
+
+			task, err := c.r.Tasks.Wait(ctx, c.taskID)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			out, err := c.marshalOutput(&task)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(out)
+
+			if task.State == core.TaskStateFailed {
+				panic(&printedErrorPanic{})
+			}
+		},
+	}
+	c.taskCmd.AddCommand(c.taskWaitCmd)
+}
+
+func (c *CLI) initTaskFlags() {
+	c.taskGetCmd.Flags().StringVar(&c.taskID, "taskid", "", "taskid")
+	c.taskWaitCmd.Flags().StringVar(&c.taskID, "taskid", "", "taskid")
+	c.taskWaitCmd.Flags().DurationVar(&c.taskTimeout, "timeout", 0,
+		"The maximum amount of time to wait")
+
+	c.addOutputFormatFlag(c.taskCmd.Flags())
+	c.addOutputFormatFlag(c.taskGetCmd.Flags())
+	c.addOutputFormatFlag(c.taskWaitCmd.Flags())
+}
+
+// runTask runs an operation that may be requested with the --runasync flag.
+//
+// If the flag is set the operation is recorded as a queued task and the
+// current command is started again in the background to run it. The queued
+// task is returned immediately so its ID can be given to "rexray task wait".
+// The background process finds the task ID in its environment, runs the
+// operation synchronously, and records the result on the task.
+//
+// If the flag is not set the operation's result is returned.
+func (c *CLI) runTask(operation string, f core.TaskFunc) (interface{}, error) {
+
+	if taskID := os.Getenv(taskIDEnvVar); taskID != "" {
+		task, err := c.r.Tasks.Run(taskID, f)
+		if err != nil {
+			return nil, err
+		}
+		return task, nil
+	}
+
+	if !c.runAsync {
+		return f(context.Background())
+	}
+
+	task, err := c.r.Tasks.Create(operation)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, thisAbsPath := gotil.GetThisPathParts()
+	cmd := exec.Command(thisAbsPath, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", taskIDEnvVar, task.ID))
+
+	log.WithFields(log.Fields{
+		"taskID":    task.ID,
+		"operation": operation}).Debug("starting task in background")
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// failTask records the error on the queued task that this process was started
+// to run, if any, so that the task does not remain queued when the process
+// fails before the task's operation is run.
+func (c *CLI) failTask(err error) {
+	taskID := os.Getenv(taskIDEnvVar)
+	if taskID == "" {
+		return
+	}
+	c.r.Tasks.Run(taskID, func(ctx context.Context) (interface{}, error) {
+		return nil, err
+	})
+}
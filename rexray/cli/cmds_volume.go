@@ -5,6 +5,9 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
 )
 
 func (c *CLI) initVolumeCmdsAndFlags() {
@@ -54,12 +57,34 @@ func (c *CLI) initVolumeCmds() {
 		Aliases: []string{"ls", "list"},
 		Run: func(cmd *cobra.Command, args []string) {
 
-			allVolumes, err := c.r.Storage.GetVolume(c.volumeID, c.volumeName)
+			query := &core.VolumeQuery{
+				VolumeID:         c.volumeID,
+				VolumeName:       c.volumeName,
+				NamePattern:      c.namePattern,
+				Status:           c.volumeStatus,
+				AvailabilityZone: c.availabilityZone,
+				VolumeType:       c.volumeType,
+				InstanceID:       c.instanceID,
+				Labels:           c.volumeLabels(),
+				Limit:            c.limit,
+				Token:            c.token,
+			}
+			result, err := c.r.Storage.QueryVolumesContext(
+				c.storageContext(context.Background()), query)
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			if len(allVolumes) > 0 {
+			if c.limit > 0 || c.token != "" {
+				out, err := c.marshalOutput(&result)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(out)
+				return
+			}
+
+			if allVolumes := result.Volumes; len(allVolumes) > 0 {
 				out, err := c.marshalOutput(&allVolumes)
 				if err != nil {
 					log.Fatal(err)
@@ -80,9 +105,19 @@ func (c *CLI) initVolumeCmds() {
 				log.Fatalf("missing --size")
 			}
 
-			volume, err := c.r.Storage.CreateVolume(
-				c.runAsync, c.volumeName, c.volumeID, c.snapshotID,
-				c.volumeType, c.iops, c.size, c.availabilityZone)
+			if err := core.CheckCreateVolume(c.storage(),
+				c.volumeType, c.iops, c.availabilityZone); err != nil {
+				log.Fatal(err)
+			}
+			labels := c.volumeLabels()
+
+			volume, err := c.runTask("CreateVolume",
+				func(ctx context.Context) (interface{}, error) {
+					ctx = core.WithLabels(c.storageContext(ctx), labels)
+					return c.r.Storage.CreateVolumeContext(ctx, false,
+						c.volumeName, c.volumeID, c.snapshotID,
+						c.volumeType, c.iops, c.size, c.availabilityZone)
+				})
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -125,8 +160,17 @@ func (c *CLI) initVolumeCmds() {
 				log.Fatalf("missing --volumeid")
 			}
 
-			volumeAttachment, err := c.r.Storage.AttachVolume(
-				c.runAsync, c.volumeID, c.instanceID, c.force)
+			mode, err := core.ParseAccessMode(c.accessMode)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			volumeAttachment, err := c.runTask("AttachVolume",
+				func(ctx context.Context) (interface{}, error) {
+					return c.r.Storage.AttachVolumeContext(
+						core.WithAccessMode(ctx, mode),
+						false, c.volumeID, c.instanceID, c.force)
+				})
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -146,16 +190,36 @@ func (c *CLI) initVolumeCmds() {
 		Short: "Detach a volume",
 		Run: func(cmd *cobra.Command, args []string) {
 
+			if c.all {
+				results, err := c.r.Volume.DetachAll(c.instanceID)
+				if err != nil {
+					log.Fatal(err)
+				}
+				c.printVolumeResults(results)
+				return
+			}
+
 			if c.volumeID == "" {
 				log.Fatalf("missing --volumeid")
 			}
 
-			err := c.r.Storage.DetachVolume(
-				c.runAsync, c.volumeID, c.instanceID, c.force)
+			task, err := c.runTask("DetachVolume",
+				func(ctx context.Context) (interface{}, error) {
+					return nil, c.r.Storage.DetachVolumeContext(
+						ctx, false, c.volumeID, c.instanceID, c.force)
+				})
 			if err != nil {
 				log.Fatal(err)
 			}
 
+			if task != nil {
+				out, err := c.marshalOutput(&task)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(out)
+			}
+
 		},
 	}
 	c.volumeCmd.AddCommand(c.volumeDetachCmd)
@@ -168,7 +232,25 @@ func (c *CLI) initVolumeCmds() {
 				log.Fatal("Missing --volumename or --volumeid")
 			}
 
-			mountPath, err := c.r.Volume.Mount(
+			ctx := context.Background()
+			if cmd.Flags().Changed("encrypted") {
+				ctx = core.WithEncryption(ctx, c.encrypted)
+			}
+			if c.fsOpts != "" {
+				ctx = core.WithFormatOptions(
+					ctx, &core.FormatOptions{Args: c.fsOpts})
+			}
+			if c.mountOpts != "" {
+				ctx = core.WithMountOptions(ctx, c.mountOpts)
+			}
+			if c.accessMode != "" {
+				mode, err := core.ParseAccessMode(c.accessMode)
+				if err != nil {
+					log.Fatal(err)
+				}
+				ctx = core.WithAccessMode(ctx, mode)
+			}
+			mountPath, err := c.r.Volume.MountContext(ctx,
 				c.volumeName, c.volumeID, c.overwriteFs, c.fsType, false)
 			if err != nil {
 				log.Fatal(err)
@@ -189,6 +271,15 @@ func (c *CLI) initVolumeCmds() {
 		Short: "Unmount a volume",
 		Run: func(cmd *cobra.Command, args []string) {
 
+			if c.all {
+				results, err := c.r.Volume.UnmountAll()
+				if err != nil {
+					log.Fatal(err)
+				}
+				c.printVolumeResults(results)
+				return
+			}
+
 			if c.volumeName == "" && c.volumeID == "" {
 				log.Fatal("Missing --volumename or --volumeid")
 			}
@@ -225,11 +316,71 @@ func (c *CLI) initVolumeCmds() {
 		},
 	}
 	c.volumeCmd.AddCommand(c.volumePathCmd)
+
+	c.volumeExpandCmd = &cobra.Command{
+		Use:   "expand",
+		Short: "Expand a volume and the filesystem on it",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			if c.volumeName == "" && c.volumeID == "" {
+				log.Fatal("Missing --volumename or --volumeid")
+			}
+
+			if c.size == 0 {
+				log.Fatal("Missing --size")
+			}
+
+			err := c.r.Volume.Expand(c.volumeName, c.volumeID, c.size)
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	c.volumeCmd.AddCommand(c.volumeExpandCmd)
+
+	c.volumeStatsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Get the usage statistics of the mounted volumes",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			stats, err := c.r.Volume.StatsContext(
+				context.Background(), c.volumeName, c.volumeID)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if len(stats) > 0 {
+				out, err := c.marshalOutput(&stats)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(out)
+			}
+		},
+	}
+	c.volumeCmd.AddCommand(c.volumeStatsCmd)
 }
 
 func (c *CLI) initVolumeFlags() {
 	c.volumeGetCmd.Flags().StringVar(&c.volumeName, "volumename", "", "volumename")
 	c.volumeGetCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
+	c.volumeGetCmd.Flags().StringVar(&c.provider, "provider", "", "provider")
+	c.volumeGetCmd.Flags().StringSliceVar(&c.labels, "label", nil,
+		"Get only the volumes with the label, in the form key=value")
+	c.volumeGetCmd.Flags().StringVar(&c.namePattern, "namepattern", "",
+		"Get only the volumes with names that match the pattern, ex. db-*")
+	c.volumeGetCmd.Flags().StringVar(&c.volumeStatus, "status", "",
+		"Get only the volumes with the status")
+	c.volumeGetCmd.Flags().StringVar(&c.availabilityZone, "availabilityzone", "",
+		"Get only the volumes in the availability zone")
+	c.volumeGetCmd.Flags().StringVar(&c.volumeType, "volumetype", "",
+		"Get only the volumes of the volume type")
+	c.volumeGetCmd.Flags().StringVar(&c.instanceID, "instanceid", "",
+		"Get only the volumes attached to the instance")
+	c.volumeGetCmd.Flags().IntVar(&c.limit, "limit", 0,
+		"The maximum number of volumes to get")
+	c.volumeGetCmd.Flags().StringVar(&c.token, "token", "",
+		"The token returned with the previous page of volumes")
 	c.volumeCreateCmd.Flags().BoolVar(&c.runAsync, "runasync", false, "runasync")
 	c.volumeCreateCmd.Flags().StringVar(&c.volumeName, "volumename", "", "volumename")
 	c.volumeCreateCmd.Flags().StringVar(&c.volumeType, "volumetype", "", "volumetype")
@@ -238,23 +389,46 @@ func (c *CLI) initVolumeFlags() {
 	c.volumeCreateCmd.Flags().Int64Var(&c.iops, "iops", 0, "IOPS")
 	c.volumeCreateCmd.Flags().Int64Var(&c.size, "size", 0, "size")
 	c.volumeCreateCmd.Flags().StringVar(&c.availabilityZone, "availabilityzone", "", "availabilityzone")
+	c.volumeCreateCmd.Flags().StringVar(&c.provider, "provider", "", "provider")
+	c.volumeCreateCmd.Flags().StringSliceVar(&c.labels, "label", nil,
+		"A label for the volume, in the form key=value")
 	c.volumeRemoveCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
 	c.volumeAttachCmd.Flags().BoolVar(&c.runAsync, "runasync", false, "runasync")
 	c.volumeAttachCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
 	c.volumeAttachCmd.Flags().StringVar(&c.instanceID, "instanceid", "", "instanceid")
 	c.volumeAttachCmd.Flags().BoolVar(&c.force, "force", false, "force")
+	c.volumeAttachCmd.Flags().StringVar(&c.accessMode, "accessmode", "",
+		"The access mode: rw, ro, ro-many, or rw-many")
 	c.volumeDetachCmd.Flags().BoolVar(&c.runAsync, "runasync", false, "runasync")
 	c.volumeDetachCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
 	c.volumeDetachCmd.Flags().StringVar(&c.instanceID, "instanceid", "", "instanceid")
 	c.volumeDetachCmd.Flags().BoolVar(&c.force, "force", false, "force")
+	c.volumeDetachCmd.Flags().BoolVar(&c.all, "all", false,
+		"Detach all of the volumes mounted by REX-Ray or attached to --instanceid")
 	c.volumeMountCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
 	c.volumeMountCmd.Flags().StringVar(&c.volumeName, "volumename", "", "volumename")
 	c.volumeMountCmd.Flags().BoolVar(&c.overwriteFs, "overwritefs", false, "overwritefs")
 	c.volumeMountCmd.Flags().StringVar(&c.fsType, "fstype", "", "fstype")
+	c.volumeMountCmd.Flags().BoolVar(&c.encrypted, "encrypted", false,
+		"Encrypt the volume's device with LUKS, or decrypt it if it is encrypted")
+	c.volumeMountCmd.Flags().StringVar(&c.fsOpts, "fsopts", "",
+		"Additional arguments for mkfs if the volume is formatted")
+	c.volumeMountCmd.Flags().StringVar(&c.mountOpts, "mountopts", "",
+		"Comma-separated options with which the filesystem is mounted")
+	c.volumeMountCmd.Flags().StringVar(&c.accessMode, "accessmode", "",
+		"The access mode: rw, ro, ro-many, or rw-many")
 	c.volumeUnmountCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
 	c.volumeUnmountCmd.Flags().StringVar(&c.volumeName, "volumename", "", "volumename")
+	c.volumeUnmountCmd.Flags().BoolVar(&c.all, "all", false,
+		"Unmount all of the volumes mounted by REX-Ray")
 	c.volumePathCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
 	c.volumePathCmd.Flags().StringVar(&c.volumeName, "volumename", "", "volumename")
+	c.volumeExpandCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
+	c.volumeExpandCmd.Flags().StringVar(&c.volumeName, "volumename", "", "volumename")
+	c.volumeExpandCmd.Flags().Int64Var(&c.size, "size", 0,
+		"The new size of the volume in GB")
+	c.volumeStatsCmd.Flags().StringVar(&c.volumeID, "volumeid", "", "volumeid")
+	c.volumeStatsCmd.Flags().StringVar(&c.volumeName, "volumename", "", "volumename")
 
 	c.addOutputFormatFlag(c.volumeCmd.Flags())
 	c.addOutputFormatFlag(c.volumeGetCmd.Flags())
@@ -263,4 +437,25 @@ func (c *CLI) initVolumeFlags() {
 	c.addOutputFormatFlag(c.volumeMountCmd.Flags())
 	c.addOutputFormatFlag(c.volumePathCmd.Flags())
 	c.addOutputFormatFlag(c.volumeMapCmd.Flags())
+	c.addOutputFormatFlag(c.volumeDetachCmd.Flags())
+	c.addOutputFormatFlag(c.volumeUnmountCmd.Flags())
+	c.addOutputFormatFlag(c.volumeStatsCmd.Flags())
+}
+
+// printVolumeResults prints the results of a batch volume operation and exits
+// with an error if the operation failed for any of the volumes.
+func (c *CLI) printVolumeResults(results []*core.VolumeResult) {
+	if len(results) > 0 {
+		out, err := c.marshalOutput(&results)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(out)
+	}
+
+	for _, r := range results {
+		if r.Error != "" {
+			panic(&printedErrorPanic{})
+		}
+	}
 }
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/akutz/gofig"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+func TestParseAccessMode(t *testing.T) {
+	for s, exp := range map[string]core.AccessMode{
+		"":        core.AccessModeReadWriteSingle,
+		"rw":      core.AccessModeReadWriteSingle,
+		"ro":      core.AccessModeReadOnlySingle,
+		"RO-MANY": core.AccessModeReadOnlyMany,
+		"rw-many": core.AccessModeReadWriteMany,
+	} {
+		mode, err := core.ParseAccessMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode != exp {
+			t.Fatalf("mode(%s)=%s, exp=%s", s, mode, exp)
+		}
+	}
+	if _, err := core.ParseAccessMode("wo"); err == nil {
+		t.Fatal("expected invalid access mode error")
+	}
+}
+
+func TestAccessModeFlags(t *testing.T) {
+	if core.AccessModeReadWriteSingle.ReadOnly() ||
+		core.AccessModeReadWriteSingle.Many() {
+		t.Fatal("rw is neither read-only nor many")
+	}
+	if !core.AccessModeReadOnlySingle.ReadOnly() ||
+		core.AccessModeReadOnlySingle.Many() {
+		t.Fatal("ro is read-only and not many")
+	}
+	if !core.AccessModeReadOnlyMany.ReadOnly() ||
+		!core.AccessModeReadOnlyMany.Many() {
+		t.Fatal("ro-many is read-only and many")
+	}
+	if core.AccessModeReadWriteMany.ReadOnly() ||
+		!core.AccessModeReadWriteMany.Many() {
+		t.Fatal("rw-many is many and not read-only")
+	}
+}
+
+func TestAccessModeContext(t *testing.T) {
+	mode, ok := core.AccessModeFromContext(context.Background())
+	if ok || mode != core.AccessModeReadWriteSingle {
+		t.Fatalf("mode=%s ok=%v", mode, ok)
+	}
+	ctx := core.WithAccessMode(
+		context.Background(), core.AccessModeReadOnlyMany)
+	mode, ok = core.AccessModeFromContext(ctx)
+	if !ok || mode != core.AccessModeReadOnlyMany {
+		t.Fatalf("mode=%s ok=%v", mode, ok)
+	}
+}
+
+func TestCheckAccessMode(t *testing.T) {
+	d := getLimitedStorDriver(t, &core.StorageCapabilities{})
+
+	if err := core.CheckAccessMode(d, core.AccessModeReadOnlySingle); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.CheckAccessMode(d, core.AccessModeReadOnlyMany); err == nil {
+		t.Fatal("expected error for unsupported multi-attach")
+	}
+	if err := core.CheckVolumeOpts(d, core.VolumeOpts{
+		"accessmode": "rw-many"}); err == nil {
+		t.Fatal("expected error for unsupported multi-attach")
+	}
+	if err := core.CheckVolumeOpts(d, core.VolumeOpts{
+		"accessmode": "bad"}); err == nil {
+		t.Fatal("expected invalid access mode error")
+	}
+
+	d = getLimitedStorDriver(t, &core.StorageCapabilities{MultiAttach: true})
+	if err := core.CheckAccessMode(d, core.AccessModeReadWriteMany); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageDriverManagerAttachVolumeAccessMode(t *testing.T) {
+	for _, retries := range []bool{false, true} {
+		c := gofig.New()
+		c.Set("rexray.osDrivers", []string{mock.MockOSDriverName})
+		c.Set("rexray.volumeDrivers", []string{mock.MockVolDriverName})
+		c.Set("rexray.storageDrivers", []string{mock.MockStorDriverName})
+		if retries {
+			c.Set("rexray.storage.retry.writes.maxAttempts", 3)
+		}
+		r := core.New(c)
+		if err := r.InitDrivers(); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := core.WithAccessMode(
+			context.Background(), core.AccessModeReadWriteMany)
+		if _, err := r.Storage.AttachVolumeContext(
+			ctx, false, "test", "", false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Storage.AttachVolume(
+			false, "test", "", false); err != nil {
+			t.Fatal(err)
+		}
+
+		// the access mode reaches the mock storage driver through the
+		// metrics and retry decorators that wrap it
+		calls := findCalls(r, "AttachVolume")
+		if len(calls) != 2 ||
+			calls[0].Args[2] != string(core.AccessModeReadWriteMany) ||
+			calls[1].Args[2] != string(core.AccessModeReadWriteSingle) {
+			t.Fatalf("retries=%v calls=%v", retries, calls)
+		}
+	}
+}
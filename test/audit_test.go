@@ -0,0 +1,104 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+func getRexRayWithAudit(t *testing.T) (*core.RexRay, func()) {
+	d, err := ioutil.TempDir("", "rexray-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := core.New(nil)
+	r.Config.Set("rexray.osDrivers", []string{mock.MockOSDriverName})
+	r.Config.Set("rexray.volumeDrivers", []string{mock.MockVolDriverName})
+	r.Config.Set("rexray.storageDrivers", []string{mock.MockStorDriverName})
+	r.Config.Set("rexray.audit.file", filepath.Join(d, "audit.log"))
+	if err := r.InitDrivers(); err != nil {
+		os.RemoveAll(d)
+		t.Fatal(err)
+	}
+	return r, func() { os.RemoveAll(d) }
+}
+
+func TestAuditRecords(t *testing.T) {
+	r, cleanup := getRexRayWithAudit(t)
+	defer cleanup()
+
+	ctx := core.WithCaller(context.Background(), "test")
+	if _, err := r.Storage.AttachVolumeContext(
+		ctx, false, "test", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.OS.Mount(
+		"/dev/xvda", "/mnt/test", "password=secret,ro", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := r.ReadAudit(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("len(records)=%d", len(records))
+	}
+
+	a := records[0]
+	if a.Operation != "AttachVolume" || a.Caller != "test" ||
+		a.Driver != mock.MockStorDriverName || a.Result != "succeeded" {
+		t.Fatalf("record=%+v", a)
+	}
+	if a.Args["volumeID"] != "test" {
+		t.Fatalf("args=%v", a.Args)
+	}
+
+	a = records[1]
+	if a.Operation != "Mount" {
+		t.Fatalf("record=%+v", a)
+	}
+	if o := a.Args["mountOptions"]; o != "password=******,ro" {
+		t.Fatalf("mountOptions=%v", o)
+	}
+}
+
+func TestAuditQuery(t *testing.T) {
+	r, cleanup := getRexRayWithAudit(t)
+	defer cleanup()
+
+	if _, err := r.Storage.AttachVolume(false, "test", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Storage.DetachVolume(false, "test", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := r.ReadAudit(&core.AuditQuery{Operation: "detachvolume"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Operation != "DetachVolume" {
+		t.Fatalf("records=%v", records)
+	}
+
+	if records, err = r.ReadAudit(&core.AuditQuery{Limit: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Operation != "DetachVolume" {
+		t.Fatalf("records=%v", records)
+	}
+
+	if records, err = r.ReadAudit(&core.AuditQuery{Failed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("len(records)=%d", len(records))
+	}
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+func getRexRayWithCache(t *testing.T) *core.RexRay {
+	r := core.New(nil)
+	r.Config.Set("rexray.osDrivers", []string{mock.MockOSDriverName})
+	r.Config.Set("rexray.volumeDrivers", []string{mock.MockVolDriverName})
+	r.Config.Set("rexray.storageDrivers", []string{mock.MockStorDriverName})
+	r.Config.Set("rexray.storage.cache.instanceTTL", "1h")
+	r.Config.Set("rexray.storage.cache.volumeTTL", "1m")
+	if err := r.InitDrivers(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestStorageCacheVolumeCopies(t *testing.T) {
+	r := getRexRayWithCache(t)
+
+	volumes, err := r.Storage.GetVolume("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 1 {
+		t.Fatalf("len(volumes)=%d", len(volumes))
+	}
+	volumes[0].Name = "changed"
+
+	if volumes, err = r.Storage.GetVolume("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if volumes[0].Name != "test" {
+		t.Fatalf("cached volume modified by caller: %s", volumes[0].Name)
+	}
+}
+
+func TestStorageCacheInstance(t *testing.T) {
+	r := getRexRayWithCache(t)
+
+	for i := 0; i < 2; i++ {
+		instances, err := r.Storage.GetInstances()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(instances) != 1 || instances[0].InstanceID != "test" {
+			t.Fatalf("instances=%v", instances)
+		}
+		instances[0].InstanceID = "changed"
+	}
+}
+
+func TestStorageCacheInvalidate(t *testing.T) {
+	r := getRexRayWithCache(t)
+
+	if _, err := r.Storage.GetVolumeAttach("test", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.AttachVolume(false, "test", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.GetVolumeAttach("test", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Storage.RemoveVolume("test"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+type limitedStorDriver struct {
+	core.StorageDriver
+	caps *core.StorageCapabilities
+}
+
+func (d *limitedStorDriver) Capabilities() *core.StorageCapabilities {
+	return d.caps
+}
+
+func getLimitedStorDriver(
+	t *testing.T, caps *core.StorageCapabilities) core.StorageDriver {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := r.Storage.Driver(mock.MockStorDriverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &limitedStorDriver{d, caps}
+}
+
+func TestStorageCapabilitiesDefault(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps := core.GetStorageCapabilities(r.Storage)
+	for _, c := range core.Capabilities {
+		if !caps.Supports(c) {
+			t.Fatalf("capability %s not supported", c)
+		}
+	}
+	if _, ok := r.StorageCapabilities()[mock.MockStorDriverName]; !ok {
+		t.Fatal("missing mock storage driver capabilities")
+	}
+}
+
+func TestCheckCreateVolume(t *testing.T) {
+	d := getLimitedStorDriver(t, &core.StorageCapabilities{VolumeTypes: true})
+
+	if err := core.CheckCreateVolume(d, "io1", 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.CheckCreateVolume(d, "", 100, ""); err == nil {
+		t.Fatal("expected error for unsupported IOPS")
+	}
+	if err := core.CheckCreateVolume(d, "", 0, "us-east-1a"); err == nil {
+		t.Fatal("expected error for unsupported availability zone")
+	}
+}
+
+func TestCheckVolumeOpts(t *testing.T) {
+	d := getLimitedStorDriver(t, &core.StorageCapabilities{})
+
+	if err := core.CheckVolumeOpts(d, core.VolumeOpts{
+		"size": "1", "newfstype": "ext4"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.CheckVolumeOpts(d, core.VolumeOpts{
+		"snapshotname": "test"}); err == nil {
+		t.Fatal("expected error for unsupported snapshots")
+	}
+	if err := core.CheckVolumeOpts(d, core.VolumeOpts{
+		"iops": "100"}); err == nil {
+		t.Fatal("expected error for unsupported IOPS")
+	}
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core/errors"
+)
+
+func cancelledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestStorageDriverManagerAttachVolumeContext(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.AttachVolumeContext(
+		context.Background(), false, "", "", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageDriverManagerAttachVolumeContextCancelled(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.AttachVolumeContext(
+		cancelledContext(), false, "", "", false); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if calls := findCalls(r, "AttachVolume"); len(calls) != 0 {
+		t.Fatalf("calls=%v", calls)
+	}
+}
+
+func TestStorageDriverManagerAttachVolumeContextNoDrivers(t *testing.T) {
+	r, err := getRexRayNoDrivers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.AttachVolumeContext(
+		context.Background(), false, "", "",
+		false); err != errors.ErrNoStorageDetected {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageDriverManagerAttachVolumeInvalidTimeout(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.storage.timeouts.attach", "invalid")
+	if _, err := r.Storage.AttachVolume(false, "", "", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageDriverManagerGetVolumeContextCancelled(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.GetVolumeContext(
+		cancelledContext(), "", ""); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestVolumeDriverManagerMountContextCancelled(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Volume.MountContext(
+		cancelledContext(), "", "", false, "", false); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestVolumeDriverManagerMountContextNoDrivers(t *testing.T) {
+	r, err := getRexRayNoDrivers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Volume.MountContext(
+		context.Background(), "", "", false, "",
+		false); err != errors.ErrNoVolumesDetected {
+		t.Fatal(err)
+	}
+}
+
+func TestOSDriverManagerFormatContextCancelled(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.OS.FormatContext(
+		cancelledContext(), "", "", false); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageDriverManagerAttachVolumeTimeoutNotAbandoned(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.storage.timeouts.attach", "10ms")
+	r.Config.Set("mockProvider.delay.AttachVolume", "50ms")
+
+	// the mock storage driver does not observe contexts, so the attach
+	// runs to completion rather than being abandoned at its deadline
+	if _, err := r.Storage.AttachVolume(false, "", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if calls := findCalls(r, "AttachVolume"); len(calls) != 1 {
+		t.Fatalf("calls=%v", calls)
+	}
+}
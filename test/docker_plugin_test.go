@@ -0,0 +1,100 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akutz/goof"
+
+	"github.com/emccode/rexray/core/errors"
+	"github.com/emccode/rexray/daemon/module/docker/plugin"
+)
+
+func TestPluginErrorMessage(t *testing.T) {
+	err := goof.WithFieldE(
+		"volumeName", "db01", "error mounting volume",
+		errors.ErrNoVolumesDetected)
+	msg := plugin.ErrorMessage(err)
+	if msg != "error mounting volume: no volumes detected "+
+		"(code=NoVolumesDetected, volumeName=db01)" {
+		t.Fatalf("msg=%s", msg)
+	}
+
+	if msg := plugin.ErrorMessage(goof.New("test")); msg != "test" {
+		t.Fatalf("msg=%s", msg)
+	}
+}
+
+func TestPluginErrorStatus(t *testing.T) {
+	for err, status := range map[error]int{
+		errors.ErrNoVolumesReturned: http.StatusNotFound,
+		errors.ErrNoVolumesDetected: http.StatusServiceUnavailable,
+		errors.ErrNoStorageDetected: http.StatusServiceUnavailable,
+		errors.ErrMissingVolumeID:   http.StatusBadRequest,
+		errors.ErrCircuitOpen:       http.StatusServiceUnavailable,
+		goof.New("test"):            http.StatusInternalServerError,
+	} {
+		if s := plugin.ErrorStatus(err); s != status {
+			t.Fatalf("err=%v status=%d", err, s)
+		}
+	}
+}
+
+func TestPluginWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	plugin.WriteError(w, goof.New(`volume "db01" is in use`))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("code=%d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != plugin.ContentType {
+		t.Fatalf("contentType=%s", ct)
+	}
+	var res plugin.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Err != `volume "db01" is in use` {
+		t.Fatalf("err=%s", res.Err)
+	}
+}
+
+func TestPluginDecodeRequest(t *testing.T) {
+	r, err := http.NewRequest("POST", "http://test/VolumeDriver.Mount",
+		strings.NewReader(`{"Name":"db01","ID":"a1b2"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pr plugin.VolumeRequest
+	if !plugin.DecodeRequest(httptest.NewRecorder(), r, &pr) {
+		t.Fatal("expected request to decode")
+	}
+	if pr.Name != "db01" || pr.ID != "a1b2" {
+		t.Fatalf("request=%v", pr)
+	}
+
+	r, err = http.NewRequest(
+		"POST", "http://test/VolumeDriver.List", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plugin.DecodeRequest(httptest.NewRecorder(), r, &pr) {
+		t.Fatal("expected empty request to decode")
+	}
+
+	r, err = http.NewRequest(
+		"POST", "http://test/VolumeDriver.Mount", strings.NewReader("{"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	if plugin.DecodeRequest(w, r, &pr) {
+		t.Fatal("expected invalid request to fail")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("code=%d", w.Code)
+	}
+}
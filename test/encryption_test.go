@@ -0,0 +1,96 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+)
+
+func TestEncryptionKeyEnv(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.encryption.keyProvider", "env")
+	r.Config.Set("rexray.encryption.env.name", "REXRAY_TEST_KEY")
+
+	os.Setenv("REXRAY_TEST_KEY", "secret")
+	defer os.Unsetenv("REXRAY_TEST_KEY")
+
+	key, err := r.EncryptionKey(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "secret" {
+		t.Fatalf("key=%s", key)
+	}
+
+	os.Unsetenv("REXRAY_TEST_KEY")
+	if _, err := r.EncryptionKey(context.Background(), "test"); err == nil {
+		t.Fatal("expected empty key error")
+	}
+}
+
+func TestEncryptionKeyFileDir(t *testing.T) {
+	d, err := ioutil.TempDir("", "rexray-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	if err := ioutil.WriteFile(
+		filepath.Join(d, "vol-123"), []byte("key-123"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.encryption.keyProvider", "file")
+	r.Config.Set("rexray.encryption.file.path", d)
+
+	key, err := r.EncryptionKey(context.Background(), "vol-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "key-123" {
+		t.Fatalf("key=%s", key)
+	}
+	if _, err := r.EncryptionKey(context.Background(), "vol-456"); err == nil {
+		t.Fatal("expected missing key error")
+	}
+}
+
+func TestEncryptionKeyUnknownProvider(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.encryption.keyProvider", "vault")
+	if _, err := r.EncryptionKey(context.Background(), "test"); err == nil {
+		t.Fatal("expected unknown key provider error")
+	}
+}
+
+func TestEncryptionContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := core.EncryptionFromContext(ctx); ok {
+		t.Fatal("expected no encryption flag")
+	}
+	if key := core.EncryptionKeyFromContext(ctx); key != nil {
+		t.Fatalf("key=%s", key)
+	}
+
+	ctx = core.WithEncryptionKey(core.WithEncryption(ctx, true), []byte("k"))
+	if encrypted, ok := core.EncryptionFromContext(ctx); !ok || !encrypted {
+		t.Fatalf("encrypted=%v ok=%v", encrypted, ok)
+	}
+	if key := core.EncryptionKeyFromContext(ctx); string(key) != "k" {
+		t.Fatalf("key=%s", key)
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+)
+
+func nextEvent(t *testing.T, s *core.EventSubscription) *core.Event {
+	select {
+	case e := <-s.C:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return nil
+}
+
+func TestEvents(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := r.Events.Subscribe(
+		core.EventVolumeAttached, core.EventVolumeDetached)
+	defer s.Close()
+
+	ctx := core.WithCaller(context.Background(), "test")
+	if _, err := r.Storage.AttachVolumeContext(
+		ctx, false, "test", "i-1234", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.GetVolume("test", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Storage.DetachVolumeContext(
+		ctx, false, "test", "i-1234", false); err != nil {
+		t.Fatal(err)
+	}
+
+	e := nextEvent(t, s)
+	if e.Type != core.EventVolumeAttached || e.VolumeID != "test" ||
+		e.InstanceID != "i-1234" || e.Caller != "test" {
+		t.Fatalf("event=%+v", e)
+	}
+	if e = nextEvent(t, s); e.Type != core.EventVolumeDetached {
+		t.Fatalf("event=%+v", e)
+	}
+}
+
+func TestEventsSharedBus(t *testing.T) {
+	r1, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := r1.Events.Subscribe(core.EventVolumeRemoved)
+	if err := r2.Storage.RemoveVolume("test"); err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(t, s); e.VolumeID != "test" {
+		t.Fatalf("event=%+v", e)
+	}
+
+	s.Close()
+	if _, ok := <-s.C; ok {
+		t.Fatal("expected closed subscription")
+	}
+}
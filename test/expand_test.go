@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/core/errors"
+)
+
+func TestExpandVolume(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := r.Events.Subscribe(core.EventVolumeExpanded)
+	defer s.Close()
+
+	if err := r.Volume.Expand("", "test", 20); err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(t, s); e.VolumeID != "test" {
+		t.Fatalf("event=%+v", e)
+	}
+}
+
+func TestExpandVolumeInvalidSize(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Volume.Expand("", "test", 10)
+	if c := errors.ErrCode(err); c != errors.ErrCodeInvalidVolumeSize {
+		t.Fatalf("err=%v", err)
+	}
+
+	if err := r.Storage.ExpandVolume("test", 0); err == nil {
+		t.Fatal("expected invalid size error")
+	}
+}
@@ -0,0 +1,40 @@
+package test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+)
+
+func TestFormatOptionsContext(t *testing.T) {
+	ctx := context.Background()
+	if opts := core.FormatOptionsFromContext(ctx); opts != nil {
+		t.Fatalf("opts=%v", opts)
+	}
+
+	ctx = core.WithFormatOptions(ctx, &core.FormatOptions{
+		Args:  "-b size=4096",
+		Label: "db01",
+	})
+	opts := core.FormatOptionsFromContext(ctx)
+	if opts == nil {
+		t.Fatal("expected format options")
+	}
+	if opts.Args != "-b size=4096" || opts.Label != "db01" {
+		t.Fatalf("opts=%v", opts)
+	}
+}
+
+func TestMountOptionsContext(t *testing.T) {
+	ctx := context.Background()
+	if opts := core.MountOptionsFromContext(ctx); opts != "" {
+		t.Fatalf("opts=%s", opts)
+	}
+
+	ctx = core.WithMountOptions(ctx, "noatime,discard")
+	if opts := core.MountOptionsFromContext(ctx); opts != "noatime,discard" {
+		t.Fatalf("opts=%s", opts)
+	}
+}
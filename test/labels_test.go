@@ -0,0 +1,135 @@
+package test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := core.ParseLabels([]string{"env=prod", "app = db", "tier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 3 ||
+		labels["env"] != "prod" || labels["app"] != "db" || labels["tier"] != "" {
+		t.Fatalf("labels=%v", labels)
+	}
+	if _, err := core.ParseLabels([]string{"=prod"}); err == nil {
+		t.Fatal("expected error parsing label without key")
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"env": "prod", "app": "db"}
+	if !core.MatchLabels(labels, nil) {
+		t.Fatal("expected empty selector to match")
+	}
+	if !core.MatchLabels(labels, map[string]string{"env": "prod"}) {
+		t.Fatal("expected selector to match")
+	}
+	if core.MatchLabels(labels, map[string]string{"env": "dev"}) {
+		t.Fatal("expected selector not to match")
+	}
+	if core.MatchLabels(nil, map[string]string{"env": "prod"}) {
+		t.Fatal("expected selector not to match unlabeled volume")
+	}
+}
+
+func TestStorageDriverManagerCreateVolumeLabels(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := core.WithLabels(
+		context.Background(), map[string]string{"env": "prod", "app": "db"})
+	volume, err := r.Storage.CreateVolumeContext(
+		ctx, false, "test", "", "", "", 0, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume.Name != "test" {
+		t.Fatalf("volume.Name=%s", volume.Name)
+	}
+	if len(volume.Labels) != 2 || volume.Labels["env"] != "prod" {
+		t.Fatalf("volume.Labels=%v", volume.Labels)
+	}
+
+	ctx = core.WithLabels(
+		context.Background(), map[string]string{"env": "a,b"})
+	if _, err := r.Storage.CreateVolumeContext(
+		ctx, false, "test", "", "", "", 0, 1, ""); err == nil {
+		t.Fatal("expected error encoding label in name")
+	}
+}
+
+func TestStorageDriverManagerCreateSnapshotLabels(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := core.WithLabels(
+		context.Background(), map[string]string{"env": "prod"})
+	snapshots, err := r.Storage.CreateSnapshotContext(
+		ctx, false, "test", "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Name != "test" ||
+		snapshots[0].Labels["env"] != "prod" {
+		t.Fatalf("snapshots=%v", snapshots)
+	}
+}
+
+func TestStorageDriverManagerGetVolumeLabelSelector(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err := r.Storage.GetVolumeContext(
+		core.WithLabelSelector(context.Background(), nil), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 1 {
+		t.Fatalf("len(volumes)=%d", len(volumes))
+	}
+
+	volumes, err = r.Storage.GetVolumeContext(
+		core.WithLabelSelector(
+			context.Background(), map[string]string{"env": "prod"}), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 0 {
+		t.Fatalf("len(volumes)=%d", len(volumes))
+	}
+}
+
+func TestStorageDriverManagerGetVolumeStorageDriver(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := core.WithStorageDriver(
+		context.Background(), mock.MockStorDriverName)
+	volumes, err := r.Storage.GetVolumeContext(ctx, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 1 {
+		t.Fatalf("len(volumes)=%d", len(volumes))
+	}
+
+	ctx = core.WithStorageDriver(context.Background(), "invalid")
+	if _, err := r.Storage.GetVolumeContext(ctx, "", ""); err == nil {
+		t.Fatal("expected error getting volumes from invalid driver")
+	}
+}
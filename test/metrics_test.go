@@ -0,0 +1,58 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akutz/goof"
+
+	"github.com/emccode/rexray/core/errors"
+	"github.com/emccode/rexray/daemon/module"
+)
+
+func TestErrCode(t *testing.T) {
+	if c := errors.ErrCode(errors.ErrNoVolumesDetected); c !=
+		errors.ErrCodeNoVolumesDetected {
+		t.Fatalf("code=%s", c.Name())
+	}
+	err := goof.WithFieldE(
+		"volumeID", "test", "error getting volume", errors.ErrMissingVolumeID)
+	if c := errors.ErrCode(err); c != errors.ErrCodeMissingVolumeID {
+		t.Fatalf("code=%s", c.Name())
+	}
+	if c := errors.ErrCode(goof.New("test")); c != errors.ErrCodeUnknown {
+		t.Fatalf("code=%s", c.Name())
+	}
+	if n := errors.ErrCodeCircuitOpen.Name(); n != "CircuitOpen" {
+		t.Fatalf("name=%s", n)
+	}
+}
+
+func TestInstrumentMux(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/VolumeDriver.Path", func(
+		w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.CloseNotifier); !ok {
+			t.Fatal("expected response writer to implement CloseNotifier")
+		}
+		http.Error(w, `{"Error":"test"}`, 500)
+	})
+	h := module.InstrumentMux("test", mux)
+
+	for path, code := range map[string]int{
+		"/VolumeDriver.Path": 500,
+		"/unknown":           404,
+	} {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest("POST", fmt.Sprintf("http://test%s", path), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("path=%s code=%d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+func TestQuiesceFromContext(t *testing.T) {
+	if _, ok := core.QuiesceFromContext(context.Background()); ok {
+		t.Fatal("expected no quiesce flag")
+	}
+	ctx := core.WithQuiesce(context.Background(), false)
+	if q, ok := core.QuiesceFromContext(ctx); !ok || q {
+		t.Fatalf("quiesce=%v ok=%v", q, ok)
+	}
+}
+
+// quiesceOps returns the freeze, thaw, and snapshot operations performed by
+// the mock drivers in the order in which they completed.
+func quiesceOps(r *core.RexRay) string {
+	var ops []string
+	for _, c := range mock.Calls(r) {
+		switch c.Operation {
+		case "Freeze", "Thaw", "CreateSnapshot":
+			ops = append(ops, c.Operation)
+		}
+	}
+	return strings.Join(ops, ",")
+}
+
+func getRexRayMounted() (*core.RexRay, error) {
+	r, err := getRexRay()
+	if err != nil {
+		return nil, err
+	}
+	r.Config.Set("mockProvider.mountPoint", "/mnt/test")
+	return r, nil
+}
+
+func TestCreateSnapshotQuiesce(t *testing.T) {
+	r, err := getRexRayMounted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := core.WithQuiesce(context.Background(), true)
+	snapshots, err := r.Storage.CreateSnapshotContext(
+		ctx, false, "snap", "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].VolumeID != "test" {
+		t.Fatalf("snapshots=%v", snapshots)
+	}
+	if ops := quiesceOps(r); ops != "Freeze,CreateSnapshot,Thaw" {
+		t.Fatalf("ops=%s", ops)
+	}
+	if calls := findCalls(r, "Freeze"); calls[0].Args[0] != "/mnt/test" {
+		t.Fatalf("calls=%v", calls)
+	}
+}
+
+func TestCreateSnapshotQuiesceNotMounted(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := core.WithQuiesce(context.Background(), true)
+	if _, err := r.Storage.CreateSnapshotContext(
+		ctx, false, "snap", "test", ""); err != nil {
+		t.Fatal(err)
+	}
+	if ops := quiesceOps(r); ops != "CreateSnapshot" {
+		t.Fatalf("ops=%s", ops)
+	}
+}
+
+func TestCreateSnapshotQuiesceSnapshotFailed(t *testing.T) {
+	r, err := getRexRayMounted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("mockProvider.fail", []string{"CreateSnapshot"})
+
+	ctx := core.WithQuiesce(context.Background(), true)
+	if _, err := r.Storage.CreateSnapshotContext(
+		ctx, false, "snap", "test", ""); err == nil {
+		t.Fatal("expected error creating snapshot")
+	}
+	if ops := quiesceOps(r); ops != "Freeze,CreateSnapshot,Thaw" {
+		t.Fatalf("ops=%s", ops)
+	}
+}
+
+func TestCreateSnapshotQuiesceTimeout(t *testing.T) {
+	r, err := getRexRayMounted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.storage.snapshots.quiesceTimeout", "10ms")
+	r.Config.Set("mockProvider.delay.CreateSnapshot", "100ms")
+
+	// the watchdog thaws the filesystem before the snapshot completes, and
+	// the filesystem is not thawed again when it does
+	ctx := core.WithQuiesce(context.Background(), true)
+	if _, err := r.Storage.CreateSnapshotContext(
+		ctx, false, "snap", "test", ""); err != nil {
+		t.Fatal(err)
+	}
+	if ops := quiesceOps(r); ops != "Freeze,Thaw,CreateSnapshot" {
+		t.Fatalf("ops=%s", ops)
+	}
+}
+
+func TestCreateSnapshotQuiesceAsync(t *testing.T) {
+	r, err := getRexRayMounted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := core.WithQuiesce(context.Background(), true)
+	if _, err := r.Storage.CreateSnapshotContext(
+		ctx, true, "snap", "test", ""); err == nil {
+		t.Fatal("expected error creating asynchronous quiesced snapshot")
+	}
+	if ops := quiesceOps(r); ops != "" {
+		t.Fatalf("ops=%s", ops)
+	}
+}
@@ -0,0 +1,167 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/akutz/goof"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/core/errors"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+// failingStorDriver fails the first failures calls to GetVolume and
+// RemoveVolume with err.
+type failingStorDriver struct {
+	core.StorageDriver
+	err      error
+	failures int
+	calls    int
+}
+
+func (d *failingStorDriver) fail() error {
+	d.calls++
+	if d.calls <= d.failures {
+		return d.err
+	}
+	return nil
+}
+
+func (d *failingStorDriver) GetVolume(
+	volumeID, volumeName string) ([]*core.Volume, error) {
+	if err := d.fail(); err != nil {
+		return nil, err
+	}
+	return d.StorageDriver.GetVolume(volumeID, volumeName)
+}
+
+func (d *failingStorDriver) RemoveVolume(volumeID string) error {
+	if err := d.fail(); err != nil {
+		return err
+	}
+	return d.StorageDriver.RemoveVolume(volumeID)
+}
+
+func getFailingStorDriver(
+	t *testing.T, fe error, failures int) *failingStorDriver {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := r.Storage.Driver(mock.MockStorDriverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &failingStorDriver{StorageDriver: d, err: fe, failures: failures}
+}
+
+func testRetryPolicy() *core.RetryPolicy {
+	return &core.RetryPolicy{
+		ReadAttempts:     3,
+		WriteAttempts:    3,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       time.Millisecond,
+		FailureThreshold: 10,
+		ResetTimeout:     time.Hour,
+	}
+}
+
+func TestRetryTransientRead(t *testing.T) {
+	fd := getFailingStorDriver(t, goof.New("503 Service Unavailable"), 2)
+	d := core.NewRetryStorageDriver(fd, testRetryPolicy())
+
+	if _, err := d.GetVolume("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if fd.calls != 3 {
+		t.Fatalf("calls=%d", fd.calls)
+	}
+}
+
+func TestRetryPermanentRead(t *testing.T) {
+	fd := getFailingStorDriver(t, goof.New("volume not found"), 2)
+	d := core.NewRetryStorageDriver(fd, testRetryPolicy())
+
+	if _, err := d.GetVolume("", ""); err == nil {
+		t.Fatal("expected error")
+	}
+	if fd.calls != 1 {
+		t.Fatalf("calls=%d", fd.calls)
+	}
+}
+
+func TestRetryWrite(t *testing.T) {
+	fd := getFailingStorDriver(t, goof.New("503 Service Unavailable"), 1)
+	d := core.NewRetryStorageDriver(fd, testRetryPolicy())
+
+	if err := d.RemoveVolume("test"); err == nil {
+		t.Fatal("expected unavailable write not to be retried")
+	}
+	if fd.calls != 1 {
+		t.Fatalf("calls=%d", fd.calls)
+	}
+
+	fd = getFailingStorDriver(t, goof.WithFieldE(
+		"volumeID", "test", "error removing volume",
+		goof.New("RequestLimitExceeded: Request limit exceeded.")), 1)
+	d = core.NewRetryStorageDriver(fd, testRetryPolicy())
+
+	if err := d.RemoveVolume("test"); err != nil {
+		t.Fatal(err)
+	}
+	if fd.calls != 2 {
+		t.Fatalf("calls=%d", fd.calls)
+	}
+}
+
+func TestRetryCircuitBreaker(t *testing.T) {
+	p := testRetryPolicy()
+	p.ReadAttempts = 1
+	p.FailureThreshold = 2
+	p.ResetTimeout = 50 * time.Millisecond
+
+	fd := getFailingStorDriver(t, goof.New("connection refused"), 2)
+	d := core.NewRetryStorageDriver(fd, p)
+
+	for i := 0; i < 2; i++ {
+		if _, err := d.GetVolume("", ""); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	_, err := d.GetVolume("", "")
+	if err == nil {
+		t.Fatal("expected circuit breaker to be open")
+	}
+	if inner, _ := err.(interface {
+		Fields() map[string]interface{}
+	}).Fields()["inner"].(error); inner != errors.ErrCircuitOpen {
+		t.Fatalf("err=%v", err)
+	}
+	if fd.calls != 2 {
+		t.Fatalf("calls=%d", fd.calls)
+	}
+
+	time.Sleep(p.ResetTimeout)
+	if _, err := d.GetVolume("", ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryDriverInterfaces(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := r.Storage.Driver(mock.MockStorDriverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.(core.ContextStorageDriver); !ok {
+		t.Fatal("expected retry driver to implement ContextStorageDriver")
+	}
+	if _, ok := d.(core.LabelStorageDriver); ok {
+		t.Fatal("expected retry driver not to implement LabelStorageDriver")
+	}
+}
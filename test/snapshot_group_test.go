@@ -0,0 +1,52 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/core/errors"
+)
+
+func TestCreateSnapshotGroup(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := r.Storage.CreateSnapshotGroup(false,
+		map[string]string{"vol-1": "db", "vol-2": "db"}, "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("len(snapshots)=%d", len(snapshots))
+	}
+
+	groupID := snapshots[0].Labels[core.SnapshotGroupLabel]
+	if groupID == "" {
+		t.Fatal("missing snapshot group label")
+	}
+	for _, s := range snapshots {
+		if s.Name != "db" {
+			t.Fatalf("snapshot.Name=%s", s.Name)
+		}
+		if s.Labels[core.SnapshotGroupLabel] != groupID {
+			t.Fatalf("snapshot.Labels=%v", s.Labels)
+		}
+		if s.Labels[core.SnapshotGroupVolumeLabel] != "test" {
+			t.Fatalf("snapshot.Labels=%v", s.Labels)
+		}
+	}
+}
+
+func TestCreateSnapshotGroupNoVolumes(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Storage.CreateSnapshotGroup(
+		false, nil, ""); err != errors.ErrMissingVolumeID {
+		t.Fatalf("err=%v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	start := time.Date(2016, time.March, 14, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"*/15 * * * *",
+			time.Date(2016, time.March, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 2 * * *",
+			time.Date(2016, time.March, 15, 2, 0, 0, 0, time.UTC)},
+		{"@hourly",
+			time.Date(2016, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"30 1 * * 0",
+			time.Date(2016, time.March, 20, 1, 30, 0, 0, time.UTC)},
+		{"0 0 1 4 *",
+			time.Date(2016, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *",
+			time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, err := core.ParseCronSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		if next := c.Next(start); !next.Equal(tt.next) {
+			t.Errorf("%s: next=%v, expected %v", tt.expr, next, tt.next)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *"} {
+		if _, err := core.ParseCronSchedule(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestSnapshotSchedulerRun(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	r.Config.Set("rexray.storage.snapshots.schedules", []string{"nightly"})
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.cron", "0 2 * * *")
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.volumeName", "te*")
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.retainCount", 7)
+
+	s, err := core.NewSnapshotScheduler(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Run(context.Background(), "hourly"); err == nil {
+		t.Fatal("expected error running unknown schedule")
+	}
+
+	st, err := s.Run(context.Background(), "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.LastSnapshots) != 1 || st.LastRun.IsZero() {
+		t.Fatalf("status=%+v", st)
+	}
+
+	// the last run is read back by a new scheduler
+	if s, err = core.NewSnapshotScheduler(r); err != nil {
+		t.Fatal(err)
+	}
+	status := s.Status()
+	if len(status) != 1 || !status[0].LastRun.Equal(st.LastRun) {
+		t.Fatalf("status=%+v", status)
+	}
+}
+
+func TestSnapshotSchedulesInvalid(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.storage.snapshots.schedules", []string{"weekly"})
+	r.Config.Set("rexray.storage.snapshots.schedule.weekly.cron", "@weekly")
+	r.Config.Set("rexray.storage.snapshots.schedule.weekly.retainAge", "1w")
+	if _, err := core.SnapshotSchedules(r); err == nil {
+		t.Fatal("expected invalid retention age error")
+	}
+}
+
+// seedScheduledSnapshots creates snapshots of the mock volume as if they
+// were taken by the named schedule the provided number of days ago.
+func seedScheduledSnapshots(
+	t *testing.T, r *core.RexRay, schedule string, daysAgo ...int) {
+	for _, d := range daysAgo {
+		taken := time.Now().Add(-time.Duration(d) * 24 * time.Hour)
+		ctx := core.WithLabels(context.Background(), map[string]string{
+			core.SnapshotScheduleLabel:     schedule,
+			core.SnapshotScheduleTimeLabel: strconv.FormatInt(taken.Unix(), 10),
+		})
+		if _, err := r.Storage.CreateSnapshotContext(
+			ctx, false, "seed", "test", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSnapshotSchedulerPrune(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	r.Config.Set("rexray.storage.snapshots.schedules", []string{"nightly"})
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.cron", "0 2 * * *")
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.retainCount", 2)
+	seedScheduledSnapshots(t, r, "nightly", 3, 2, 1)
+
+	s, err := core.NewSnapshotScheduler(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.Run(context.Background(), "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.LastSnapshots) != 1 || len(st.LastPruned) != 2 {
+		t.Fatalf("status=%+v", st)
+	}
+}
+
+func TestSnapshotSchedulerSnapshotFailedNotPruned(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	r.Config.Set("rexray.storage.snapshots.schedules", []string{"nightly"})
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.cron", "0 2 * * *")
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.retainCount", 1)
+	r.Config.Set("rexray.storage.snapshots.schedule.nightly.retainAge", "1h")
+	seedScheduledSnapshots(t, r, "nightly", 3, 2, 1)
+
+	s, err := core.NewSnapshotScheduler(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("mockProvider.fail", []string{"CreateSnapshot"})
+
+	st, err := s.Run(context.Background(), "nightly")
+	if err == nil {
+		t.Fatal("expected error running schedule")
+	}
+	if len(st.LastSnapshots) != 0 || len(st.LastPruned) != 0 {
+		t.Fatalf("status=%+v", st)
+	}
+	if calls := findCalls(r, "RemoveSnapshot"); len(calls) != 0 {
+		t.Fatalf("calls=%v", calls)
+	}
+}
@@ -1,8 +1,11 @@
 package test
 
 import (
+	"strings"
 	"testing"
 
+	"golang.org/x/net/context"
+
 	"github.com/emccode/rexray/core/errors"
 	"github.com/emccode/rexray/drivers/mock"
 )
@@ -87,6 +90,21 @@ func TestStorageDriverManagerGetInstance(t *testing.T) {
 	}
 }
 
+func TestStorageDriverManagerGetVolumeInstance(t *testing.T) {
+	r, err := getRexRayTwoStorDrivers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := r.Storage.GetVolumeInstanceContext(
+		context.Background(), "test2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.ProviderName != mock.MockStorDriver2Name {
+		t.Fatalf("providerName=%s", i.ProviderName)
+	}
+}
+
 func TestStorageDriverManagerGetInstanceNoDrivers(t *testing.T) {
 	r, err := getRexRayNoDrivers()
 	if err != nil {
@@ -479,3 +497,117 @@ func TestStorageDriverManagerCopySnapshotNoDrivers(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStorageDriverManagerDriver(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := r.Storage.Driver(strings.ToLower(mock.MockStorDriverName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name() != mock.MockStorDriverName {
+		t.Fatalf("driver name != %s, == %s", mock.MockStorDriverName, d.Name())
+	}
+}
+
+func TestStorageDriverManagerDriverNotConfigured(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.Driver(mock.BadMockStorDriverName); err == nil {
+		t.Fatal("expected error for unconfigured driver")
+	}
+}
+
+func TestStorageDriverManagerDriverNoDrivers(t *testing.T) {
+	r, err := getRexRayNoDrivers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.Driver(
+		mock.MockStorDriverName); err != errors.ErrNoStorageDetected {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageDriverManagerDefaultDriver(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.storage.defaultDriver", mock.BadMockStorDriverName)
+	if _, err := r.Storage.GetInstance(); err == nil {
+		t.Fatal("expected error for unconfigured default driver")
+	}
+	r.Config.Set("rexray.storage.defaultDriver", mock.MockStorDriverName)
+	if _, err := r.Storage.GetInstance(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageDriverManagerGetVolumeMerged(t *testing.T) {
+	r, err := getRexRayTwoStorDrivers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err := r.Storage.GetVolume("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for _, v := range volumes {
+		ids[v.VolumeID] = true
+	}
+	if len(volumes) != 2 || !ids["test"] || !ids["test2"] {
+		t.Fatalf("volumes=%v", volumes)
+	}
+}
+
+func TestStorageDriverManagerRoutesToOwner(t *testing.T) {
+	r, err := getRexRayTwoStorDrivers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Storage.AttachVolume(false, "test2", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Storage.DetachVolume(false, "test2", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Storage.AttachVolume(false, "test", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	attaches := findCalls(r, "AttachVolume")
+	if len(attaches) != 2 ||
+		attaches[0].Driver != mock.MockStorDriver2Name ||
+		attaches[1].Driver != mock.MockStorDriverName {
+		t.Fatalf("attaches=%v", attaches)
+	}
+	detaches := findCalls(r, "DetachVolume")
+	if len(detaches) != 1 || detaches[0].Driver != mock.MockStorDriver2Name {
+		t.Fatalf("detaches=%v", detaches)
+	}
+}
+
+func TestStorageDriverManagerRoutesUnknownToDefault(t *testing.T) {
+	r, err := getRexRayTwoStorDrivers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("rexray.storage.defaultDriver", mock.MockStorDriver2Name)
+
+	if _, err := r.Storage.CreateVolume(
+		false, "vol1", "", "", "", 0, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	creates := findCalls(r, "CreateVolume")
+	if len(creates) != 1 || creates[0].Driver != mock.MockStorDriver2Name {
+		t.Fatalf("creates=%v", creates)
+	}
+}
@@ -0,0 +1,203 @@
+package test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/util"
+)
+
+func getRexRayWithTempLib(t *testing.T) (*core.RexRay, func()) {
+	d, err := ioutil.TempDir("", "rexray-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := util.GetPrefix()
+	util.Prefix(d)
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, func() {
+		util.Prefix(prefix)
+		os.RemoveAll(d)
+	}
+}
+
+func TestTaskSubmitSucceeded(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	task, err := r.Tasks.Submit("AttachVolume",
+		func(ctx context.Context) (interface{}, error) {
+			return r.Storage.AttachVolumeContext(ctx, false, "", "", false)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	task, err = r.Tasks.Wait(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.State != core.TaskStateSucceeded {
+		t.Fatalf("task.State=%s", task.State)
+	}
+	if task.Result == nil {
+		t.Fatal("task.Result is nil")
+	}
+}
+
+func TestTaskSubmitFailed(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	task, err := r.Tasks.Submit("CopySnapshot",
+		func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New("copy failed")
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	task, err = r.Tasks.Wait(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.State != core.TaskStateFailed {
+		t.Fatalf("task.State=%s", task.State)
+	}
+	if task.Error != "copy failed" {
+		t.Fatalf("task.Error=%s", task.Error)
+	}
+}
+
+func TestTaskCreateAndRun(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	task, err := r.Tasks.Create("DetachVolume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.State != core.TaskStateQueued {
+		t.Fatalf("task.State=%s", task.State)
+	}
+
+	tasks, err := r.Tasks.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Fatalf("len(tasks)=%d", len(tasks))
+	}
+
+	f := func(ctx context.Context) (interface{}, error) { return nil, nil }
+	if task, err = r.Tasks.Run(task.ID, f); err != nil {
+		t.Fatal(err)
+	}
+	if task.State != core.TaskStateSucceeded {
+		t.Fatalf("task.State=%s", task.State)
+	}
+
+	if _, err := r.Tasks.Run(task.ID, f); err == nil {
+		t.Fatal("expected error running completed task")
+	}
+}
+
+func TestTaskWaitTimeout(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	task, err := r.Tasks.Create("CopySnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := r.Tasks.Wait(ctx, task.ID); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+}
+
+func TestTaskGetInvalidID(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	if _, err := r.Tasks.Get("../../etc/passwd"); err == nil {
+		t.Fatal("expected error getting invalid task ID")
+	}
+	if _, err := r.Tasks.Get("0123456789abcdef"); err == nil {
+		t.Fatal("expected error getting missing task")
+	}
+}
+
+func TestTaskWaitOtherProcess(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	task, err := r.Tasks.Create("CopySnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestTaskHelperProcess$")
+	cmd.Env = append(os.Environ(),
+		"REXRAY_TEST_TASK_ID="+task.ID,
+		"REXRAY_TEST_PREFIX="+util.GetPrefix())
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	task, err = r.Tasks.Wait(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.State != core.TaskStateSucceeded {
+		t.Fatalf("task.State=%s", task.State)
+	}
+	if task.Result != "copied" {
+		t.Fatalf("task.Result=%v", task.Result)
+	}
+}
+
+// TestTaskHelperProcess is not a real test. It runs the queued task for
+// TestTaskWaitOtherProcess in a separate process.
+func TestTaskHelperProcess(t *testing.T) {
+	taskID := os.Getenv("REXRAY_TEST_TASK_ID")
+	if taskID == "" {
+		return
+	}
+	util.Prefix(os.Getenv("REXRAY_TEST_PREFIX"))
+
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Tasks.Run(taskID,
+		func(ctx context.Context) (interface{}, error) {
+			return "copied", nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+}
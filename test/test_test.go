@@ -14,12 +14,22 @@ import (
 	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/core/errors"
 	"github.com/emccode/rexray/drivers/mock"
+	"github.com/emccode/rexray/util"
 )
 
 func TestMain(m *testing.M) {
 	mock.RegisterMockDrivers()
 	mock.RegisterBadMockDrivers()
-	os.Exit(m.Run())
+
+	d, err := ioutil.TempDir("", "rexray-test")
+	if err != nil {
+		panic(err)
+	}
+	util.Prefix(d)
+
+	code := m.Run()
+	os.RemoveAll(d)
+	os.Exit(code)
 }
 
 func getRexRay() (*core.RexRay, error) {
@@ -36,6 +46,33 @@ func getRexRay() (*core.RexRay, error) {
 	return r, nil
 }
 
+func getRexRayTwoStorDrivers() (*core.RexRay, error) {
+	c := gofig.New()
+	c.Set("rexray.osDrivers", []string{mock.MockOSDriverName})
+	c.Set("rexray.volumeDrivers", []string{mock.MockVolDriverName})
+	c.Set("rexray.storageDrivers", []string{
+		mock.MockStorDriverName, mock.MockStorDriver2Name})
+	r := core.New(c)
+
+	if err := r.InitDrivers(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// findCalls returns the operations with the provided name that were
+// performed by the mock drivers of the REX-Ray instance.
+func findCalls(r *core.RexRay, op string) []*mock.Call {
+	var found []*mock.Call
+	for _, c := range mock.Calls(r) {
+		if c.Operation == op {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
 func getRexRayNoDrivers() (*core.RexRay, error) {
 	c := gofig.New()
 	c.Set("rexray.osDrivers", []string{""})
@@ -189,6 +226,7 @@ func TestDriverNames(t *testing.T) {
 		strings.ToLower(mock.MockOSDriverName),
 		strings.ToLower(mock.MockVolDriverName),
 		strings.ToLower(mock.MockStorDriverName),
+		strings.ToLower(mock.MockStorDriver2Name),
 		strings.ToLower(mock.BadMockOSDriverName),
 		strings.ToLower(mock.BadMockVolDriverName),
 		strings.ToLower(mock.BadMockStorDriverName),
@@ -235,6 +273,7 @@ func TestRexRayDriverNames(t *testing.T) {
 		strings.ToLower(mock.MockOSDriverName),
 		strings.ToLower(mock.MockVolDriverName),
 		strings.ToLower(mock.MockStorDriverName),
+		strings.ToLower(mock.MockStorDriver2Name),
 		strings.ToLower(mock.BadMockOSDriverName),
 		strings.ToLower(mock.BadMockVolDriverName),
 		strings.ToLower(mock.BadMockStorDriverName),
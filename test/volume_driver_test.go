@@ -1,10 +1,15 @@
 package test
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/akutz/gofig"
+
+	"github.com/emccode/rexray/core"
 	"github.com/emccode/rexray/core/errors"
 	"github.com/emccode/rexray/drivers/mock"
+	"github.com/emccode/rexray/util"
 )
 
 func TestVolumeDriverName(t *testing.T) {
@@ -40,17 +45,36 @@ func TestUnmountAll(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := r.Volume.UnmountAll(); err != nil {
+	if _, err := r.Volume.UnmountAll(); err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestUnmountAllMountedElsewhere(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("mockProvider.mountPoint", "/var/lib/mysql")
+
+	results, err := r.Volume.UnmountAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Error == "" {
+		t.Fatalf("results=%v", results)
+	}
+	if calls := findCalls(r, "Unmount"); len(calls) != 0 {
+		t.Fatalf("calls=%v", calls)
+	}
+}
+
 func TestUnmountAllNoDrivers(t *testing.T) {
 	r, err := getRexRayNoDrivers()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := r.Volume.UnmountAll(); err != errors.ErrNoVolumesDetected {
+	if _, err := r.Volume.UnmountAll(); err != errors.ErrNoVolumesDetected {
 		t.Fatal(err)
 	}
 }
@@ -60,9 +84,60 @@ func TestRemoveAll(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := r.Volume.RemoveAll(); err != nil {
+	r.Config.Set("mockProvider.mountPoint", util.LibFilePath("volumes/test"))
+
+	results, err := r.Volume.RemoveAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].VolumeID != "test" ||
+		results[0].Error != "" {
+		t.Fatalf("results=%v", results)
+	}
+	if len(findCalls(r, "Unmount")) != 1 ||
+		len(findCalls(r, "RemoveVolume")) != 1 {
+		t.Fatalf("calls=%v", mock.Calls(r))
+	}
+}
+
+func TestRemoveAllNotManaged(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the volume is attached but was not mounted by REX-Ray
+	results, err := r.Volume.RemoveAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 ||
+		results[0].Error != "volume is not mounted by REX-Ray" {
+		t.Fatalf("results=%v", results)
+	}
+	if calls := mock.Calls(r); len(calls) != 0 {
+		t.Fatalf("calls=%v", calls)
+	}
+}
+
+func TestRemoveAllMountedElsewhere(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Set("mockProvider.mountPoint", "/var/lib/mysql")
+
+	results, err := r.Volume.RemoveAll()
+	if err != nil {
 		t.Fatal(err)
 	}
+	if len(results) != 1 || !strings.Contains(
+		results[0].Error, "mounted outside of REX-Ray at /var/lib/mysql") {
+		t.Fatalf("results=%v", results)
+	}
+	if calls := mock.Calls(r); len(calls) != 0 {
+		t.Fatalf("calls=%v", calls)
+	}
 }
 
 func TestRemoveAllNoDrivers(t *testing.T) {
@@ -70,7 +145,7 @@ func TestRemoveAllNoDrivers(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := r.Volume.RemoveAll(); err != errors.ErrNoVolumesDetected {
+	if _, err := r.Volume.RemoveAll(); err != errors.ErrNoVolumesDetected {
 		t.Fatal(err)
 	}
 }
@@ -80,9 +155,52 @@ func TestDetachAll(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := r.Volume.DetachAll(""); err != nil {
+	r.Config.Set("mockProvider.mountPoint", util.LibFilePath("volumes/test"))
+
+	results, err := r.Volume.DetachAll("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].VolumeID != "test" ||
+		results[0].Error != "" {
+		t.Fatalf("results=%v", results)
+	}
+	if len(findCalls(r, "Unmount")) != 1 {
+		t.Fatalf("calls=%v", mock.Calls(r))
+	}
+}
+
+func TestDetachAllNotManaged(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := r.Volume.DetachAll("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 ||
+		results[0].Error != "volume is not mounted by REX-Ray" {
+		t.Fatalf("results=%v", results)
+	}
+	if calls := mock.Calls(r); len(calls) != 0 {
+		t.Fatalf("calls=%v", calls)
+	}
+}
+
+func TestDetachAllOtherInstance(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := r.Volume.DetachAll("other")
+	if err != nil {
 		t.Fatal(err)
 	}
+	if len(results) != 0 {
+		t.Fatalf("results=%v", results)
+	}
 }
 
 func TestDetachAllNoDrivers(t *testing.T) {
@@ -90,7 +208,7 @@ func TestDetachAllNoDrivers(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := r.Volume.DetachAll(""); err != errors.ErrNoVolumesDetected {
+	if _, err := r.Volume.DetachAll(""); err != errors.ErrNoVolumesDetected {
 		t.Fatal(err)
 	}
 }
@@ -178,6 +296,27 @@ func TestVolumeDriverManagerPath(t *testing.T) {
 	}
 }
 
+func TestDockerVolumeDriverPathTwoStorDrivers(t *testing.T) {
+	c := gofig.New()
+	c.Set("rexray.osDrivers", []string{mock.MockOSDriverName})
+	c.Set("rexray.volumeDrivers", []string{"docker"})
+	c.Set("rexray.storageDrivers", []string{
+		mock.MockStorDriverName, mock.MockStorDriver2Name})
+	c.Set("mockProvider.mountPoint", "/mnt/test2")
+	r := core.New(c)
+	if err := r.InitDrivers(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := r.Volume.Path("test2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != "/mnt/test2/data" {
+		t.Fatalf("path=%s", p)
+	}
+}
+
 func TestVolumeDriverManagerPathNoDrivers(t *testing.T) {
 	r, err := getRexRayNoDrivers()
 	if err != nil {
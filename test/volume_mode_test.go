@@ -0,0 +1,38 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/emccode/rexray/core"
+)
+
+func TestParseVolumeMode(t *testing.T) {
+	for s, exp := range map[string]string{
+		"":           core.VolumeModeFilesystem,
+		"filesystem": core.VolumeModeFilesystem,
+		"block":      core.VolumeModeBlock,
+		"Block":      core.VolumeModeBlock,
+	} {
+		mode, err := core.ParseVolumeMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode != exp {
+			t.Fatalf("mode(%s)=%s, exp=%s", s, mode, exp)
+		}
+	}
+	if _, err := core.ParseVolumeMode("raw"); err == nil {
+		t.Fatal("expected invalid volume mode error")
+	}
+}
+
+func TestIsBlockVolume(t *testing.T) {
+	v := &core.Volume{Labels: map[string]string{}}
+	if core.IsBlockVolume(v) {
+		t.Fatal("expected filesystem volume")
+	}
+	v.Labels[core.VolumeModeLabel] = core.VolumeModeBlock
+	if !core.IsBlockVolume(v) {
+		t.Fatal("expected block volume")
+	}
+}
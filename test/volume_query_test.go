@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/drivers/mock"
+)
+
+func TestVolumeQueryMatch(t *testing.T) {
+	v := &core.Volume{
+		Name:             "db-01",
+		VolumeID:         "vol-01",
+		AvailabilityZone: "us-east-1a",
+		Status:           "in-use",
+		VolumeType:       "gp2",
+		Attachments: []*core.VolumeAttachment{
+			&core.VolumeAttachment{InstanceID: "i-01"},
+		},
+		Labels: map[string]string{"env": "prod"},
+	}
+
+	for _, q := range []*core.VolumeQuery{
+		&core.VolumeQuery{},
+		&core.VolumeQuery{NamePattern: "db-*"},
+		&core.VolumeQuery{Status: "IN-USE"},
+		&core.VolumeQuery{AvailabilityZone: "us-east-1a", VolumeType: "gp2"},
+		&core.VolumeQuery{InstanceID: "i-01"},
+		&core.VolumeQuery{Labels: map[string]string{"env": "prod"}},
+	} {
+		if !q.Match(v) {
+			t.Fatalf("expected match %+v", q)
+		}
+	}
+
+	for _, q := range []*core.VolumeQuery{
+		&core.VolumeQuery{VolumeName: "db"},
+		&core.VolumeQuery{NamePattern: "web-*"},
+		&core.VolumeQuery{Status: "available"},
+		&core.VolumeQuery{InstanceID: "i-02"},
+		&core.VolumeQuery{Labels: map[string]string{"env": "dev"}},
+	} {
+		if q.Match(v) {
+			t.Fatalf("expected no match %+v", q)
+		}
+	}
+}
+
+func TestStorageDriverManagerQueryVolumes(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := r.Storage.QueryVolumes(
+		&core.VolumeQuery{NamePattern: "te*", InstanceID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Volumes) != 1 || result.NextToken != "" {
+		t.Fatalf("result=%+v", result)
+	}
+
+	result, err = r.Storage.QueryVolumes(&core.VolumeQuery{Status: "invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Volumes) != 0 {
+		t.Fatalf("len(result.Volumes)=%d", len(result.Volumes))
+	}
+
+	if _, err := r.Storage.QueryVolumes(
+		&core.VolumeQuery{NamePattern: "["}); err == nil {
+		t.Fatal("expected error for invalid name pattern")
+	}
+}
+
+func TestStorageDriverManagerQueryVolumesToken(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(volumeID string) string {
+		return base64.URLEncoding.EncodeToString(
+			[]byte(mock.MockStorDriverName + "/" + volumeID))
+	}
+
+	result, err := r.Storage.QueryVolumes(
+		&core.VolumeQuery{Limit: 1, Token: token("a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Volumes) != 1 {
+		t.Fatalf("len(result.Volumes)=%d", len(result.Volumes))
+	}
+
+	result, err = r.Storage.QueryVolumes(
+		&core.VolumeQuery{Limit: 1, Token: token("test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Volumes) != 0 {
+		t.Fatalf("len(result.Volumes)=%d", len(result.Volumes))
+	}
+
+	if _, err := r.Storage.QueryVolumes(
+		&core.VolumeQuery{Token: "invalid"}); err == nil {
+		t.Fatal("expected error for invalid token")
+	}
+}
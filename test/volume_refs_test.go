@@ -0,0 +1,290 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+	"github.com/emccode/rexray/util"
+)
+
+type volumeRefs struct {
+	MountPath string
+	Refs      []struct {
+		Caller  string
+		MountID string
+	}
+}
+
+func readVolumeRefs(t *testing.T) map[string]*volumeRefs {
+	buf, err := ioutil.ReadFile(util.LibFilePath("volumes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := map[string]*volumeRefs{}
+	if err := json.Unmarshal(buf, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestVolumeRefsPersisted(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Volume.Mount("vol1", "", false, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Volume.Unmount("vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	state := readVolumeRefs(t)
+	if vr, ok := state["vol1"]; !ok || len(vr.Refs) != 1 {
+		t.Fatalf("state=%v", state)
+	}
+
+	if err := r.Volume.Unmount("vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	state = readVolumeRefs(t)
+	if _, ok := state["vol1"]; ok {
+		t.Fatalf("state=%v", state)
+	}
+}
+
+func TestVolumeRefsReleaseMountRef(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	for _, id := range []string{"mount1", "mount2", "mount3"} {
+		ctx := core.WithMountRef(context.Background(), "test", id)
+		if _, err := r.Volume.MountContext(
+			ctx, "vol1", "", false, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := core.WithMountRef(context.Background(), "test", "mount1")
+	if err := r.Volume.UnmountContext(ctx, "vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	state := readVolumeRefs(t)
+	vr, ok := state["vol1"]
+	if !ok || len(vr.Refs) != 2 {
+		t.Fatalf("state=%v", state)
+	}
+	for _, ref := range vr.Refs {
+		if ref.Caller != "test" || ref.MountID == "mount1" {
+			t.Fatalf("ref=%v", ref)
+		}
+	}
+}
+
+func TestVolumeRefsReconcile(t *testing.T) {
+	_, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	if err := ioutil.WriteFile(util.LibFilePath("volumes.json"), []byte(
+		`{"vol1":{"MountPath":"/var/lib/rexray/volumes/vol1/data",`+
+			`"Refs":[{"Caller":"test"}]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := getRexRay(); err != nil {
+		t.Fatal(err)
+	}
+
+	state := readVolumeRefs(t)
+	if _, ok := state["vol1"]; ok {
+		t.Fatalf("state=%v", state)
+	}
+}
+
+func TestVolumeRefsMountIDIdempotent(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	ctx1 := core.WithMountRef(context.Background(), "test", "mount1")
+	for i := 0; i < 2; i++ {
+		if _, err := r.Volume.MountContext(
+			ctx1, "vol1", "", false, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state := readVolumeRefs(t)
+	if vr, ok := state["vol1"]; !ok || len(vr.Refs) != 1 {
+		t.Fatalf("state=%v", state)
+	}
+
+	// an unmount with a mount ID that holds no reference releases nothing
+	ctx2 := core.WithMountRef(context.Background(), "test", "mount2")
+	if err := r.Volume.UnmountContext(ctx2, "vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	state = readVolumeRefs(t)
+	if vr, ok := state["vol1"]; !ok || len(vr.Refs) != 1 {
+		t.Fatalf("state=%v", state)
+	}
+
+	if err := r.Volume.UnmountContext(ctx1, "vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	state = readVolumeRefs(t)
+	if _, ok := state["vol1"]; ok {
+		t.Fatalf("state=%v", state)
+	}
+}
+
+func TestVolumeRefsRelease(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	for _, id := range []string{"mount1", "mount2"} {
+		ctx := core.WithMountRef(context.Background(), "test", id)
+		if _, err := r.Volume.MountContext(
+			ctx, "vol1", "", false, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refs := r.Volume.MountRefs()
+	if vr, ok := refs["vol1"]; !ok || len(vr.Refs) != 2 {
+		t.Fatalf("refs=%v", refs)
+	}
+
+	if err := r.Volume.ReleaseMountRef("vol1", "test", "mount3"); err == nil {
+		t.Fatal("expected error releasing unknown mount ref")
+	}
+
+	if err := r.Volume.ReleaseMountRef("vol1", "test", "mount1"); err != nil {
+		t.Fatal(err)
+	}
+
+	refs = r.Volume.MountRefs()
+	vr, ok := refs["vol1"]
+	if !ok || len(vr.Refs) != 1 || vr.Refs[0].MountID != "mount2" {
+		t.Fatalf("refs=%v", refs)
+	}
+
+	if err := r.Volume.ReleaseMountRef("vol1", "test", "mount2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if refs = r.Volume.MountRefs(); len(refs) != 0 {
+		t.Fatalf("refs=%v", refs)
+	}
+}
+
+func TestVolumeRefsMountIDNoState(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	// an unmount with a mount ID of a volume on which no references are
+	// held does not unmount the volume
+	ctx := core.WithMountRef(context.Background(), "test", "mount1")
+	if err := r.Volume.UnmountContext(ctx, "vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if calls := findCalls(r, "Unmount"); len(calls) != 0 {
+		t.Fatalf("calls=%v", calls)
+	}
+
+	// an unmount without a mount ID still does
+	if err := r.Volume.Unmount("vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if calls := findCalls(r, "Unmount"); len(calls) != 1 {
+		t.Fatalf("calls=%v", calls)
+	}
+}
+
+func TestVolumeRefsReconcileBlock(t *testing.T) {
+	_, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	// the mount path of a volume with the block mode is a link to its
+	// device, which is kept while the device exists
+	linked := util.LibFilePath("vol1.dev")
+	if err := os.Symlink("/dev/null", linked); err != nil {
+		t.Fatal(err)
+	}
+	dangling := util.LibFilePath("vol2.dev")
+	if err := os.Symlink(
+		path.Join(util.LibFilePath("missing"), "xvdb"), dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(util.LibFilePath("volumes.json"), []byte(
+		fmt.Sprintf(`{"vol1":{"MountPath":%q,"Refs":[{"Caller":"test"}]},`+
+			`"vol2":{"MountPath":%q,"Refs":[{"Caller":"test"}]}}`,
+			linked, dangling)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := getRexRay(); err != nil {
+		t.Fatal(err)
+	}
+
+	state := readVolumeRefs(t)
+	if vr, ok := state["vol1"]; !ok || len(vr.Refs) != 1 {
+		t.Fatalf("state=%v", state)
+	}
+	if _, ok := state["vol2"]; ok {
+		t.Fatalf("state=%v", state)
+	}
+}
+
+func TestVolumeRefsUnmountFailed(t *testing.T) {
+	r, cleanup := getRexRayWithTempLib(t)
+	defer cleanup()
+
+	ctx := core.WithMountRef(context.Background(), "test", "mount1")
+	if _, err := r.Volume.MountContext(
+		ctx, "vol1", "", false, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the reference is kept when the volume cannot be unmounted, so a
+	// retried unmount with the same mount ID unmounts the volume
+	r.Config.Set("mockProvider.fail", []string{"Unmount"})
+	if err := r.Volume.UnmountContext(ctx, "vol1", ""); err == nil {
+		t.Fatal("expected error unmounting volume")
+	}
+	state := readVolumeRefs(t)
+	vr, ok := state["vol1"]
+	if !ok || len(vr.Refs) != 1 || vr.Refs[0].MountID != "mount1" {
+		t.Fatalf("state=%v", state)
+	}
+
+	r.Config.Set("mockProvider.fail", []string{})
+	if err := r.Volume.UnmountContext(ctx, "vol1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if calls := findCalls(r, "Unmount"); len(calls) != 2 {
+		t.Fatalf("calls=%v", calls)
+	}
+	state = readVolumeRefs(t)
+	if _, ok := state["vol1"]; ok {
+		t.Fatalf("state=%v", state)
+	}
+}
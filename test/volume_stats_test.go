@@ -0,0 +1,69 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/emccode/rexray/core"
+)
+
+func TestOSDriverStats(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err := r.OS.StatsContext(context.Background(), "/dev/xvdb", "/mnt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms.CapacityBytes != 1024 || ms.UsedBytes != 256 ||
+		ms.AvailableBytes != 768 {
+		t.Fatalf("stats=%v", ms)
+	}
+	if ms.Inodes != 64 || ms.InodesUsed != 16 || ms.InodesFree != 48 {
+		t.Fatalf("stats=%v", ms)
+	}
+}
+
+func TestVolumeStatsNotMounted(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the mock OS driver has no mounts, so no volume has statistics
+	stats, err := r.Volume.StatsContext(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil || len(stats) != 0 {
+		t.Fatalf("stats=%v", stats)
+	}
+}
+
+func TestVolumeStatsCollector(t *testing.T) {
+	r, err := getRexRay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := core.NewVolumeStatsCollector(r, time.Second)
+
+	descs := make(chan *prometheus.Desc, 32)
+	c.Describe(descs)
+	close(descs)
+	if len(descs) != 11 {
+		t.Fatalf("len(descs)=%d", len(descs))
+	}
+
+	metrics := make(chan prometheus.Metric, 32)
+	c.Collect(metrics)
+	close(metrics)
+	if len(metrics) != 0 {
+		t.Fatalf("len(metrics)=%d", len(metrics))
+	}
+}